@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+//agePopulationProvider holds the population per age group for Austria and the configured provinces
+type agePopulationProvider struct {
+	regions []string
+	groups  []string
+	data    map[string]map[string]uint64
+}
+
+type ageStat struct {
+	//Group is the age group in years, e.g. 15-24
+	Group    string `json:"group"`
+	Infected uint64 `json:"infected"`
+	//InfectedShare is the share of all infections (0-1)
+	InfectedShare float64 `json:"infected_share"`
+	//Population are the inhabitants of the age group, 0 if unknown
+	Population uint64 `json:"population"`
+	//PopulationShare is the share of the population (0-1)
+	PopulationShare float64 `json:"population_share"`
+	InfectedPer100k float64 `json:"infected_per_100k"`
+}
+
+type sexStat struct {
+	Sex      string `json:"sex"`
+	Infected uint64 `json:"infected"`
+	//InfectedShare is the share of all infections (0-1)
+	InfectedShare float64 `json:"infected_share"`
+}
+
+//newAgePopulationProvider returns the age groups bundled with the binary
+func newAgePopulationProvider() *agePopulationProvider {
+	result, err := loadAgePopulationProvider("")
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+//loadAgePopulationProvider reads the embedded altersgruppen.csv and merges the rows of override on top of it
+func loadAgePopulationProvider(override string) (*agePopulationProvider, error) {
+	file, err := embeddedMetadata.Open("altersgruppen.csv")
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	result := &agePopulationProvider{data: make(map[string]map[string]uint64)}
+	err = result.parse("altersgruppen.csv", file)
+	if err != nil {
+		return nil, err
+	}
+	if override != "" {
+		overrideFile, err := os.Open(override)
+		if err != nil {
+			return nil, err
+		}
+		defer overrideFile.Close()
+		err = result.parse(override, overrideFile)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (p *agePopulationProvider) parse(filename string, reader io.Reader) error {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = 3
+	records, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for i, row := range records {
+		population, err := strconv.ParseUint(row[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid population %q", filename, i+1, row[2])
+		}
+		region := normalizeName(row[0])
+		group := strings.TrimSpace(row[1])
+		if _, ok := p.data[region]; !ok {
+			p.data[region] = make(map[string]uint64)
+			p.regions = append(p.regions, row[0])
+		}
+		if !p.hasGroup(group) {
+			p.groups = append(p.groups, group)
+		}
+		p.data[region][group] = population
+	}
+	return nil
+}
+
+func (p *agePopulationProvider) hasGroup(group string) bool {
+	for _, g := range p.groups {
+		if g == group {
+			return true
+		}
+	}
+	return false
+}
+
+//getPopulation for an age group of a region, 0 if unknown
+func (p *agePopulationProvider) getPopulation(region string, group string) uint64 {
+	if groups, ok := p.data[normalizeName(region)]; ok {
+		return groups[strings.TrimSpace(group)]
+	}
+	return 0
+}
+
+//getAgeStats combines the infections per age group with the population of region
+func (p *agePopulationProvider) getAgeStats(region string, infections map[string]uint64) []ageStat {
+	totalInfected := uint64(0)
+	for _, v := range infections {
+		totalInfected += v
+	}
+	totalPopulation := uint64(0)
+	for _, group := range p.groups {
+		totalPopulation += p.getPopulation(region, group)
+	}
+
+	result := make([]ageStat, 0, len(infections))
+	for _, group := range p.groups {
+		if infected, ok := infections[group]; ok {
+			result = append(result, p.getAgeStat(region, group, infected, totalInfected, totalPopulation))
+		}
+	}
+	for group, infected := range infections {
+		if !p.hasGroup(group) {
+			result = append(result, p.getAgeStat(region, group, infected, totalInfected, totalPopulation))
+		}
+	}
+	return result
+}
+
+func (p *agePopulationProvider) getAgeStat(region string, group string, infected uint64, totalInfected uint64, totalPopulation uint64) ageStat {
+	stat := ageStat{Group: group, Infected: infected, Population: p.getPopulation(region, group)}
+	if totalInfected > 0 {
+		stat.InfectedShare = float64(infected) / float64(totalInfected)
+	}
+	if stat.Population > 0 {
+		stat.PopulationShare = float64(stat.Population) / float64(totalPopulation)
+		stat.InfectedPer100k = infection100k(infected, stat.Population)
+	}
+	return stat
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAgePopulation(t *testing.T) {
+	ages := newAgePopulationProvider()
+	assert.Equal(t, 10, len(ages.groups))
+	assert.Equal(t, []string{"Austria"}, ages.regions, "provinces are only read from the age_groups file")
+	assert.Nil(t, validateAgePopulation(ages))
+
+	sum := uint64(0)
+	for _, group := range ages.groups {
+		sum += ages.getPopulation("Austria", group)
+	}
+	assert.Equal(t, uint64(8901064), sum, "population on 1.1.2020")
+	assert.Equal(t, uint64(0), ages.getPopulation("xxxxx", "<5"))
+
+	partial := writeTempMetadata(t, "Wien,<5,100000\n")
+	defer os.Remove(partial)
+	ages, err := loadAgePopulationProvider(partial)
+	assert.Nil(t, err)
+	assert.EqualError(t, validateAgePopulation(ages), "Missing population for age group 5-14 in Wien")
+}
+
+func TestAgeStats(t *testing.T) {
+	filename := writeTempMetadata(t, "Austria,<5,100000\nAustria,>84,300000\n")
+	defer os.Remove(filename)
+	ages, err := loadAgePopulationProvider(filename)
+	assert.Nil(t, err)
+
+	result := ages.getAgeStats("Austria", map[string]uint64{">84": 30, "<5": 10, "unknown": 60})
+	assert.Equal(t, 3, len(result))
+	assert.Equal(t, "<5", result[0].Group)
+	assert.Equal(t, 10.0, result[0].InfectedPer100k)
+	assert.Equal(t, 0.1, result[0].InfectedShare)
+	assert.Equal(t, ">84", result[1].Group)
+	assert.Equal(t, 10.0, result[1].InfectedPer100k)
+	assert.Equal(t, "unknown", result[2].Group)
+	assert.Equal(t, uint64(0), result[2].Population)
+	assert.Equal(t, 0.0, result[2].InfectedPer100k)
+}
+
+func TestAgeMetrics(t *testing.T) {
+	mockServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`var dpAltersverteilung = [{"label":"<5","y":43},{"label":"15-24","y":936}];`))
+	}))
+	defer mockServer.Close()
+	h := newHealthMinistryExporter()
+	h.url = mockServer.URL
+
+	result, err := h.getAgeMetrics()
+	assert.Nil(t, err)
+	assert.Nil(t, result.checkMetric("cov19_age_distribution", "group=<5", func(x float64) bool { return x == 43 }))
+	assert.Nil(t, result.checkMetric("cov19_age_infected_per_100k", "group=15-24", func(x float64) bool { return x > 99 && x < 101 }))
+
+	stats, err := newApi(h, nil, nil, nil).GetAgeStat()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(stats))
+	assert.Equal(t, "<5", stats[0].Group)
+	assert.Equal(t, uint64(43), stats[0].Infected)
+}
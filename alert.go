@@ -0,0 +1,611 @@
+package main
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+type alertConfig struct {
+	Rules    []alertRule    `json:"rules"`
+	Channels []alertChannel `json:"channels"`
+}
+
+type alertRule struct {
+	Name string `json:"name"`
+	//Scope is total, bundesland, bezirk, world or source
+	Scope string `json:"scope"`
+	//Regions limits the rule to some regions, all regions if empty
+	Regions []string `json:"regions"`
+	//Metric is the json name of a numeric field of the scope, hours_since_change for sources
+	Metric string `json:"metric"`
+	//Function is applied to the metric: empty for the current value, increase, increase_per_100k or change_ratio
+	//compared to the daily snapshot WindowDays ago
+	Function   string `json:"function"`
+	WindowDays int    `json:"window_days"`
+	//Operator is >, >=, < or <=
+	Operator  string  `json:"operator"`
+	Threshold float64 `json:"threshold"`
+	//For is the number of seconds the condition needs to hold before the alert fires
+	For float64 `json:"for"`
+	//Channels are the names of the channels notified, all channels if empty
+	Channels []string `json:"channels"`
+}
+
+type alertChannel struct {
+	Name string `json:"name"`
+	//Type is webhook, matrix, telegram or smtp
+	Type string `json:"type"`
+	//URL is the webhook url, the matrix homeserver or the telegram api (https://api.telegram.org if empty)
+	URL string `json:"url"`
+	//Token is the matrix access token or the telegram bot token
+	Token  string `json:"token"`
+	Room   string `json:"room"`
+	ChatID string `json:"chat_id"`
+	//Host is the host:port of the smtp server
+	Host     string   `json:"host"`
+	Username string   `json:"username"`
+	Password string   `json:"password"`
+	From     string   `json:"from"`
+	To       []string `json:"to"`
+}
+
+var alertFunctions = []string{"", "increase", "increase_per_100k", "change_ratio"}
+
+var alertOperators = map[string]func(a, b float64) bool{
+	">":  func(a, b float64) bool { return a > b },
+	">=": func(a, b float64) bool { return a >= b },
+	"<":  func(a, b float64) bool { return a < b },
+	"<=": func(a, b float64) bool { return a <= b },
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+//matchesRegion compares the names normalized, all regions match an empty list
+func matchesRegion(regions []string, region string) bool {
+	if len(regions) == 0 {
+		return true
+	}
+	for _, r := range regions {
+		if normalizeName(r) == normalizeName(region) {
+			return true
+		}
+	}
+	return false
+}
+
+//scopeFields returns the numeric fields of the rows of a scope
+func scopeFields(scope string) map[string]float64 {
+	zero := &snapshot{Bundesland: make([]bundeslandStat, 1), Bezirk: make([]bezirkStat, 1), World: make([]worldStat, 1)}
+	return numericFields(rows(scopeRows(zero, scope))[0], make(map[string]float64))
+}
+
+func (c alertConfig) validate() error {
+	names := make([]string, 0, len(c.Channels))
+	for i, ch := range c.Channels {
+		err := ch.validate()
+		if err != nil {
+			return fmt.Errorf("alert channel %d: %v", i, err)
+		}
+		if contains(names, ch.Name) {
+			return fmt.Errorf("alert channel %d: Duplicate name %q", i, ch.Name)
+		}
+		names = append(names, ch.Name)
+	}
+	rules := make([]string, 0, len(c.Rules))
+	for i, r := range c.Rules {
+		err := r.validate()
+		if err != nil {
+			return fmt.Errorf("alert rule %d: %v", i, err)
+		}
+		if contains(rules, r.Name) {
+			return fmt.Errorf("alert rule %d: Duplicate name %q", i, r.Name)
+		}
+		rules = append(rules, r.Name)
+		for _, ch := range r.Channels {
+			if !contains(names, ch) {
+				return fmt.Errorf("alert rule %d: Unknown channel %q", i, ch)
+			}
+		}
+	}
+	return nil
+}
+
+func (r alertRule) validate() error {
+	if r.Name == "" {
+		return fmt.Errorf("Missing name")
+	}
+	if r.Scope == "source" {
+		if r.Metric != "hours_since_change" || r.Function != "" {
+			return fmt.Errorf("Sources only support the metric hours_since_change without a function")
+		}
+	} else if !contains(eventScopes, r.Scope) {
+		return fmt.Errorf("Unknown scope %q", r.Scope)
+	} else if _, ok := scopeFields(r.Scope)[r.Metric]; !ok {
+		return fmt.Errorf("Unknown metric %q for scope %s", r.Metric, r.Scope)
+	}
+	if !contains(alertFunctions, r.Function) {
+		return fmt.Errorf("Unknown function %q, use increase, increase_per_100k or change_ratio", r.Function)
+	}
+	if _, ok := scopeFields(r.Scope)["population"]; r.Function == "increase_per_100k" && !ok {
+		return fmt.Errorf("Function increase_per_100k needs a population, scope %s has none", r.Scope)
+	}
+	if _, ok := alertOperators[r.Operator]; !ok {
+		return fmt.Errorf("Unknown operator %q, use >, >=, < or <=", r.Operator)
+	}
+	if r.WindowDays < 0 || r.For < 0 {
+		return fmt.Errorf("window_days and for can not be negative")
+	}
+	return nil
+}
+
+func (c alertChannel) validate() error {
+	if c.Name == "" {
+		return fmt.Errorf("Missing name")
+	}
+	if c.URL != "" && !strings.HasPrefix(c.URL, "http://") && !strings.HasPrefix(c.URL, "https://") {
+		return fmt.Errorf("Invalid url %q", c.URL)
+	}
+	switch c.Type {
+	case "webhook":
+		if c.URL == "" {
+			return fmt.Errorf("A webhook needs a url")
+		}
+	case "matrix":
+		if c.URL == "" || c.Token == "" || c.Room == "" {
+			return fmt.Errorf("A matrix channel needs url, token and room")
+		}
+	case "telegram":
+		if c.Token == "" || c.ChatID == "" {
+			return fmt.Errorf("A telegram channel needs token and chat_id")
+		}
+	case "smtp":
+		if c.Host == "" || c.From == "" || len(c.To) == 0 {
+			return fmt.Errorf("A smtp channel needs host, from and to")
+		}
+	default:
+		return fmt.Errorf("Unknown channel type %q, use webhook, matrix, telegram or smtp", c.Type)
+	}
+	return nil
+}
+
+//windowDays defaults to a week
+func (r alertRule) windowDays() int {
+	if r.WindowDays == 0 {
+		return 7
+	}
+	return r.WindowDays
+}
+
+//expression describes the value of the rule, e.g. increase_per_100k(infected, 7d)
+func (r alertRule) expression() string {
+	if r.Function == "" {
+		return r.Metric
+	}
+	return fmt.Sprintf("%s(%s, %dd)", r.Function, r.Metric, r.windowDays())
+}
+
+//values computes the value of the rule for every matching region,
+//regions without enough data to compute the function are left out
+func (r alertRule) values(current *snapshot, at func(time.Time) *snapshot, sources []upstreamChange, now time.Time) map[string]float64 {
+	result := make(map[string]float64)
+	if r.Scope == "source" {
+		for _, s := range sources {
+			name := sourceTitle([]upstreamChange{s})
+			if matchesRegion(r.Regions, name) {
+				result[name] = now.Sub(s.Changed).Hours()
+			}
+		}
+		return result
+	}
+	if current == nil {
+		return result
+	}
+	old := make(map[string]map[string]float64)
+	if r.Function != "" {
+		then := at(current.Time.AddDate(0, 0, -r.windowDays()))
+		if then == nil {
+			return result
+		}
+		for _, row := range rows(scopeRows(then, r.Scope)) {
+			old[rowName(row)] = numericFields(row, make(map[string]float64))
+		}
+	}
+	for _, row := range rows(scopeRows(current, r.Scope)) {
+		name := rowName(row)
+		if !matchesRegion(r.Regions, name) {
+			continue
+		}
+		values := numericFields(row, make(map[string]float64))
+		previous, ok := old[name]
+		if r.Function != "" && !ok {
+			continue
+		}
+		switch r.Function {
+		case "":
+			result[name] = values[r.Metric]
+		case "increase":
+			result[name] = values[r.Metric] - previous[r.Metric]
+		case "increase_per_100k":
+			if values["population"] > 0 {
+				result[name] = (values[r.Metric] - previous[r.Metric]) / values["population"] * 100000
+			}
+		case "change_ratio":
+			if previous[r.Metric] != 0 {
+				result[name] = (values[r.Metric] - previous[r.Metric]) / previous[r.Metric]
+			}
+		}
+	}
+	return result
+}
+
+//alertState is an alert of a rule for one region
+type alertState struct {
+	Rule   string  `json:"rule"`
+	Region string  `json:"region"`
+	Value  float64 `json:"value"`
+	//State is pending until the condition held for the duration of the rule, then firing and finally resolved
+	State      string     `json:"state"`
+	Since      time.Time  `json:"since"`
+	FiredAt    *time.Time `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time `json:"resolved_at,omitempty"`
+}
+
+//alertNotification is sent to the channels when an alert fires or resolves
+type alertNotification struct {
+	//Status is firing or resolved
+	Status     string     `json:"status"`
+	Rule       string     `json:"rule"`
+	Scope      string     `json:"scope"`
+	Region     string     `json:"region"`
+	Expression string     `json:"expression"`
+	Value      float64    `json:"value"`
+	Operator   string     `json:"operator"`
+	Threshold  float64    `json:"threshold"`
+	StartsAt   time.Time  `json:"starts_at"`
+	EndsAt     *time.Time `json:"ends_at,omitempty"`
+	channels   []string
+}
+
+func (n alertNotification) text() string {
+	return fmt.Sprintf("[%s] %s: %s %s is %g, threshold %s %g",
+		strings.ToUpper(n.Status), n.Rule, n.Region, n.Expression, n.Value, n.Operator, n.Threshold)
+}
+
+//maxResolved is the number of resolved alerts kept for /admin/alerts
+const maxResolved = 100
+
+//alertInterval is the time between two evaluations of the rules
+var alertInterval = time.Minute
+
+//alertEngine evaluates the alert rules on the snapshots of the collector and notifies the channels
+type alertEngine struct {
+	lock     sync.Mutex
+	config   alertConfig
+	alerts   map[string]*alertState
+	resolved []alertState
+	//failures is the last error of every channel, empty after a successful notification
+	failures map[string]string
+	client   *http.Client
+	nextTxn  uint64
+	now      func() time.Time
+}
+
+func newAlertEngine() *alertEngine {
+	return &alertEngine{
+		alerts:   make(map[string]*alertState),
+		failures: make(map[string]string),
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+	}
+}
+
+//configure replaces the rules and channels, alerts of removed rules are dropped
+func (e *alertEngine) configure(c alertConfig) {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	e.config = c
+	for key, a := range e.alerts {
+		known := false
+		for _, r := range c.Rules {
+			known = known || r.Name == a.Rule
+		}
+		if !known {
+			delete(e.alerts, key)
+		}
+	}
+	for name := range e.failures {
+		known := false
+		for _, ch := range c.Channels {
+			known = known || ch.Name == name
+		}
+		if !known {
+			delete(e.failures, name)
+		}
+	}
+}
+
+//run evaluates the rules every alertInterval
+func (e *alertEngine) run(c *collector) {
+	for {
+		current, _ := c.snapshots()
+		e.evaluate(current, c.snapshotAt, upstream.changedSince(time.Time{}))
+		time.Sleep(alertInterval)
+	}
+}
+
+//evaluate updates the alerts and sends a notification for every alert that started firing or resolved
+func (e *alertEngine) evaluate(current *snapshot, at func(time.Time) *snapshot, sources []upstreamChange) []alertNotification {
+	e.lock.Lock()
+	now := e.now()
+	notifications := make([]alertNotification, 0)
+	active := make(map[string]bool)
+	for _, r := range e.config.Rules {
+		values := r.values(current, at, sources, now)
+		regions := make([]string, 0, len(values))
+		for region := range values {
+			regions = append(regions, region)
+		}
+		sort.Strings(regions)
+		for _, region := range regions {
+			value := values[region]
+			if !alertOperators[r.Operator](value, r.Threshold) {
+				continue
+			}
+			key := r.Name + "\x00" + region
+			active[key] = true
+			a, ok := e.alerts[key]
+			if !ok {
+				a = &alertState{Rule: r.Name, Region: region, State: "pending", Since: now}
+				e.alerts[key] = a
+			}
+			a.Value = value
+			if a.State == "pending" && now.Sub(a.Since) >= seconds(r.For) {
+				a.State = "firing"
+				firedAt := now
+				a.FiredAt = &firedAt
+				notifications = append(notifications, newAlertNotification(r, a))
+			}
+		}
+	}
+	keys := make([]string, 0, len(e.alerts))
+	for key := range e.alerts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		a := e.alerts[key]
+		if active[key] {
+			continue
+		}
+		delete(e.alerts, key)
+		if a.State != "firing" {
+			continue
+		}
+		a.State = "resolved"
+		resolvedAt := now
+		a.ResolvedAt = &resolvedAt
+		e.resolved = append(e.resolved, *a)
+		if len(e.resolved) > maxResolved {
+			e.resolved = e.resolved[len(e.resolved)-maxResolved:]
+		}
+		for _, r := range e.config.Rules {
+			if r.Name == a.Rule {
+				n := newAlertNotification(r, a)
+				n.EndsAt = &resolvedAt
+				notifications = append(notifications, n)
+			}
+		}
+	}
+	channels := e.config.Channels
+	e.lock.Unlock()
+
+	for _, n := range notifications {
+		for _, ch := range channels {
+			if len(n.channels) > 0 && !contains(n.channels, ch.Name) {
+				continue
+			}
+			err := e.notify(ch, n)
+			e.lock.Lock()
+			if err != nil {
+				logger.Printf("Alert notification via %s failed: %v", ch.Name, err)
+				e.failures[ch.Name] = err.Error()
+			} else {
+				delete(e.failures, ch.Name)
+			}
+			e.lock.Unlock()
+		}
+	}
+	return notifications
+}
+
+func newAlertNotification(r alertRule, a *alertState) alertNotification {
+	return alertNotification{
+		Status:     a.State,
+		Rule:       r.Name,
+		Scope:      r.Scope,
+		Region:     a.Region,
+		Expression: r.expression(),
+		Value:      a.Value,
+		Operator:   r.Operator,
+		Threshold:  r.Threshold,
+		StartsAt:   a.Since,
+		channels:   r.Channels,
+	}
+}
+
+func (e *alertEngine) notify(ch alertChannel, n alertNotification) error {
+	switch ch.Type {
+	case "webhook":
+		return e.send(http.MethodPost, ch.URL, nil, n)
+	case "matrix":
+		e.lock.Lock()
+		e.nextTxn++
+		txn := fmt.Sprintf("covid19-at-%d-%d", e.now().Unix(), e.nextTxn)
+		e.lock.Unlock()
+		u := strings.TrimSuffix(ch.URL, "/") + "/_matrix/client/r0/rooms/" + url.PathEscape(ch.Room) + "/send/m.room.message/" + txn
+		body := map[string]string{"msgtype": "m.text", "body": n.text()}
+		return e.send(http.MethodPut, u, map[string]string{"Authorization": "Bearer " + ch.Token}, body)
+	case "telegram":
+		api := ch.URL
+		if api == "" {
+			api = "https://api.telegram.org"
+		}
+		body := map[string]string{"chat_id": ch.ChatID, "text": n.text()}
+		return e.send(http.MethodPost, strings.TrimSuffix(api, "/")+"/bot"+ch.Token+"/sendMessage", nil, body)
+	case "smtp":
+		return sendMail(ch, n.text(), n.text())
+	}
+	return fmt.Errorf("Unknown channel type %q", ch.Type)
+}
+
+func (e *alertEngine) send(method string, url string, headers map[string]string, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	request, err := http.NewRequest(method, url, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		request.Header.Set(k, v)
+	}
+	response, err := e.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", request.URL.Host, response.Status)
+	}
+	return nil
+}
+
+//sendMail delivers a plain text mail to the recipients of the channel, STARTTLS is used if the server offers it
+func sendMail(ch alertChannel, subject string, body string) error {
+	host, _, err := net.SplitHostPort(ch.Host)
+	if err != nil {
+		return err
+	}
+	conn, err := net.DialTimeout("tcp", ch.Host, 10*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(30 * time.Second))
+	client, err := smtp.NewClient(conn, host)
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	defer client.Close()
+	if ok, _ := client.Extension("STARTTLS"); ok {
+		err = client.StartTLS(&tls.Config{ServerName: host})
+		if err != nil {
+			return err
+		}
+	}
+	if ch.Username != "" {
+		err = client.Auth(smtp.PlainAuth("", ch.Username, ch.Password, host))
+		if err != nil {
+			return err
+		}
+	}
+	err = client.Mail(ch.From)
+	if err != nil {
+		return err
+	}
+	for _, to := range ch.To {
+		err = client.Rcpt(to)
+		if err != nil {
+			return err
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	message := fmt.Sprintf("From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=utf-8\r\n\r\n%s\r\n",
+		ch.From, strings.Join(ch.To, ", "), subject, body)
+	_, err = w.Write([]byte(message))
+	if err != nil {
+		return err
+	}
+	err = w.Close()
+	if err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+//states returns the pending and firing alerts followed by the resolved ones
+func (e *alertEngine) states() []alertState {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	result := make([]alertState, 0, len(e.alerts)+len(e.resolved))
+	for _, a := range e.alerts {
+		result = append(result, *a)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Rule+"\x00"+result[i].Region < result[j].Rule+"\x00"+result[j].Region
+	})
+	return append(result, e.resolved...)
+}
+
+//GetMetrics exposes the pending and firing alerts
+func (e *alertEngine) GetMetrics() (metrics, error) {
+	result := make(metrics, 0)
+	for _, a := range e.states() {
+		if a.State == "resolved" {
+			continue
+		}
+		tags := map[string]string{"rule": a.Rule, "region": a.Region, "state": a.State}
+		result = append(result, metric{Name: "cov19_alert_active", Value: 1, Tags: &tags})
+	}
+	return result, nil
+}
+
+//Health reports channels whose last notification failed
+func (e *alertEngine) Health() []error {
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	names := make([]string, 0, len(e.failures))
+	for name := range e.failures {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	result := make([]error, 0)
+	for _, name := range names {
+		result = append(result, fmt.Errorf("Alert channel %s failed: %s", name, e.failures[name]))
+	}
+	return result
+}
+
+//handleAlerts returns the pending, firing and recently resolved alerts
+func (e *alertEngine) handleAlerts(w http.ResponseWriter, r *http.Request) {
+	if !rl.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	writeJson(w, func() (interface{}, error) { return e.states(), nil })
+}
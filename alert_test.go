@@ -0,0 +1,208 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testAlertSnapshots() (*snapshot, func(time.Time) *snapshot) {
+	weekAgo := &snapshot{
+		Time:       time.Date(2020, 3, 23, 20, 0, 0, 0, time.UTC),
+		Bundesland: []bundeslandStat{{Name: "Wien", IntensiveCare: 20}, {Name: "Tirol", IntensiveCare: 30}},
+		Bezirk: []bezirkStat{
+			{Name: "Innsbruck-Land", Population: 180000, Infected: 100},
+			{Name: "Wien(Stadt)", Population: 1900000, Infected: 1000},
+		},
+	}
+	current := &snapshot{
+		Time:       time.Date(2020, 3, 30, 20, 0, 0, 0, time.UTC),
+		Bundesland: []bundeslandStat{{Name: "Wien", IntensiveCare: 25}, {Name: "Tirol", IntensiveCare: 31}},
+		Bezirk: []bezirkStat{
+			{Name: "Innsbruck-Land", Population: 180000, Infected: 298},
+			{Name: "Wien(Stadt)", Population: 1900000, Infected: 1500},
+			{Name: "Atlantis", Infected: 3},
+		},
+	}
+	at := func(t time.Time) *snapshot {
+		if t.Before(weekAgo.Time) {
+			return nil
+		}
+		return weekAgo
+	}
+	return current, at
+}
+
+func TestAlertRuleValues(t *testing.T) {
+	current, at := testAlertSnapshots()
+	now := current.Time
+
+	incidence := alertRule{Scope: "bezirk", Metric: "infected", Function: "increase_per_100k", Operator: ">", Threshold: 100}
+	assert.Equal(t, map[string]float64{"Innsbruck-Land": 110, "Wien(Stadt)": 500.0 / 19}, incidence.values(current, at, nil, now))
+	assert.Equal(t, "increase_per_100k(infected, 7d)", incidence.expression())
+
+	icu := alertRule{Scope: "bundesland", Regions: []string{"wien"}, Metric: "intensive_care", Function: "change_ratio", Operator: ">", Threshold: 0.2}
+	assert.Equal(t, map[string]float64{"Wien": 0.25}, icu.values(current, at, nil, now))
+
+	icu.WindowDays = 14
+	assert.Equal(t, map[string]float64{}, icu.values(current, at, nil, now))
+
+	stale := alertRule{Scope: "source", Metric: "hours_since_change", Operator: ">", Threshold: 6}
+	sources := []upstreamChange{{"ecdc", "https://www.ecdc.europa.eu/en/cases", now.Add(-8 * time.Hour)}}
+	assert.Equal(t, map[string]float64{"ECDC cases": 8}, stale.values(current, at, sources, now))
+}
+
+func TestAlertConfig(t *testing.T) {
+	c := alertConfig{
+		Rules:    []alertRule{{Name: "incidence", Scope: "bezirk", Metric: "infected", Function: "increase_per_100k", Operator: ">", Threshold: 100, Channels: []string{"ops"}}},
+		Channels: []alertChannel{{Name: "ops", Type: "webhook", URL: "http://localhost/alerts"}},
+	}
+	assert.Nil(t, c.validate())
+	c.Rules[0].Channels = []string{"chat"}
+	assert.EqualError(t, c.validate(), `alert rule 0: Unknown channel "chat"`)
+
+	assert.EqualError(t, alertRule{Name: "x", Scope: "bezirk", Metric: "dead", Operator: ">"}.validate(), `Unknown metric "dead" for scope bezirk`)
+	assert.EqualError(t, alertRule{Name: "x", Scope: "bezirk", Metric: "infected", Operator: "=="}.validate(), `Unknown operator "==", use >, >=, < or <=`)
+	assert.EqualError(t, alertRule{Name: "x", Scope: "total", Metric: "total_infected", Function: "increase_per_100k", Operator: ">"}.validate(), "Function increase_per_100k needs a population, scope total has none")
+	assert.EqualError(t, alertRule{Name: "x", Scope: "source", Metric: "infected", Operator: ">"}.validate(), "Sources only support the metric hours_since_change without a function")
+	assert.EqualError(t, alertChannel{Name: "chat", Type: "telegram", Token: "secret"}.validate(), "A telegram channel needs token and chat_id")
+	assert.EqualError(t, alertChannel{Name: "chat", Type: "irc"}.validate(), `Unknown channel type "irc", use webhook, matrix, telegram or smtp`)
+}
+
+func TestAlertStates(t *testing.T) {
+	lock := sync.Mutex{}
+	received := make([]alertNotification, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := alertNotification{}
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&n))
+		lock.Lock()
+		received = append(received, n)
+		lock.Unlock()
+	}))
+	defer ts.Close()
+
+	current, at := testAlertSnapshots()
+	now := current.Time
+	e := newAlertEngine()
+	e.now = func() time.Time { return now }
+	e.configure(alertConfig{
+		Rules:    []alertRule{{Name: "incidence", Scope: "bezirk", Metric: "infected", Function: "increase_per_100k", Operator: ">", Threshold: 100, For: 3600}},
+		Channels: []alertChannel{{Name: "ops", Type: "webhook", URL: ts.URL}},
+	})
+
+	assert.Equal(t, 0, len(e.evaluate(current, at, nil)))
+	assert.Equal(t, "pending", e.states()[0].State)
+
+	now = now.Add(time.Hour)
+	result := e.evaluate(current, at, nil)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "firing", result[0].Status)
+	assert.Equal(t, "Innsbruck-Land", result[0].Region)
+	assert.Equal(t, "[FIRING] incidence: Innsbruck-Land increase_per_100k(infected, 7d) is 110, threshold > 100", result[0].text())
+	metrics, _ := e.GetMetrics()
+	assert.Nil(t, metrics.checkMetric("cov19_alert_active", "state=firing", func(x float64) bool { return x == 1 }))
+
+	//firing alerts are only notified once
+	now = now.Add(time.Hour)
+	assert.Equal(t, 0, len(e.evaluate(current, at, nil)))
+
+	current.Bezirk[0].Infected = 200
+	now = now.Add(time.Hour)
+	result = e.evaluate(current, at, nil)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "resolved", result[0].Status)
+	assert.Equal(t, now, *result[0].EndsAt)
+	states := e.states()
+	assert.Equal(t, 1, len(states))
+	assert.Equal(t, "resolved", states[0].State)
+	assert.Equal(t, 2, len(received))
+	assert.Equal(t, "resolved", received[1].Status)
+	assert.Equal(t, 0, len(e.Health()))
+}
+
+//smtpStandIn accepts a single mail and returns it on the channel
+func smtpStandIn(t *testing.T) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	mails := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("220 localhost ESMTP\r\n"))
+		mail := ""
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			switch {
+			case strings.HasPrefix(line, "EHLO"), strings.HasPrefix(line, "HELO"):
+				conn.Write([]byte("250 localhost\r\n"))
+			case strings.HasPrefix(line, "DATA"):
+				conn.Write([]byte("354 go ahead\r\n"))
+				for {
+					line, err = reader.ReadString('\n')
+					if err != nil || line == ".\r\n" {
+						break
+					}
+					mail += line
+				}
+				mails <- mail
+				conn.Write([]byte("250 ok\r\n"))
+			case strings.HasPrefix(line, "QUIT"):
+				conn.Write([]byte("221 bye\r\n"))
+				return
+			default:
+				conn.Write([]byte("250 ok\r\n"))
+			}
+		}
+	}()
+	return listener.Addr().String(), mails
+}
+
+func TestAlertChannels(t *testing.T) {
+	requests := make(map[string]map[string]string)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		body := make(map[string]string)
+		assert.Nil(t, json.NewDecoder(r.Body).Decode(&body))
+		requests[r.Method+" "+r.URL.EscapedPath()+" "+r.Header.Get("Authorization")] = body
+	}))
+	defer ts.Close()
+	host, mails := smtpStandIn(t)
+
+	e := newAlertEngine()
+	e.now = func() time.Time { return time.Unix(1585555200, 0) }
+	n := alertNotification{Status: "firing", Rule: "icu", Region: "Wien", Expression: "change_ratio(intensive_care, 7d)", Value: 0.25, Operator: ">", Threshold: 0.2}
+	text := "[FIRING] icu: Wien change_ratio(intensive_care, 7d) is 0.25, threshold > 0.2"
+
+	assert.Nil(t, e.notify(alertChannel{Type: "matrix", URL: ts.URL, Token: "secret", Room: "!ops:example.com"}, n))
+	assert.Equal(t, map[string]string{"msgtype": "m.text", "body": text}, requests["PUT /_matrix/client/r0/rooms/%21ops:example.com/send/m.room.message/covid19-at-1585555200-1 Bearer secret"])
+
+	assert.Nil(t, e.notify(alertChannel{Type: "telegram", URL: ts.URL, Token: "123:abc", ChatID: "-42"}, n))
+	assert.Equal(t, map[string]string{"chat_id": "-42", "text": text}, requests["POST /bot123:abc/sendMessage "])
+
+	assert.Nil(t, e.notify(alertChannel{Type: "smtp", Host: host, From: "covid19@example.com", To: []string{"ops@example.com"}}, n))
+	mail := <-mails
+	assert.Contains(t, mail, "Subject: "+text+"\r\n")
+	assert.Contains(t, mail, "To: ops@example.com\r\n")
+
+	err := e.notify(alertChannel{Type: "webhook", URL: ts.URL + "/missing"}, n)
+	assert.EqualError(t, err, strings.TrimPrefix(ts.URL, "http://")+" returned 404 Not Found")
+}
@@ -1,42 +1,68 @@
 package main
 
-type apiLocaiton struct {
-	Lat  float64
-	Long float64
+import "sort"
+
+//apiLocation is the WGS84 center of a region in degrees, 0/0 if unknown
+type apiLocation struct {
+	Lat  float64 `json:"latitude"`
+	Long float64 `json:"longitude"`
 }
 
 type bundeslandStat struct {
-	Name          string
-	Location      apiLocaiton
-	Population    uint64
-	Infected      uint64
-	Dead          uint64
-	Hospitalized  uint64
-	IntensiveCare uint64
+	Name     string      `json:"name"`
+	Location apiLocation `json:"location"`
+	//Population is the number of inhabitants, 0 if unknown
+	Population uint64 `json:"population"`
+	//Infected are the confirmed infections since the start of the pandemic
+	Infected uint64 `json:"infected"`
+	//Dead are the deaths since the start of the pandemic
+	Dead uint64 `json:"dead"`
+	//Hospitalized are the patients currently in hospital, including intensive care
+	Hospitalized uint64 `json:"hospitalized"`
+	//IntensiveCare are the patients currently in intensive care
+	IntensiveCare uint64 `json:"intensive_care"`
+	//Tests are the tests performed since the start of the pandemic, 0 if unknown
+	Tests uint64 `json:"tests"`
+	//InfectedPer100k are the infections per 100.000 inhabitants, 0 if the population is unknown
+	InfectedPer100k float64 `json:"infected_per_100k"`
+	//DeadPer100k are the deaths per 100.000 inhabitants, 0 if the population is unknown
+	DeadPer100k float64 `json:"dead_per_100k"`
 }
 
 type bezirkStat struct {
-	Name       string
-	Location   apiLocaiton
-	Population uint64
-	Infected   uint64
+	Name string `json:"name"`
+	//Province is the Bundesland of the district, empty if unknown
+	Province string      `json:"province"`
+	Location apiLocation `json:"location"`
+	//Population is the number of inhabitants, 0 if unknown
+	Population uint64 `json:"population"`
+	//Infected are the confirmed infections since the start of the pandemic
+	Infected uint64 `json:"infected"`
+	//InfectedPer100k are the infections per 100.000 inhabitants, 0 if the population is unknown
+	InfectedPer100k float64 `json:"infected_per_100k"`
 }
 
 type overallStat struct {
-	TotalInfected            uint64
-	TotalDead                uint64
-	TotalHospitalized        uint64
-	TotalIntensiveCare       uint64
-	AgeDistributionInfection map[string]uint64
+	TotalInfected      uint64 `json:"total_infected"`
+	TotalDead          uint64 `json:"total_dead"`
+	TotalHospitalized  uint64 `json:"total_hospitalized"`
+	TotalIntensiveCare uint64 `json:"total_intensive_care"`
+	//TotalTests are the tests performed in Austria, the sum of the provinces if the national number is missing
+	TotalTests uint64 `json:"total_tests"`
+	//AgeDistributionInfection are the confirmed infections by age group
+	AgeDistributionInfection map[string]uint64 `json:"age_distribution_infection"`
 }
 
 type api struct {
-	he *healthMinistryExporter
-	se *socialMinistryExporter
+	he         *healthMinistryExporter
+	se         *socialMinistryExporter
+	ee         *ecdcExporter
+	mde        *mathdroExporter
+	boundaries *boundaryProvider
 }
 
-func newApi(he *healthMinistryExporter, se *socialMinistryExporter) *api {
-	return &api{he, se}
+func newApi(he *healthMinistryExporter, se *socialMinistryExporter, ee *ecdcExporter, mde *mathdroExporter) *api {
+	return &api{he, se, ee, mde, newBoundaryProvider()}
 }
 
 func (a *api) GetOverallStat() (overallStat, error) {
@@ -56,17 +82,23 @@ func (a *api) GetOverallStat() (overallStat, error) {
 	sumDead := uint64(0)
 	sumHospitalized := uint64(0)
 	sumIntensiveCare := uint64(0)
+	sumTests := uint64(0)
 
 	for _, v := range bundeslandStats {
 		sumInfect += v.Infected
 		sumDead += v.Dead
 		sumHospitalized += v.Hospitalized
 		sumIntensiveCare += v.IntensiveCare
+		sumTests += v.Tests
 	}
 	r.TotalDead = sumDead
 	r.TotalInfected = sumInfect
 	r.TotalHospitalized = sumHospitalized
 	r.TotalIntensiveCare = sumIntensiveCare
+	r.TotalTests = sumTests
+	if tests, err := a.se.getTotalTests(); err == nil && tests > 0 {
+		r.TotalTests = tests
+	}
 
 	confirmed := d.findMetric("cov19_confirmed", "")
 	if confirmed != nil {
@@ -76,6 +108,36 @@ func (a *api) GetOverallStat() (overallStat, error) {
 	return r, nil
 }
 
+func (a *api) GetAgeStat() ([]ageStat, error) {
+	ageStats, err := a.he.getAgeStat()
+	if err != nil {
+		return nil, err
+	}
+	return a.he.ages.getAgeStats("Austria", ageStats), nil
+}
+
+//GetSexStat returns the infections by sex, sorted by sex
+func (a *api) GetSexStat() ([]sexStat, error) {
+	sexStats, err := a.he.getSexStat()
+	if err != nil {
+		return nil, err
+	}
+	total := uint64(0)
+	for _, v := range sexStats {
+		total += v
+	}
+	result := make([]sexStat, 0, len(sexStats))
+	for sex, infected := range sexStats {
+		stat := sexStat{Sex: sex, Infected: infected}
+		if total > 0 {
+			stat.InfectedShare = float64(infected) / float64(total)
+		}
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Sex < result[j].Sex })
+	return result, nil
+}
+
 func (a *api) GetBezirkStat() ([]bezirkStat, error) {
 	return a.he.getBezirkStat()
 }
@@ -93,22 +155,30 @@ func (a *api) GetBundeslandStat() ([]bundeslandStat, error) {
 
 	result := make([]bundeslandStat, 0)
 	for k, v := range bundeslandStats {
-		data := a.se.mp.getMetadata(k)
 		hospitalized := uint64(0)
 		intensiveCare := uint64(0)
 		if v, ok := hospitalStat[k]; ok {
 			hospitalized = v.Hospitalized
 			intensiveCare = v.IntensiveCare
 		}
-		result = append(result, bundeslandStat{
+		stat := bundeslandStat{
 			Name:          k,
 			Infected:      v.infected,
 			Dead:          v.deaths,
-			Population:    data.population,
 			Hospitalized:  hospitalized,
 			IntensiveCare: intensiveCare,
-			Location:      apiLocaiton{Lat: data.location.lat, Long: data.location.long},
-		})
+			Tests:         v.tests,
+		}
+		if data := a.se.mp.getMetadata(k); data != nil {
+			stat.Population = data.population
+			stat.Location = apiLocation{Lat: data.location.lat, Long: data.location.long}
+		}
+		if stat.Population > 0 {
+			stat.InfectedPer100k = infection100k(stat.Infected, stat.Population)
+			stat.DeadPer100k = infection100k(stat.Dead, stat.Population)
+		}
+		result = append(result, stat)
 	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
 	return result, nil
 }
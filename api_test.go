@@ -0,0 +1,114 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const mockSocialMinistryPage = `<html><body><div id="content">
+<p><strong>Bestätigte Fälle</strong>, Stand 30.03.2020, 08:00 Uhr: Burgenland (120), Kärnten (200), Wien (1.500)</p>
+<p><strong>Todesfälle</strong>, Stand 30.03.2020, 08:00 Uhr: Burgenland (2), Wien (30)</p>
+<p><strong>Testungen</strong>: Bisher durchgeführte Testungen in Österreich: 42.000 – Burgenland (2.000), Kärnten (4.000), Wien (20.000)</p>
+</div>
+<table><tbody>
+<tr><td>Burgenland</td><td>10</td><td>2</td></tr>
+<tr><td>Wien</td><td>100</td><td>25</td></tr>
+<tr><td>Österreich</td><td>110</td><td>27</td></tr>
+</tbody></table>
+</body></html>`
+
+var mockHealthMinistryFiles = map[string]string{
+	"/Bezirke.js":               `var dpBezirke = [{"label":"Innsbruck-Land","y":150},{"label":"Atlantis","y":3}];`,
+	"/Bundesland.js":            `var dpBundesland = [{"label":"Bgld","y":120},{"label":"Ktn","y":200},{"label":"W","y":1500}];`,
+	"/Altersverteilung.js":      `var dpAltersverteilung = [{"label":"<5","y":20},{"label":"15-24","y":1800}];`,
+	"/Geschlechtsverteilung.js": `var dpGeschlechtsverteilung = [{"label":"weiblich","y":48},{"label":"männlich","y":52}];`,
+	"/SimpleData.js":            `var Erkrankungen = 1820; var LetzteAktualisierung = "30.03.2020 08:00.00";`,
+}
+
+//mockEcdcPage has the layout of the ECDC table, the last row holds the total and is skipped
+const mockEcdcPage = `<html><body><table><tbody>
+<tr><td>Europe</td><td>Austria</td><td>9618</td><td>108</td></tr>
+<tr><td>Europe</td><td>Italy</td><td>101739</td><td>11591</td></tr>
+<tr><td>America</td><td>United_States_of_America</td><td>140640</td><td>2398</td></tr>
+<tr><td>Oceania</td><td>Atlantis</td><td>7</td><td>0</td></tr>
+<tr><td></td><td>Total</td><td>252004</td><td>14097</td></tr>
+</tbody></table></body></html>`
+
+const mockRecovered = `[
+{"provinceState":null,"countryRegion":"Austria","recovered":636,"lat":47.5162,"long":14.5501},
+{"provinceState":"Washington","countryRegion":"US","recovered":100,"lat":47.4009,"long":-121.4905},
+{"provinceState":"New York","countryRegion":"US","recovered":2,"lat":42.1657,"long":-74.9481}
+]`
+
+//newMockApi returns an api backed by local stand-ins for the ministries, the ECDC and mathdro
+func newMockApi(t *testing.T) (*api, func()) {
+	socialMinistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockSocialMinistryPage))
+	}))
+	healthMinistry := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		content, ok := mockHealthMinistryFiles[r.URL.Path]
+		if !ok {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	h := newHealthMinistryExporter()
+	h.url = healthMinistry.URL
+	s := newSocialMinistryExporter(newMetadataProvider())
+	s.url = socialMinistry.URL
+	s.hospitalURL = socialMinistry.URL
+	ecdc := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockEcdcPage))
+	}))
+	mathdro := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(mockRecovered))
+	}))
+	e := newEcdcExporter(newMetadataProvider())
+	e.Url = ecdc.URL
+	m := newMathdroExporter()
+	m.url = mathdro.URL + "/"
+	return newApi(h, s, e, m), func() {
+		socialMinistry.Close()
+		healthMinistry.Close()
+		ecdc.Close()
+		mathdro.Close()
+	}
+}
+
+func TestMockApi(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	total, err := mockApi.GetOverallStat()
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1820), total.TotalInfected)
+	assert.Equal(t, uint64(32), total.TotalDead)
+	assert.Equal(t, uint64(110), total.TotalHospitalized)
+	assert.Equal(t, uint64(42000), total.TotalTests)
+
+	bundesland, err := mockApi.GetBundeslandStat()
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(bundesland))
+	for _, b := range bundesland {
+		if b.Name == "Wien" {
+			assert.Equal(t, uint64(20000), b.Tests)
+		}
+	}
+
+	metrics, err := mockApi.se.GetMetrics()
+	assert.Nil(t, err)
+	assert.Nil(t, metrics.checkMetric("cov19_tests_detail", "province=Kärnten", func(x float64) bool { return x == 4000 }))
+
+	bezirke, err := mockApi.GetBezirkStat()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(bezirke))
+	assert.Equal(t, uint64(0), bezirke[1].Population)
+
+	sex, err := mockApi.GetSexStat()
+	assert.Nil(t, err)
+	assert.Equal(t, []sexStat{{Sex: "männlich", Infected: 52, InfectedShare: 0.52}, {Sex: "weiblich", Infected: 48, InfectedShare: 0.48}}, sex)
+}
@@ -0,0 +1,287 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/andybalholm/brotli"
+)
+
+//cachedResponse is a successful response of a handler, shared by all requests for the same url and content type
+type cachedResponse struct {
+	header http.Header
+	body   []byte
+	//encoded are the compressed bodies by content encoding, created on the first request accepting them
+	encoded      map[string][]byte
+	etag         string
+	expires      time.Time
+	lastModified time.Time
+}
+
+//maxCacheEntries limits the memory of the cache, query strings are chosen by the clients
+const maxCacheEntries = 1000
+
+//responseCache keeps the responses of the api and metrics for the refresh interval,
+//so polling clients neither trigger requests to the upstream sources nor download unchanged data again
+type responseCache struct {
+	lock     sync.Mutex
+	interval time.Duration
+	entries  map[string]*cachedResponse
+	now      func() time.Time
+	//dataModified is the time the upstream data last changed, zero if unknown
+	dataModified func() time.Time
+}
+
+func newResponseCache(interval time.Duration) *responseCache {
+	return &responseCache{interval: interval, entries: make(map[string]*cachedResponse), now: time.Now, dataModified: upstream.lastChanged}
+}
+
+//setInterval changes the refresh interval and drops all cached responses
+func (c *responseCache) setInterval(interval time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.interval = interval
+	c.entries = make(map[string]*cachedResponse)
+}
+
+//responseRecorder buffers the response of a handler
+type responseRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) Header() http.Header {
+	return r.header
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	if r.status == 0 {
+		r.status = http.StatusOK
+	}
+	return r.body.Write(b)
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	if r.status == 0 {
+		r.status = status
+	}
+}
+
+//cacheKey identifies the response of r, the query parameters are sorted and the host is part of the key
+//because feeds and pages link to baseURL(r)
+func cacheKey(r *http.Request) string {
+	return baseURL(r) + r.URL.EscapedPath() + "?" + r.URL.Query().Encode() + "\n" + r.Header.Get("Accept")
+}
+
+//evict drops the expired entries and, if the cache is still full, the entries expiring first. Callers hold the lock.
+func (c *responseCache) evict(now time.Time) {
+	for key, entry := range c.entries {
+		if !now.Before(entry.expires) {
+			delete(c.entries, key)
+		}
+	}
+	for len(c.entries) >= maxCacheEntries {
+		oldest := ""
+		for key, entry := range c.entries {
+			if oldest == "" || entry.expires.Before(c.entries[oldest].expires) {
+				oldest = key
+			}
+		}
+		delete(c.entries, oldest)
+	}
+}
+
+//lastModified is the Last-Modified header of the handler, the time the upstream data changed or now.
+//It is later than the previous response, otherwise clients would keep the old content with If-Modified-Since.
+func lastModified(header http.Header, previous *cachedResponse, now time.Time, dataModified func() time.Time) time.Time {
+	result, err := http.ParseTime(header.Get("Last-Modified"))
+	if err != nil {
+		result = dataModified()
+	}
+	if result.IsZero() || result.After(now) || (previous != nil && !result.Truncate(time.Second).After(previous.lastModified.Truncate(time.Second))) {
+		return now
+	}
+	return result
+}
+
+//get returns the cached response for r, or runs h and caches its response if it was successful
+func (c *responseCache) get(r *http.Request, h http.HandlerFunc) (*cachedResponse, *responseRecorder) {
+	key := cacheKey(r)
+	c.lock.Lock()
+	entry, ok := c.entries[key]
+	now := c.now()
+	interval := c.interval
+	dataModified := c.dataModified
+	c.lock.Unlock()
+	if ok && now.Before(entry.expires) {
+		return entry, nil
+	}
+
+	recorder := &responseRecorder{header: make(http.Header)}
+	h(recorder, r)
+	if recorder.status == 0 {
+		recorder.status = http.StatusOK
+	}
+	if recorder.status != http.StatusOK {
+		return nil, recorder
+	}
+	sum := sha256.Sum256(recorder.body.Bytes())
+	result := &cachedResponse{
+		header:  recorder.header,
+		body:    recorder.body.Bytes(),
+		encoded: make(map[string][]byte),
+		etag:    `"` + hex.EncodeToString(sum[:16]) + `"`,
+		expires: now.Add(interval),
+	}
+	if ok && entry.etag == result.etag {
+		//the data did not change upstream since the last refresh
+		result.lastModified = entry.lastModified
+		result.encoded = entry.encoded
+	} else {
+		result.lastModified = lastModified(recorder.header, entry, now, dataModified)
+	}
+	c.lock.Lock()
+	if _, exists := c.entries[key]; !exists && len(c.entries) >= maxCacheEntries {
+		c.evict(now)
+	}
+	c.entries[key] = result
+	c.lock.Unlock()
+	return result, nil
+}
+
+//accepts checks the Accept-Encoding header of r for encoding with a quality above 0
+func accepts(r *http.Request, encoding string) bool {
+	for _, accepted := range strings.Split(r.Header.Get("Accept-Encoding"), ",") {
+		parts := strings.Split(accepted, ";")
+		if strings.TrimSpace(parts[0]) != encoding {
+			continue
+		}
+		for _, parameter := range parts[1:] {
+			if q := strings.TrimSpace(parameter); strings.HasPrefix(q, "q=") {
+				quality, err := strconv.ParseFloat(q[2:], 64)
+				return err == nil && quality > 0
+			}
+		}
+		return true
+	}
+	return false
+}
+
+//negotiateEncoding returns br or gzip if the client accepts it, br compresses better and is preferred.
+//An empty result leaves the response uncompressed.
+func negotiateEncoding(r *http.Request) string {
+	for _, encoding := range []string{"br", "gzip"} {
+		if accepts(r, encoding) {
+			return encoding
+		}
+	}
+	return ""
+}
+
+//etagMatches implements If-None-Match for the given (weak or strong) etag
+func etagMatches(ifNoneMatch string, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+func notModified(r *http.Request, etag string, lastModified time.Time) bool {
+	if ifNoneMatch := r.Header.Get("If-None-Match"); ifNoneMatch != "" {
+		return etagMatches(ifNoneMatch, etag)
+	}
+	if since, err := http.ParseTime(r.Header.Get("If-Modified-Since")); err == nil {
+		return !lastModified.Truncate(time.Second).After(since)
+	}
+	return false
+}
+
+//encode compresses body with the content encoding br or gzip
+func encode(body []byte, encoding string) []byte {
+	buffer := bytes.Buffer{}
+	var writer io.WriteCloser = gzip.NewWriter(&buffer)
+	if encoding == "br" {
+		writer = brotli.NewWriter(&buffer)
+	}
+	writer.Write(body)
+	writer.Close()
+	return buffer.Bytes()
+}
+
+//streamed checks whether r asks for ndjson, which is flushed row by row and can not be buffered
+func streamed(r *http.Request) bool {
+	format, err := negotiateFormat(r)
+	return err == nil && format == formatNdjson
+}
+
+//cached serves the responses of h from the cache with ETag, Last-Modified and Cache-Control headers,
+//answers conditional requests with 304 and compresses the response for clients accepting br or gzip.
+//Ndjson responses are passed through uncached.
+func (c *responseCache) cached(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if (r.Method != http.MethodGet && r.Method != http.MethodHead) || streamed(r) {
+			h(w, r)
+			return
+		}
+		entry, recorder := c.get(r, h)
+		if entry == nil {
+			for k, v := range recorder.header {
+				w.Header()[k] = append([]string(nil), v...)
+			}
+			w.WriteHeader(recorder.status)
+			w.Write(recorder.body.Bytes())
+			return
+		}
+
+		body := entry.body
+		etag := entry.etag
+		encoding := negotiateEncoding(r)
+		if encoding != "" {
+			//a strong etag needs to differ between encodings
+			etag = strings.TrimSuffix(etag, `"`) + "-" + encoding + `"`
+		}
+		for k, v := range entry.header {
+			w.Header()[k] = append([]string(nil), v...)
+		}
+		maxAge := entry.expires.Sub(c.now())
+		if maxAge < 0 {
+			maxAge = 0
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", entry.lastModified.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(maxAge.Seconds())))
+		w.Header().Add("Vary", "Accept, Accept-Encoding")
+		if notModified(r, etag, entry.lastModified) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+
+		if encoding != "" {
+			c.lock.Lock()
+			if entry.encoded[encoding] == nil {
+				entry.encoded[encoding] = encode(entry.body, encoding)
+			}
+			body = entry.encoded[encoding]
+			c.lock.Unlock()
+			w.Header().Set("Content-Encoding", encoding)
+		}
+		w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+		if r.Method == http.MethodHead {
+			return
+		}
+		w.Write(body)
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Date(2020, 3, 30, 8, 0, 0, 0, time.UTC)
+	cache := newResponseCache(time.Minute)
+	cache.now = func() time.Time { return now }
+	cache.dataModified = func() time.Time { return time.Date(2020, 3, 30, 7, 30, 0, 0, time.UTC) }
+	calls := 0
+	body := "first"
+	handler := cache.cached(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Add("Content-type", "text/plain")
+		w.Write([]byte(body))
+	})
+
+	get := func(header http.Header) *httptest.ResponseRecorder {
+		request := httptest.NewRequest("GET", "/api/v1/total", nil)
+		for k, v := range header {
+			request.Header[k] = v
+		}
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		return recorder
+	}
+
+	response := get(nil)
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "first", response.Body.String())
+	assert.Equal(t, "text/plain", response.Header().Get("Content-type"))
+	assert.Equal(t, "public, max-age=60", response.Header().Get("Cache-Control"))
+	assert.Equal(t, "Mon, 30 Mar 2020 07:30:00 GMT", response.Header().Get("Last-Modified"), "the time the upstream data changed")
+	etag := response.Header().Get("ETag")
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+
+	now = now.Add(20 * time.Second)
+	response = get(http.Header{"If-None-Match": {`"other", ` + etag}})
+	assert.Equal(t, http.StatusNotModified, response.Code)
+	assert.Equal(t, "public, max-age=40", response.Header().Get("Cache-Control"))
+	assert.Equal(t, 1, calls)
+
+	//unchanged data keeps its etag and Last-Modified after a refresh
+	now = now.Add(time.Minute)
+	response = get(http.Header{"If-Modified-Since": {"Mon, 30 Mar 2020 07:30:00 GMT"}})
+	assert.Equal(t, http.StatusNotModified, response.Code)
+	assert.Equal(t, etag, response.Header().Get("ETag"))
+	assert.Equal(t, 2, calls)
+
+	body = "second"
+	now = now.Add(time.Minute)
+	response = get(http.Header{"If-None-Match": {etag}})
+	assert.Equal(t, http.StatusOK, response.Code)
+	assert.Equal(t, "second", response.Body.String())
+	assert.NotEqual(t, etag, response.Header().Get("ETag"))
+	assert.Equal(t, "Mon, 30 Mar 2020 08:02:20 GMT", response.Header().Get("Last-Modified"), "changed without new upstream data")
+}
+
+func TestResponseCacheKey(t *testing.T) {
+	a := httptest.NewRequest("GET", "http://localhost/chart/total.svg?metric=dead&days=7", nil)
+	b := httptest.NewRequest("GET", "http://localhost/chart/total.svg?days=7&metric=dead", nil)
+	assert.Equal(t, cacheKey(a), cacheKey(b))
+	c := httptest.NewRequest("GET", "http://example.com/chart/total.svg?days=7&metric=dead", nil)
+	assert.NotEqual(t, cacheKey(a), cacheKey(c))
+
+	cache := newResponseCache(time.Minute)
+	handler := cache.cached(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.URL.RawQuery))
+	})
+	for i := 0; i < maxCacheEntries+10; i++ {
+		handler(httptest.NewRecorder(), httptest.NewRequest("GET", fmt.Sprintf("/metrics?x=%d", i), nil))
+	}
+	assert.Equal(t, maxCacheEntries, len(cache.entries))
+}
+
+func TestResponseCacheGzip(t *testing.T) {
+	cache := newResponseCache(time.Minute)
+	handler := cache.cached(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("cov19_confirmed 1820\n"))
+	})
+
+	request := httptest.NewRequest("GET", "/metrics", nil)
+	request.Header.Set("Accept-Encoding", "gzip, deflate")
+	response := httptest.NewRecorder()
+	handler(response, request)
+	assert.Equal(t, "gzip", response.Header().Get("Content-Encoding"))
+	assert.Regexp(t, `-gzip"$`, response.Header().Get("ETag"))
+	assert.Equal(t, "Accept, Accept-Encoding", response.Header().Get("Vary"))
+	reader, err := gzip.NewReader(response.Body)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(reader)
+	assert.Equal(t, "cov19_confirmed 1820\n", string(body))
+
+	//br is preferred over gzip
+	request.Header.Set("Accept-Encoding", "gzip;q=1.0, br;q=0.8")
+	response = httptest.NewRecorder()
+	handler(response, request)
+	assert.Equal(t, "br", response.Header().Get("Content-Encoding"))
+	assert.Regexp(t, `-br"$`, response.Header().Get("ETag"))
+	assert.Equal(t, "Accept, Accept-Encoding", response.Header().Get("Vary"))
+	body, _ = ioutil.ReadAll(brotli.NewReader(response.Body))
+	assert.Equal(t, "cov19_confirmed 1820\n", string(body))
+
+	request.Header.Set("Accept-Encoding", "gzip;q=0, br;q=0")
+	response = httptest.NewRecorder()
+	handler(response, request)
+	assert.Equal(t, "", response.Header().Get("Content-Encoding"))
+	assert.Equal(t, "cov19_confirmed 1820\n", response.Body.String())
+}
+
+func TestResponseCacheNdjson(t *testing.T) {
+	cache := newResponseCache(time.Minute)
+	calls := 0
+	handler := cache.cached(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		writeResponse(w, r, func() (interface{}, error) { return queryTestStats, nil })
+	})
+	for i := 0; i < 2; i++ {
+		request := httptest.NewRequest("GET", "/api/v1/bezirk?format=ndjson", nil)
+		request.Header.Set("Accept-Encoding", "br, gzip")
+		response := httptest.NewRecorder()
+		handler(response, request)
+		assert.True(t, response.Flushed, "every row is flushed to the client")
+		assert.Equal(t, "", response.Header().Get("Content-Encoding"))
+		assert.Equal(t, "", response.Header().Get("ETag"))
+		assert.Equal(t, 4, strings.Count(response.Body.String(), "\n"))
+	}
+	assert.Equal(t, 2, calls, "ndjson is not cached")
+}
+
+func TestResponseCacheErrors(t *testing.T) {
+	cache := newResponseCache(time.Minute)
+	calls := 0
+	handler := cache.cached(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.WriteHeader(500)
+		w.Write([]byte("upstream failed"))
+	})
+	for i := 0; i < 2; i++ {
+		response := httptest.NewRecorder()
+		handler(response, httptest.NewRequest("GET", "/api/v1/world", nil))
+		assert.Equal(t, 500, response.Code)
+		assert.Equal(t, "upstream failed", response.Body.String())
+		assert.Equal(t, "", response.Header().Get("ETag"))
+	}
+	assert.Equal(t, 2, calls)
+}
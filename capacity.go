@@ -0,0 +1,256 @@
+package main
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+//capacityTrendDays is the number of days the growth of the occupancy is fitted to
+const capacityTrendDays = 7
+
+//capacity are the beds of a province available for COVID-19 patients
+type capacity struct {
+	//Beds are the normal care beds, without intensive care
+	Beds              uint64
+	IntensiveCareBeds uint64
+}
+
+//capacityProvider holds the hospital capacity per province. No capacities are bundled,
+//the bed counts change with the plans of the hospital operators and have to be configured.
+type capacityProvider struct {
+	data map[string]capacity
+}
+
+//newCapacityProvider returns a provider without capacities
+func newCapacityProvider() *capacityProvider {
+	return &capacityProvider{data: make(map[string]capacity)}
+}
+
+//loadCapacityProvider reads the capacities of filename, there are none if filename is empty
+func loadCapacityProvider(filename string) (*capacityProvider, error) {
+	result := newCapacityProvider()
+	if filename == "" {
+		return result, nil
+	}
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	err = result.parse(filename, file)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func (p *capacityProvider) parse(filename string, reader io.Reader) error {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = 3
+	records, err := r.ReadAll()
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for i, row := range records {
+		beds, err := strconv.ParseUint(row[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid beds %q", filename, i+1, row[1])
+		}
+		intensiveCareBeds, err := strconv.ParseUint(row[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("%s:%d: invalid intensive care beds %q", filename, i+1, row[2])
+		}
+		p.data[normalizeName(row[0])] = capacity{beds, intensiveCareBeds}
+	}
+	return nil
+}
+
+//getCapacity of a province, Austria is the sum of the provinces
+func (p *capacityProvider) getCapacity(region string) capacity {
+	if normalizeName(region) != normalizeName(austriaRegions[0]) {
+		return p.data[normalizeName(region)]
+	}
+	result := capacity{}
+	for _, province := range austriaRegions[1:] {
+		c := p.getCapacity(province)
+		result.Beds += c.Beds
+		result.IntensiveCareBeds += c.IntensiveCareBeds
+	}
+	return result
+}
+
+//configured is false without a capacities file, no occupancy is computed then
+func (p *capacityProvider) configured() bool {
+	return len(p.data) > 0
+}
+
+//validateCapacities requires the beds of every province once capacities are configured
+func validateCapacities(capacities *capacityProvider) error {
+	if !capacities.configured() {
+		return nil
+	}
+	for _, province := range austriaRegions[1:] {
+		c := capacities.getCapacity(province)
+		if c.Beds == 0 || c.IntensiveCareBeds == 0 {
+			return fmt.Errorf("Missing hospital capacity for %s", province)
+		}
+	}
+	return nil
+}
+
+type capacityStat struct {
+	Name string `json:"name"`
+	//Beds are the normal care beds available for COVID-19 patients
+	Beds              uint64 `json:"beds"`
+	IntensiveCareBeds uint64 `json:"intensive_care_beds"`
+	//NormalCare are the hospitalized patients that are not in intensive care
+	NormalCare    uint64 `json:"normal_care"`
+	IntensiveCare uint64 `json:"intensive_care"`
+	//OccupancyRatio is the share of the normal care beds in use, above 1 if there are more patients than beds
+	OccupancyRatio              float64 `json:"occupancy_ratio"`
+	IntensiveCareOccupancyRatio float64 `json:"intensive_care_occupancy_ratio"`
+	//RemainingBeds are the free normal care beds, negative if there are more patients than beds
+	RemainingBeds              int64 `json:"remaining_beds"`
+	RemainingIntensiveCareBeds int64 `json:"remaining_intensive_care_beds"`
+	//DaysUntilFull is the time until the beds are occupied at the growth of the last 7 days, -1 if the occupancy does not grow
+	DaysUntilFull              float64 `json:"days_until_full"`
+	IntensiveCareDaysUntilFull float64 `json:"intensive_care_days_until_full"`
+}
+
+//daysUntilFull divides the remaining beds by the daily growth of the last capacityTrendDays values
+func daysUntilFull(values []float64, beds uint64) float64 {
+	if len(values) > capacityTrendDays {
+		values = values[len(values)-capacityTrendDays:]
+	}
+	if len(values) == 0 {
+		return -1
+	}
+	remaining := float64(beds) - values[len(values)-1]
+	if remaining <= 0 {
+		return 0
+	}
+	if len(values) < 2 {
+		return -1
+	}
+	n := float64(len(values))
+	meanT, meanY := (n-1)/2, 0.0
+	for _, v := range values {
+		meanY += v / n
+	}
+	sxx, sxy := 0.0, 0.0
+	for t, v := range values {
+		sxx += (float64(t) - meanT) * (float64(t) - meanT)
+		sxy += (float64(t) - meanT) * (v - meanY)
+	}
+	if sxy <= 0 {
+		return -1
+	}
+	return remaining / (sxy / sxx)
+}
+
+func ratio(patients uint64, beds uint64) float64 {
+	if beds == 0 {
+		return 0
+	}
+	return float64(patients) / float64(beds)
+}
+
+//newCapacityStats returns the occupancy of Austria and the provinces in the last snapshot of the history,
+//nothing without configured capacities
+func newCapacityStats(history []*snapshot, capacities *capacityProvider) []capacityStat {
+	result := make([]capacityStat, 0, len(austriaRegions))
+	if !capacities.configured() {
+		return result
+	}
+	for _, region := range austriaRegions {
+		province := region
+		if region == austriaRegions[0] {
+			province = ""
+		}
+		_, hospitalized := chartMetrics["hospitalized"].series(history, province, len(history))
+		_, intensiveCare := chartMetrics["intensive_care"].series(history, province, len(history))
+		if len(hospitalized) == 0 {
+			continue
+		}
+		normalCare := make([]float64, 0, len(hospitalized))
+		for i := range hospitalized {
+			normalCare = append(normalCare, hospitalized[i]-intensiveCare[i])
+		}
+		c := capacities.getCapacity(region)
+		stat := capacityStat{
+			Name:              region,
+			Beds:              c.Beds,
+			IntensiveCareBeds: c.IntensiveCareBeds,
+			IntensiveCare:     uint64(intensiveCare[len(intensiveCare)-1]),
+		}
+		if n := normalCare[len(normalCare)-1]; n > 0 {
+			stat.NormalCare = uint64(n)
+		}
+		stat.OccupancyRatio = ratio(stat.NormalCare, c.Beds)
+		stat.IntensiveCareOccupancyRatio = ratio(stat.IntensiveCare, c.IntensiveCareBeds)
+		stat.RemainingBeds = difference(c.Beds, stat.NormalCare)
+		stat.RemainingIntensiveCareBeds = difference(c.IntensiveCareBeds, stat.IntensiveCare)
+		stat.DaysUntilFull = daysUntilFull(normalCare, c.Beds)
+		stat.IntensiveCareDaysUntilFull = daysUntilFull(intensiveCare, c.IntensiveCareBeds)
+		result = append(result, stat)
+	}
+	return result
+}
+
+//capacityExporter exposes the capacity and occupancy of the provinces, callers hold configLock
+type capacityExporter struct {
+	c          *collector
+	capacities *capacityProvider
+}
+
+func newCapacityExporter(c *collector, capacities *capacityProvider) *capacityExporter {
+	return &capacityExporter{c: c, capacities: capacities}
+}
+
+func (e *capacityExporter) GetMetrics() (metrics, error) {
+	result := make(metrics, 0)
+	for _, s := range newCapacityStats(e.c.dailyHistory(), e.capacities) {
+		if s.Name == austriaRegions[0] {
+			continue
+		}
+		tags := &map[string]string{"province": s.Name}
+		result = append(result,
+			metric{Name: "cov19_beds", Tags: tags, Value: float64(s.Beds)},
+			metric{Name: "cov19_intensive_care_beds", Tags: tags, Value: float64(s.IntensiveCareBeds)},
+			metric{Name: "cov19_occupancy_ratio", Tags: tags, Value: s.OccupancyRatio},
+			metric{Name: "cov19_intensive_care_occupancy_ratio", Tags: tags, Value: s.IntensiveCareOccupancyRatio},
+			metric{Name: "cov19_remaining_beds", Tags: tags, Value: float64(s.RemainingBeds)},
+			metric{Name: "cov19_intensive_care_remaining_beds", Tags: tags, Value: float64(s.RemainingIntensiveCareBeds)})
+		if s.DaysUntilFull >= 0 {
+			result = append(result, metric{Name: "cov19_days_until_full", Tags: tags, Value: s.DaysUntilFull})
+		}
+		if s.IntensiveCareDaysUntilFull >= 0 {
+			result = append(result, metric{Name: "cov19_intensive_care_days_until_full", Tags: tags, Value: s.IntensiveCareDaysUntilFull})
+		}
+	}
+	return result, nil
+}
+
+func (e *capacityExporter) Health() []error {
+	return nil
+}
+
+//handleApiV1Capacity serves the capacity and occupancy of Austria and the provinces
+func (e *capacityExporter) handleApiV1Capacity(w http.ResponseWriter, r *http.Request) {
+	if !e.capacities.configured() {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No hospital capacities configured"))
+		return
+	}
+	history := e.c.dailyHistory()
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	writeListResponse(w, r, capacityStat{}, func() (interface{}, error) { return newCapacityStats(history, e.capacities), nil })
+}
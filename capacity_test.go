@@ -0,0 +1,121 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//testCapacities returns capacities of every province for tests, they are not real bed counts
+func testCapacities(t *testing.T) *capacityProvider {
+	filename := writeTempMetadata(t, "Burgenland,260,22\nKärnten,640,77\nNiederösterreich,1500,178\nOberösterreich,1400,154\n"+
+		"Salzburg,470,62\nSteiermark,1200,143\nTirol,700,100\nVorarlberg,330,33\nWien,1900,300\n")
+	defer os.Remove(filename)
+	result, err := loadCapacityProvider(filename)
+	assert.Nil(t, err)
+	return result
+}
+
+func TestCapacityProvider(t *testing.T) {
+	p := newCapacityProvider()
+	assert.False(t, p.configured())
+	assert.Nil(t, validateCapacities(p), "capacities are optional")
+	p, err := loadCapacityProvider("")
+	assert.Nil(t, err)
+	assert.False(t, p.configured(), "no capacities are bundled")
+
+	p = testCapacities(t)
+	assert.Nil(t, validateCapacities(p))
+	assert.Equal(t, capacity{1900, 300}, p.getCapacity("wien"))
+	assert.Equal(t, capacity{8400, 1069}, p.getCapacity("Austria"))
+	assert.Equal(t, capacity{}, p.getCapacity("Bayern"))
+
+	invalid := writeTempMetadata(t, "Wien,2000,many\n")
+	defer os.Remove(invalid)
+	_, err = loadCapacityProvider(invalid)
+	assert.EqualError(t, err, invalid+`:1: invalid intensive care beds "many"`)
+
+	missing := writeTempMetadata(t, "Wien,1900,300\nTirol,700,0\n")
+	defer os.Remove(missing)
+	p, _ = loadCapacityProvider(missing)
+	assert.EqualError(t, validateCapacities(p), "Missing hospital capacity for Burgenland")
+}
+
+func TestDaysUntilFull(t *testing.T) {
+	assert.Equal(t, -1.0, daysUntilFull(nil, 100))
+	assert.Equal(t, -1.0, daysUntilFull([]float64{50}, 100))
+	assert.Equal(t, 5.0, daysUntilFull([]float64{30, 40, 50}, 100))
+	assert.Equal(t, -1.0, daysUntilFull([]float64{50, 40, 30}, 100))
+	assert.Equal(t, 0.0, daysUntilFull([]float64{90, 110}, 100))
+	assert.Equal(t, 10.0, daysUntilFull([]float64{0, 100, 10, 20, 30, 40, 50, 60, 70}, 170), "only the last 7 days")
+}
+
+func TestCapacityStats(t *testing.T) {
+	history := testHistory(bundeslandStat{Hospitalized: 800, IntensiveCare: 200}, bundeslandStat{Hospitalized: 900, IntensiveCare: 210}, bundeslandStat{Hospitalized: 1000, IntensiveCare: 220})
+	assert.Empty(t, newCapacityStats(history, newCapacityProvider()), "no occupancy without capacities")
+	stats := newCapacityStats(history, testCapacities(t))
+	assert.Equal(t, 3, len(stats), "Austria, Burgenland and Wien")
+	assert.Equal(t, "Austria", stats[0].Name)
+	assert.Equal(t, capacityStat{
+		Name:                        "Wien",
+		Beds:                        1900,
+		IntensiveCareBeds:           300,
+		NormalCare:                  780,
+		IntensiveCare:               220,
+		OccupancyRatio:              780.0 / 1900,
+		IntensiveCareOccupancyRatio: 220.0 / 300,
+		RemainingBeds:               1120,
+		RemainingIntensiveCareBeds:  80,
+		DaysUntilFull:               1120.0 / 90,
+		IntensiveCareDaysUntilFull:  8,
+	}, stats[2])
+	assert.Equal(t, -1.0, stats[1].DaysUntilFull)
+
+	e := newCapacityExporter(newCollector(nil), testCapacities(t))
+	metrics, _ := e.GetMetrics()
+	assert.Empty(t, metrics)
+	e.c.history, e.c.current = history[:2], history[2]
+	unconfigured, _ := newCapacityExporter(e.c, newCapacityProvider()).GetMetrics()
+	assert.Empty(t, unconfigured)
+	metrics, _ = e.GetMetrics()
+	assert.Nil(t, metrics.checkMetric("cov19_intensive_care_occupancy_ratio", "province=Wien", func(x float64) bool { return x == 220.0/300 }))
+	assert.Nil(t, metrics.checkMetric("cov19_intensive_care_days_until_full", "province=Wien", func(x float64) bool { return x == 8 }))
+	assert.Nil(t, metrics.checkMetric("cov19_intensive_care_remaining_beds", "province=Burgenland", func(x float64) bool { return x == 22 }))
+	assert.Nil(t, metrics.findMetric("cov19_intensive_care_days_until_full", "province=Burgenland"))
+	assert.Nil(t, metrics.findMetric("cov19_beds", "province=Austria"))
+}
+
+func TestCapacityHandler(t *testing.T) {
+	e := newCapacityExporter(newCollector(nil), newCapacityProvider())
+	ts := httptest.NewServer(http.HandlerFunc(e.handleApiV1Capacity))
+	defer ts.Close()
+
+	history := testHistory(bundeslandStat{Hospitalized: 800, IntensiveCare: 200}, bundeslandStat{Hospitalized: 900, IntensiveCare: 210})
+	e.c.history, e.c.current = history[:1], history[1]
+	response, err := ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode, "no capacities configured")
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "No hospital capacities configured", string(body))
+
+	e.capacities = testCapacities(t)
+	e.c.history, e.c.current = nil, nil
+	response, err = ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	e.c.history, e.c.current = history[:1], history[1]
+	response, err = ts.Client().Get(ts.URL + "?sort=-intensive_care_occupancy_ratio&top=1")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	stats := make([]capacityStat, 0)
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&stats))
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, "Wien", stats[0].Name)
+	assert.Equal(t, 0.7, stats[0].IntensiveCareOccupancyRatio)
+}
@@ -0,0 +1,298 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//chartMetric is a value of a Bundesland or of Austria that can be charted
+type chartMetric struct {
+	Label string
+	//Days charts the increase of a cumulative value over that many days, 0 charts the value itself
+	Days       int
+	bundesland func(b bundeslandStat) float64
+	total      func(s *snapshot) float64
+}
+
+//infectedPer100k returns the infections per 100k of Austria, only Bundesländer with a population are counted
+func infectedPer100k(s *snapshot) float64 {
+	infected, population := uint64(0), uint64(0)
+	for _, b := range s.Bundesland {
+		if b.Population > 0 {
+			infected += b.Infected
+			population += b.Population
+		}
+	}
+	if population == 0 {
+		return 0
+	}
+	return infection100k(infected, population)
+}
+
+//chartMetrics are the values of the metric query parameter of /chart/ and /map/
+var chartMetrics = map[string]chartMetric{
+	"infected": {"Infected", 0,
+		func(b bundeslandStat) float64 { return float64(b.Infected) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalInfected) }},
+	"new_cases": {"New cases", 1,
+		func(b bundeslandStat) float64 { return float64(b.Infected) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalInfected) }},
+	"dead": {"Dead", 0,
+		func(b bundeslandStat) float64 { return float64(b.Dead) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalDead) }},
+	"new_dead": {"New deaths", 1,
+		func(b bundeslandStat) float64 { return float64(b.Dead) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalDead) }},
+	"hospitalized": {"Hospitalized", 0,
+		func(b bundeslandStat) float64 { return float64(b.Hospitalized) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalHospitalized) }},
+	"intensive_care": {"Intensive care", 0,
+		func(b bundeslandStat) float64 { return float64(b.IntensiveCare) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalIntensiveCare) }},
+	"tests": {"Tests", 0,
+		func(b bundeslandStat) float64 { return float64(b.Tests) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalTests) }},
+	"new_tests": {"New tests", 1,
+		func(b bundeslandStat) float64 { return float64(b.Tests) },
+		func(s *snapshot) float64 { return float64(s.Total.TotalTests) }},
+	"infected_per_100k": {"Infected per 100k", 0,
+		func(b bundeslandStat) float64 { return b.InfectedPer100k },
+		infectedPer100k},
+	"incidence": {"7-day incidence", 7,
+		func(b bundeslandStat) float64 {
+			if b.Population == 0 {
+				return 0
+			}
+			return infection100k(b.Infected, b.Population)
+		},
+		infectedPer100k},
+}
+
+//value returns the metric of the Bundesland province in s, Austria if province is empty
+func (m chartMetric) value(s *snapshot, province string) (float64, bool) {
+	if province == "" {
+		return m.total(s), true
+	}
+	for _, b := range s.Bundesland {
+		if b.Name == province {
+			return m.bundesland(b), true
+		}
+	}
+	return 0, false
+}
+
+//series returns the dates and values of the metric for the last days of the history
+func (m chartMetric) series(history []*snapshot, province string, days int) ([]time.Time, []float64) {
+	dates, values := make([]time.Time, 0, len(history)), make([]float64, 0, len(history))
+	for _, s := range history {
+		if v, ok := m.value(s, province); ok {
+			dates, values = append(dates, s.Time), append(values, v)
+		}
+	}
+	if m.Days > 0 {
+		dates, values = increasesOver(dates, values, m.Days)
+	}
+	if len(values) > days {
+		dates, values = dates[len(dates)-days:], values[len(values)-days:]
+	}
+	return dates, values
+}
+
+//calendarDays is the number of local calendar days from a to b
+func calendarDays(a time.Time, b time.Time) int {
+	a, b = a.Local(), b.Local()
+	from := time.Date(a.Year(), a.Month(), a.Day(), 0, 0, 0, 0, time.UTC)
+	to := time.Date(b.Year(), b.Month(), b.Day(), 0, 0, 0, 0, time.UTC)
+	return int(math.Round(to.Sub(from).Hours() / 24))
+}
+
+//increasesOver returns the increase of every value since the last value at least days calendar days earlier,
+//values without such an earlier value are skipped
+func increasesOver(dates []time.Time, values []float64, days int) ([]time.Time, []float64) {
+	resultDates, resultValues := make([]time.Time, 0, len(dates)), make([]float64, 0, len(values))
+	for i := range values {
+		for j := i - 1; j >= 0; j-- {
+			if calendarDays(dates[j], dates[i]) >= days {
+				resultDates, resultValues = append(resultDates, dates[i]), append(resultValues, values[i]-values[j])
+				break
+			}
+		}
+	}
+	return resultDates, resultValues
+}
+
+//niceStep rounds step up to 1, 2 or 5 times a power of ten
+func niceStep(step float64) float64 {
+	if step <= 0 {
+		return 1
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(step)))
+	for _, f := range []float64{1, 2, 5} {
+		if f*magnitude >= step {
+			return f * magnitude
+		}
+	}
+	return 10 * magnitude
+}
+
+//lineChart draws the values with a y axis starting at 0, or below for negative values, and the first, middle and last date
+func lineChart(title string, dates []time.Time, values []float64, width float64, height float64) *canvas {
+	left, right, top, bottom := 50.0, 10.0, 25.0, 25.0
+	result := newCanvas("chart", width, height)
+	result.rect(0, 0, width, height, "#ffffff")
+	result.text(left, 16, 13, "start", title)
+	if len(values) < 2 {
+		result.text(width/2, height/2, 13, "middle", "No data")
+		return result
+	}
+
+	min, max := 0.0, 0.0
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	step := niceStep((max - min) / 4)
+	min, max = math.Floor(min/step)*step, math.Max(math.Ceil(max/step)*step, min+step)
+	plotWidth, plotHeight := width-left-right, height-top-bottom
+	x := func(i int) float64 { return left + float64(i)*plotWidth/float64(len(values)-1) }
+	y := func(v float64) float64 { return top + plotHeight - (v-min)/(max-min)*plotHeight }
+
+	for tick := min; tick <= max+step/2; tick += step {
+		result.polyline([][2]float64{{left, y(tick)}, {width - right, y(tick)}}, "#e0e0e0", 1)
+		result.text(left-4, y(tick)+4, 10, "end", strconv.FormatFloat(tick, 'f', -1, 64))
+	}
+	result.text(x(0), height-8, 10, "start", dates[0].Format("02.01."))
+	if len(dates) > 2 {
+		result.text(x(len(dates)/2), height-8, 10, "middle", dates[len(dates)/2].Format("02.01."))
+	}
+	result.text(x(len(dates)-1), height-8, 10, "end", dates[len(dates)-1].Format("02.01."))
+	points := make([][2]float64, 0, len(values))
+	for i, v := range values {
+		points = append(points, [2]float64{x(i), y(v)})
+	}
+	result.polyline(points, "#E02F44", 2)
+	return result
+}
+
+//parseImagePath splits name.svg or name.png, ok is false for other formats
+func parseImagePath(p string) (string, string, bool) {
+	format := strings.TrimPrefix(path.Ext(p), ".")
+	if format != "svg" && format != "png" {
+		return "", "", false
+	}
+	return strings.TrimSuffix(p, "."+format), format, true
+}
+
+//parseChartMetric reads the metric query parameter, name is used if it is missing
+func parseChartMetric(r *http.Request, name string) (chartMetric, error) {
+	if m := r.URL.Query().Get("metric"); m != "" {
+		name = m
+	}
+	metric, ok := chartMetrics[name]
+	if !ok {
+		return chartMetric{}, fmt.Errorf("Unknown metric %q", name)
+	}
+	return metric, nil
+}
+
+//writeImage writes the canvas as svg or png
+func writeImage(w http.ResponseWriter, c *canvas, format string) {
+	if format == "svg" {
+		w.Header().Set("Content-Type", "image/svg+xml")
+		w.Write([]byte(c.svg()))
+		return
+	}
+	bytes, err := c.png()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "image/png")
+	w.Write(bytes)
+}
+
+//handleChart serves /chart/total.{svg,png} and /chart/bundesland/{name}.{svg,png} with a line chart of the daily history
+func (c *collector) handleChart(w http.ResponseWriter, r *http.Request) {
+	name, format, ok := parseImagePath(strings.TrimPrefix(r.URL.Path, "/chart/"))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	province, region := "", "Austria"
+	if name != "total" {
+		if !strings.HasPrefix(name, "bundesland/") {
+			http.NotFound(w, r)
+			return
+		}
+		name = strings.TrimPrefix(name, "bundesland/")
+		province = findBundesland(name)
+		if province == "" {
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(fmt.Sprintf("Unknown Bundesland %q", name)))
+			return
+		}
+		region = province
+	}
+	metric, err := parseChartMetric(r, "new_cases")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	days := 30
+	if d := r.URL.Query().Get("days"); d != "" {
+		days, err = strconv.Atoi(d)
+		if err != nil || days < 2 || days > maxHistory {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("days must be between 2 and %d", maxHistory)))
+			return
+		}
+	}
+
+	history := c.dailyHistory()
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	dates, values := metric.series(history, province, days)
+	writeImage(w, lineChart(fmt.Sprintf("%s – %s", region, metric.Label), dates, values, 600, 300), format)
+}
+
+//handleMap serves /map/austria.{svg,png} with the Bundesländer colored by the current value of the metric,
+//increases are taken from the daily history like in charts
+func (c *collector) handleMap(w http.ResponseWriter, r *http.Request) {
+	name, format, ok := parseImagePath(strings.TrimPrefix(r.URL.Path, "/map/"))
+	if !ok || name != "austria" {
+		http.NotFound(w, r)
+		return
+	}
+	metric, err := parseChartMetric(r, "incidence")
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	history := c.dailyHistory()
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	current := history[len(history)-1]
+	regions := make([]mapRegion, 0, len(current.Bundesland))
+	for _, b := range current.Bundesland {
+		//increases are 0 until the history reaches back far enough
+		v := 0.0
+		if dates, values := metric.series(history, b.Name, 1); len(values) > 0 && dates[0].Equal(current.Time) {
+			v = values[0]
+		}
+		regions = append(regions, mapRegion{b.Name, v})
+	}
+	writeImage(w, choropleth(c.api.boundaries, regions, 600, metric.Label), format)
+}
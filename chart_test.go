@@ -0,0 +1,135 @@
+package main
+
+import (
+	"bytes"
+	"image/png"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChartSeries(t *testing.T) {
+	history := make([]*snapshot, 0)
+	for i, infected := range []uint64{100, 110, 130, 160} {
+		s := testSnapshot(infected, infected+120)
+		s.Time = s.Time.Add(time.Duration(i) * 24 * time.Hour)
+		history = append(history, s)
+	}
+
+	dates, values := chartMetrics["new_cases"].series(history, "Wien", 30)
+	assert.Equal(t, []float64{10, 20, 30}, values)
+	assert.Equal(t, history[1].Time, dates[0])
+
+	_, values = chartMetrics["infected"].series(history, "", 2)
+	assert.Equal(t, []float64{250, 280}, values)
+
+	_, values = chartMetrics["infected"].series(history, "Tirol", 30)
+	assert.Empty(t, values)
+
+	v, _ := chartMetrics["infected_per_100k"].value(history[0], "")
+	assert.InDelta(t, 5.29, v, 0.01, "only Bundesländer with population")
+
+	//a missed day is part of the next increase, the 7-day incidence starts after a week
+	_, values = chartMetrics["new_cases"].series(append(history[:2:2], history[3]), "Wien", 30)
+	assert.Equal(t, []float64{10, 50}, values)
+	for i := 4; i < 9; i++ {
+		s := testSnapshot(160+uint64(i-3)*20, 280)
+		s.Time = s.Time.Add(time.Duration(i) * 24 * time.Hour)
+		history = append(history, s)
+	}
+	dates, values = chartMetrics["incidence"].series(history, "Wien", 30)
+	assert.Equal(t, []time.Time{history[7].Time, history[8].Time}, dates)
+	assert.InDelta(t, 140/1889100.0*100000, values[0], 0.0001)
+	assert.InDelta(t, 150/1889100.0*100000, values[1], 0.0001)
+}
+
+func TestLineChart(t *testing.T) {
+	assert.Equal(t, 1.0, niceStep(0.7))
+	assert.Equal(t, 20.0, niceStep(12))
+	assert.Equal(t, 500.0, niceStep(310))
+
+	day := time.Date(2020, 3, 30, 8, 0, 0, 0, time.UTC)
+	svg := lineChart("Wien – New cases", []time.Time{day, day.Add(24 * time.Hour), day.Add(48 * time.Hour)}, []float64{0, 35, 10}, 600, 300).svg()
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" class="chart" width="600" height="300"`))
+	assert.Contains(t, svg, ">Wien – New cases</text>")
+	assert.Contains(t, svg, ">40</text>")
+	assert.Contains(t, svg, ">31.03.</text>")
+	assert.Contains(t, svg, `<polyline points="50.0,275.0 320.0,56.2 590.0,212.5" fill="none" stroke="#E02F44"`)
+
+	assert.Contains(t, lineChart("Empty", nil, nil, 600, 300).svg(), "No data")
+}
+
+func TestChartHandlers(t *testing.T) {
+	c := newCollector(&api{boundaries: newBoundaryProvider()})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/chart/", c.handleChart)
+	mux.HandleFunc("/map/", c.handleMap)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	get := func(path string) (*http.Response, []byte) {
+		response, err := ts.Client().Get(ts.URL + path)
+		assert.Nil(t, err)
+		body, _ := ioutil.ReadAll(response.Body)
+		return response, body
+	}
+
+	response, _ := get("/chart/total.svg")
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	response, _ = get("/map/austria.svg")
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	first := testSnapshot(1500, 1820)
+	c.update(first)
+	second := testSnapshot(1520, 1840)
+	second.Time = first.Time.Add(24 * time.Hour)
+	c.update(second)
+
+	response, body := get("/chart/bundesland/wien.svg?metric=infected&days=10")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "image/svg+xml", response.Header.Get("Content-Type"))
+	assert.Contains(t, string(body), ">Wien – Infected</text>")
+
+	response, body = get("/chart/total.png")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "image/png", response.Header.Get("Content-Type"))
+	img, err := png.Decode(bytes.NewReader(body))
+	assert.Nil(t, err)
+	assert.Equal(t, 600, img.Bounds().Dx())
+	assert.Equal(t, 300, img.Bounds().Dy())
+
+	response, body = get("/map/austria.svg?metric=new_cases")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Contains(t, string(body), "<title>Wien: 20.0</title>", "compared with the previous day")
+	assert.Contains(t, string(body), ">New cases</text>")
+
+	response, body = get("/map/austria.svg")
+	assert.Contains(t, string(body), "<title>Wien: 0.0</title>", "no incidence without a week of history")
+	assert.Contains(t, string(body), ">7-day incidence</text>")
+
+	response, _ = get("/chart/total.svg?days=365")
+	assert.Equal(t, http.StatusOK, response.StatusCode, "the persisted history is not limited to a month")
+
+	response, _ = get("/map/austria.png")
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "image/png", response.Header.Get("Content-Type"))
+
+	for path, status := range map[string]int{
+		"/chart/total.svg?metric=healed": http.StatusBadRequest,
+		"/chart/total.svg?days=1":        http.StatusBadRequest,
+		"/chart/total.svg?days=many":     http.StatusBadRequest,
+		"/chart/bundesland/Bayern.svg":   http.StatusNotFound,
+		"/chart/total.gif":               http.StatusNotFound,
+		"/chart/bezirk/Wien.svg":         http.StatusNotFound,
+		"/map/europe.svg":                http.StatusNotFound,
+		"/map/austria.svg?metric=healed": http.StatusBadRequest,
+	} {
+		response, _ = get(path)
+		assert.Equal(t, status, response.StatusCode, path)
+	}
+}
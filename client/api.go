@@ -0,0 +1,352 @@
+// Code generated by cmd/openapi-client from openapi.json. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"net/url"
+	"time"
+)
+
+type AgeStat struct {
+	// Age group in years, e.g. 15-24
+	Group string `json:"group"`
+	// Confirmed infections in this age group
+	Infected uint64 `json:"infected"`
+	// Infections per 100,000 inhabitants of this age group
+	InfectedPer100k float64 `json:"infected_per_100k"`
+	// Share of all infections (0-1)
+	InfectedShare float64 `json:"infected_share"`
+	// Inhabitants in this age group, 0 if unknown
+	Population uint64 `json:"population"`
+	// Share of the population (0-1)
+	PopulationShare float64 `json:"population_share"`
+}
+
+type BezirkStat struct {
+	// Confirmed infections since the start of the pandemic
+	Infected uint64 `json:"infected"`
+	// Confirmed infections per 100.000 inhabitants, 0 if the population is unknown
+	InfectedPer100k float64  `json:"infected_per_100k"`
+	Location        Location `json:"location"`
+	// Name of the district as published by the ministry
+	Name string `json:"name"`
+	// Number of inhabitants, 0 if unknown
+	Population uint64 `json:"population"`
+	// Province the district belongs to, empty if unknown
+	Province string `json:"province"`
+}
+
+type BundeslandForecast struct {
+	Bundesland string           `json:"bundesland"`
+	Series     []ForecastSeries `json:"series"`
+	// Time of the last snapshot the models are fitted to
+	Time time.Time `json:"time"`
+}
+
+type BundeslandStat struct {
+	// Deaths since the start of the pandemic
+	Dead uint64 `json:"dead"`
+	// Deaths per 100.000 inhabitants, 0 if the population is unknown
+	DeadPer100k float64 `json:"dead_per_100k"`
+	// Patients currently in hospital, including intensive care
+	Hospitalized uint64 `json:"hospitalized"`
+	// Confirmed infections since the start of the pandemic
+	Infected uint64 `json:"infected"`
+	// Confirmed infections per 100.000 inhabitants, 0 if the population is unknown
+	InfectedPer100k float64 `json:"infected_per_100k"`
+	// Patients currently in intensive care
+	IntensiveCare uint64   `json:"intensive_care"`
+	Location      Location `json:"location"`
+	// Name of the province
+	Name string `json:"name"`
+	// Number of inhabitants, 0 if unknown
+	Population uint64 `json:"population"`
+	// Tests performed since the start of the pandemic, 0 if unknown
+	Tests uint64 `json:"tests"`
+}
+
+type CapacityStat struct {
+	// Normal care beds available for COVID-19 patients
+	Beds uint64 `json:"beds"`
+	// Days until the normal care beds are occupied at the growth of the last 7 days, -1 if the occupancy does not grow
+	DaysUntilFull float64 `json:"days_until_full"`
+	IntensiveCare uint64  `json:"intensive_care"`
+	// Intensive care beds available for COVID-19 patients
+	IntensiveCareBeds uint64 `json:"intensive_care_beds"`
+	// Days until the intensive care beds are occupied, -1 if the occupancy does not grow
+	IntensiveCareDaysUntilFull float64 `json:"intensive_care_days_until_full"`
+	// Share of the intensive care beds in use
+	IntensiveCareOccupancyRatio float64 `json:"intensive_care_occupancy_ratio"`
+	Name                        string  `json:"name"`
+	// Hospitalized patients that are not in intensive care
+	NormalCare uint64 `json:"normal_care"`
+	// Share of the normal care beds in use, above 1 if there are more patients than beds
+	OccupancyRatio float64 `json:"occupancy_ratio"`
+	// Free normal care beds, negative if there are more patients than beds
+	RemainingBeds              int64 `json:"remaining_beds"`
+	RemainingIntensiveCareBeds int64 `json:"remaining_intensive_care_beds"`
+}
+
+type ContinentStat struct {
+	// Number of countries with reported cases
+	Countries uint64 `json:"countries"`
+	// Deaths of all countries
+	Dead uint64 `json:"dead"`
+	// Deaths per 100.000 inhabitants, only counting countries with a known population
+	DeadPer100k float64 `json:"dead_per_100k"`
+	// Share of the infected that died, between 0 and 1
+	FatalityRate float64 `json:"fatality_rate"`
+	// Confirmed infections of all countries
+	Infected uint64 `json:"infected"`
+	// Confirmed infections per 100.000 inhabitants, only counting countries with a known population
+	InfectedPer100k float64 `json:"infected_per_100k"`
+	// Name of the continent
+	Name string `json:"name"`
+	// Number of inhabitants of the countries with a known population
+	Population uint64 `json:"population"`
+	// Recovered cases of all countries
+	Recovered uint64 `json:"recovered"`
+}
+
+type FieldChange struct {
+	// Value in the current snapshot
+	Current float64 `json:"current"`
+	// current - previous
+	Delta float64 `json:"delta"`
+	// Value in the previous snapshot, 0 for new regions
+	Previous float64 `json:"previous"`
+}
+
+// ForecastPoint: Forecast of a day, all values are at least 0
+type ForecastPoint struct {
+	// Forecasted day
+	Date time.Time `json:"date"`
+	// Lower bound of the 95% prediction interval
+	Lower float64 `json:"lower"`
+	// Upper bound of the 95% prediction interval
+	Upper float64 `json:"upper"`
+	Value float64 `json:"value"`
+}
+
+type ForecastSeries struct {
+	// Empty if less than 3 days were collected
+	Forecast []ForecastPoint `json:"forecast"`
+	Metric   string          `json:"metric"`
+	Model    string          `json:"model"`
+}
+
+// Intervention: Reduces the contacts from day on until the next intervention
+type Intervention struct {
+	// First day of the intervention, 0 is the seed
+	Day int64 `json:"day"`
+	// Share of contacts prevented between 0 and 1, 0.4 lowers the reproduction number by 40%
+	Reduction float64 `json:"reduction"`
+}
+
+// Location: WGS84 coordinates of the center of a region
+type Location struct {
+	// Latitude in degrees, 0 if unknown
+	Latitude float64 `json:"latitude"`
+	// Longitude in degrees, 0 if unknown
+	Longitude float64 `json:"longitude"`
+}
+
+type OverallStat struct {
+	// Confirmed infections by age group
+	AgeDistributionInfection map[string]uint64 `json:"age_distribution_infection"`
+	// Deaths in Austria since the start of the pandemic
+	TotalDead uint64 `json:"total_dead"`
+	// Patients currently in hospital, including intensive care
+	TotalHospitalized uint64 `json:"total_hospitalized"`
+	// Confirmed infections in Austria since the start of the pandemic
+	TotalInfected uint64 `json:"total_infected"`
+	// Patients currently in intensive care
+	TotalIntensiveCare uint64 `json:"total_intensive_care"`
+	// Tests performed in Austria since the start of the pandemic
+	TotalTests uint64 `json:"total_tests"`
+}
+
+type RegionChange struct {
+	// Changed numeric fields by their name in the api of the scope
+	Changes map[string]FieldChange `json:"changes"`
+	// Name of the province, district or country, Austria for the total scope
+	Region string `json:"region"`
+}
+
+// SimulationDay: State at the end of a simulated day
+type SimulationDay struct {
+	Date    time.Time `json:"date"`
+	Day     int64     `json:"day"`
+	Dead    float64   `json:"dead"`
+	Exposed float64   `json:"exposed"`
+	// Cases since the start of the pandemic, a case counts once it is infectious
+	Infected   float64 `json:"infected"`
+	Infectious float64 `json:"infectious"`
+	NewCases   float64 `json:"new_cases"`
+	// Effective reproduction number
+	R           float64 `json:"r"`
+	Recovered   float64 `json:"recovered"`
+	Susceptible float64 `json:"susceptible"`
+}
+
+type SimulationParameters struct {
+	// Number of simulated days, 180 by default
+	Days int64 `json:"days"`
+	// Share of the infectious cases that die, 0.01 by default
+	FatalityRate float64 `json:"fatality_rate"`
+	// Mean time from exposure until a case is infectious, 5.2 by default
+	IncubationDays float64 `json:"incubation_days"`
+	// Mean time a case is infectious, 5 by default
+	InfectiousDays float64        `json:"infectious_days"`
+	Interventions  []Intervention `json:"interventions"`
+	// Basic reproduction number without interventions, 2.5 by default
+	R0 float64 `json:"r0"`
+}
+
+type TestStat struct {
+	// Days since the previous daily snapshot with tests, the increases are divided by them, 0 if there is none
+	Days int64 `json:"days"`
+	// Austria or the name of the province
+	Name string `json:"name"`
+	// Confirmed infections per day since the previous daily snapshot, 0 if unknown
+	NewCases int64 `json:"new_cases"`
+	// Tests performed per day since the previous daily snapshot, 0 if unknown
+	NewTests int64 `json:"new_tests"`
+	// Share of the new tests that were positive, 0 without new tests
+	PositivityRate float64 `json:"positivity_rate"`
+	// Tests performed since the start of the pandemic
+	Tests uint64 `json:"tests"`
+}
+
+type UpdateEvent struct {
+	// Increasing id of the event, send it as Last-Event-ID to resume a stream
+	Id      uint64         `json:"id"`
+	Regions []RegionChange `json:"regions"`
+	// Scope of the changed stats, also used as event type
+	Scope string `json:"scope"`
+	// Time the changes were collected
+	Time time.Time `json:"time"`
+}
+
+type WorldStat struct {
+	// Continent as published by the ECDC
+	Continent string `json:"continent"`
+	// Deaths reported by the ECDC
+	Dead uint64 `json:"dead"`
+	// Deaths per 100.000 inhabitants, 0 if the population is unknown
+	DeadPer100k float64 `json:"dead_per_100k"`
+	// Share of the infected that died, between 0 and 1
+	FatalityRate float64 `json:"fatality_rate"`
+	// Confirmed infections reported by the ECDC
+	Infected uint64 `json:"infected"`
+	// Confirmed infections per 100.000 inhabitants, 0 if the population is unknown
+	InfectedPer100k float64  `json:"infected_per_100k"`
+	Location        Location `json:"location"`
+	// Name of the country
+	Name string `json:"name"`
+	// Number of inhabitants, 0 if unknown
+	Population uint64 `json:"population"`
+	// Recovered cases reported by mathdro, summed over all provinces
+	Recovered uint64 `json:"recovered"`
+}
+
+// GetAge returns infections per age group in Austria (GET /api/v1/age)
+func (c *Client) GetAge(ctx context.Context) ([]AgeStat, error) {
+	result := make([]AgeStat, 0)
+	err := c.get(ctx, "/api/v1/age", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetBezirk returns stats per district (GET /api/v1/bezirk)
+func (c *Client) GetBezirk(ctx context.Context) ([]BezirkStat, error) {
+	result := make([]BezirkStat, 0)
+	err := c.get(ctx, "/api/v1/bezirk", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetBundesland returns stats per province (GET /api/v1/bundesland)
+func (c *Client) GetBundesland(ctx context.Context) ([]BundeslandStat, error) {
+	result := make([]BundeslandStat, 0)
+	err := c.get(ctx, "/api/v1/bundesland", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetBundeslandForecast returns forecast of the daily cases and hospital load of a province (GET /api/v1/bundesland/{name}/forecast)
+func (c *Client) GetBundeslandForecast(ctx context.Context, name string) (*BundeslandForecast, error) {
+	result := BundeslandForecast{}
+	err := c.get(ctx, "/api/v1/bundesland/"+url.PathEscape(name)+"/forecast", &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetCapacity returns hospital capacity and occupancy of Austria and every province (GET /api/v1/capacity)
+func (c *Client) GetCapacity(ctx context.Context) ([]CapacityStat, error) {
+	result := make([]CapacityStat, 0)
+	err := c.get(ctx, "/api/v1/capacity", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetContinent returns stats per continent (GET /api/v1/continent)
+func (c *Client) GetContinent(ctx context.Context) ([]ContinentStat, error) {
+	result := make([]ContinentStat, 0)
+	err := c.get(ctx, "/api/v1/continent", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTests returns tests and positivity rate of Austria and every province (GET /api/v1/tests)
+func (c *Client) GetTests(ctx context.Context) ([]TestStat, error) {
+	result := make([]TestStat, 0)
+	err := c.get(ctx, "/api/v1/tests", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetTotal returns stats for Austria (GET /api/v1/total)
+func (c *Client) GetTotal(ctx context.Context) (*OverallStat, error) {
+	result := OverallStat{}
+	err := c.get(ctx, "/api/v1/total", &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetWorld returns stats per country (GET /api/v1/world)
+func (c *Client) GetWorld(ctx context.Context) ([]WorldStat, error) {
+	result := make([]WorldStat, 0)
+	err := c.get(ctx, "/api/v1/world", &result)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetCountry returns stats of a single country (GET /api/v1/world/{country})
+func (c *Client) GetCountry(ctx context.Context, country string) (*WorldStat, error) {
+	result := WorldStat{}
+	err := c.get(ctx, "/api/v1/world/"+url.PathEscape(country), &result)
+	if err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
@@ -0,0 +1,57 @@
+//Package client is a typed client for the json api of covid19-at.
+//The types and methods in api.go are generated from openapi.json.
+package client
+
+//go:generate go run ../cmd/openapi-client -spec ../openapi.json -out api.go
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//Client calls the api of a covid19-at exporter
+type Client struct {
+	BaseURL    string
+	HTTPClient *http.Client
+}
+
+//New returns a client for the exporter running at baseURL, e.g. http://localhost:8282
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), HTTPClient: &http.Client{Timeout: 10 * time.Second}}
+}
+
+//Error is returned for responses with a status code other than 200
+type Error struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("covid19-at api returned %d: %s", e.StatusCode, e.Message)
+}
+
+func (c *Client) get(ctx context.Context, path string, result interface{}) error {
+	request, err := http.NewRequestWithContext(ctx, http.MethodGet, c.BaseURL+path, nil)
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Accept", "application/json")
+	response, err := c.HTTPClient.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return &Error{StatusCode: response.StatusCode, Message: string(body)}
+	}
+	return json.Unmarshal(body, result)
+}
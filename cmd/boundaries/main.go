@@ -0,0 +1,241 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"math"
+	"os"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+type geometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type feature struct {
+	Type       string                 `json:"type"`
+	Properties map[string]interface{} `json:"properties"`
+	Geometry   *geometry              `json:"geometry"`
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+type ring [][2]float64
+
+//districtKey matches the names of Statistik Austria and bezirke.csv, e.g. "Klagenfurt Stadt" and "Klagenfurt(Stadt)"
+func districtKey(name string) string {
+	result := strings.Builder{}
+	for _, r := range strings.ToLower(name) {
+		if unicode.IsLetter(r) {
+			result.WriteRune(r)
+		}
+	}
+	return result.String()
+}
+
+//distance of p to the line through a and b
+func distance(p, a, b [2]float64) float64 {
+	dx, dy := b[0]-a[0], b[1]-a[1]
+	if dx == 0 && dy == 0 {
+		return math.Hypot(p[0]-a[0], p[1]-a[1])
+	}
+	return math.Abs(dy*p[0]-dx*p[1]+b[0]*a[1]-b[1]*a[0]) / math.Hypot(dx, dy)
+}
+
+//simplify removes the points of a line closer than tolerance to the simplified line (Douglas-Peucker)
+func simplify(points ring, tolerance float64) ring {
+	if len(points) < 3 {
+		return append(ring{}, points...)
+	}
+	index, max := 0, 0.0
+	for i := 1; i < len(points)-1; i++ {
+		if d := distance(points[i], points[0], points[len(points)-1]); d > max {
+			index, max = i, d
+		}
+	}
+	if max <= tolerance {
+		return ring{points[0], points[len(points)-1]}
+	}
+	left := simplify(points[:index+1], tolerance)
+	return append(left[:len(left)-1], simplify(points[index:], tolerance)...)
+}
+
+//simplifyRing keeps a closed ring of at least 4 points with coordinates rounded to precision decimals
+func simplifyRing(r ring, tolerance float64, precision float64) ring {
+	result := simplify(r, tolerance)
+	if len(result) < 4 {
+		result = r
+	}
+	for i := range result {
+		result[i] = [2]float64{math.Round(result[i][0]*precision) / precision, math.Round(result[i][1]*precision) / precision}
+	}
+	return result
+}
+
+//simplifyGeometry simplifies the rings of a Polygon or MultiPolygon and drops holes that become too small
+func simplifyGeometry(g *geometry, tolerance float64, precision float64) (*geometry, error) {
+	polygons := make([][]ring, 0)
+	switch g.Type {
+	case "Polygon":
+		p := []ring{}
+		if err := json.Unmarshal(g.Coordinates, &p); err != nil {
+			return nil, err
+		}
+		polygons = append(polygons, p)
+	case "MultiPolygon":
+		if err := json.Unmarshal(g.Coordinates, &polygons); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("Unsupported geometry type %s", g.Type)
+	}
+	for i, p := range polygons {
+		simplified := []ring{simplifyRing(p[0], tolerance, precision)}
+		for _, hole := range p[1:] {
+			if h := simplify(hole, tolerance); len(h) >= 4 {
+				simplified = append(simplified, simplifyRing(hole, tolerance, precision))
+			}
+		}
+		polygons[i] = simplified
+	}
+	var coordinates interface{} = polygons
+	if g.Type == "Polygon" {
+		coordinates = polygons[0]
+	}
+	bytes, err := json.Marshal(coordinates)
+	return &geometry{Type: g.Type, Coordinates: bytes}, err
+}
+
+func readFeatures(filename string) (featureCollection, error) {
+	result := featureCollection{}
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return result, err
+	}
+	err = json.Unmarshal(bytes, &result)
+	if err != nil {
+		return result, fmt.Errorf("%s: %v", filename, err)
+	}
+	return result, nil
+}
+
+//readDistricts returns the names of bezirke.csv by their districtKey
+func readDistricts(filename string) (map[string]string, error) {
+	file, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	r := csv.NewReader(file)
+	r.FieldsPerRecord = -1
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	result := make(map[string]string)
+	for _, row := range records {
+		result[districtKey(row[0])] = row[0]
+	}
+	return result, nil
+}
+
+//writeFeatures writes one feature per line like the bundled grenzen.geojson
+func writeFeatures(filename string, features []feature) error {
+	lines := make([]string, 0, len(features))
+	for _, f := range features {
+		bytes, err := json.Marshal(f)
+		if err != nil {
+			return err
+		}
+		lines = append(lines, string(bytes))
+	}
+	return ioutil.WriteFile(filename, []byte("{\"type\":\"FeatureCollection\",\"features\":[\n"+strings.Join(lines, ",\n")+"\n]}\n"), 0644)
+}
+
+//boundaries simplifies the district polygons of Statistik Austria (data.statistik.gv.at, Politische Bezirke,
+//converted to GeoJSON in WGS84) and replaces the districts of grenzen.geojson with them
+func main() {
+	input := flag.String("input", "", "GeoJSON with the district polygons")
+	property := flag.String("property", "name", "property of the input features holding the district name")
+	bezirke := flag.String("bezirke", "bezirke.csv", "districts the features are matched with")
+	output := flag.String("output", "grenzen.geojson", "file the simplified districts are merged into")
+	tolerance := flag.Float64("tolerance", 0.005, "maximum deviation from the original outline in degrees")
+	flag.Parse()
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "-input is required")
+		os.Exit(2)
+	}
+
+	districts, err := readDistricts(*bezirke)
+	if err != nil {
+		panic(err)
+	}
+	source, err := readFeatures(*input)
+	if err != nil {
+		panic(err)
+	}
+	target, err := readFeatures(*output)
+	if err != nil {
+		panic(err)
+	}
+
+	simplified := make(map[string]feature)
+	unmatched := make([]string, 0)
+	for _, f := range source.Features {
+		name, _ := f.Properties[*property].(string)
+		district, ok := districts[districtKey(name)]
+		if !ok || f.Geometry == nil {
+			unmatched = append(unmatched, name)
+			continue
+		}
+		g, err := simplifyGeometry(f.Geometry, *tolerance, 1000)
+		if err != nil {
+			panic(fmt.Errorf("%s: %v", name, err))
+		}
+		simplified[district] = feature{Type: "Feature", Properties: map[string]interface{}{"name": district}, Geometry: g}
+	}
+
+	features := make([]feature, 0, len(target.Features)+len(simplified))
+	for _, f := range target.Features {
+		name, _ := f.Properties["name"].(string)
+		if _, ok := simplified[name]; !ok {
+			features = append(features, f)
+		}
+	}
+	names := make([]string, 0, len(simplified))
+	for name := range simplified {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		features = append(features, simplified[name])
+	}
+	err = writeFeatures(*output, features)
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d districts written to %s\n", len(simplified), *output)
+	if len(unmatched) > 0 {
+		fmt.Fprintf(os.Stderr, "No district in %s for: %s\n", *bezirke, strings.Join(unmatched, ", "))
+	}
+	missing := make([]string, 0)
+	for key, name := range districts {
+		if _, ok := simplified[name]; !ok && key != districtKey("Wien") {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		fmt.Fprintf(os.Stderr, "Missing polygons for: %s\n", strings.Join(missing, ", "))
+	}
+}
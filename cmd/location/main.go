@@ -10,6 +10,7 @@ import (
 	"net/url"
 	"os"
 	"strconv"
+	"time"
 )
 
 type mapsResponse struct {
@@ -28,8 +29,15 @@ type mapLocation struct {
 	longitude float64
 }
 
+var client = http.Client{Timeout: 10 * time.Second}
+
 func getLocation(location string, key string) (*mapLocation, error) {
-	response, err := http.Get(fmt.Sprintf("https://maps.googleapis.com/maps/api/place/findplacefromtext/json?input=%s&inputtype=textquery&&fields=geometry&key=%s", url.QueryEscape(location), key))
+	request, err := http.NewRequest(http.MethodGet, fmt.Sprintf("https://maps.googleapis.com/maps/api/place/findplacefromtext/json?input=%s&inputtype=textquery&&fields=geometry&key=%s", url.QueryEscape(location), key), nil)
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("User-Agent", "covid19-at location (+https://github.com/cinemast/covid19-at)")
+	response, err := client.Do(request)
 	if err != nil {
 		return nil, err
 	}
@@ -76,7 +84,11 @@ func main() {
 		if err != nil {
 			fmt.Println(err.Error())
 		}
-		fmt.Println(fmt.Sprintf("%s,%s,%f,%f", location, r[1], loc.latitude, loc.longitude))
+		line := fmt.Sprintf("%s,%s,%f,%f", location, r[1], loc.latitude, loc.longitude)
+		if len(r) > 4 {
+			line += "," + r[4]
+		}
+		fmt.Println(line)
 	}
 
 }
@@ -0,0 +1,209 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+type schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 string             `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Deprecated           bool               `json:"deprecated"`
+	Properties           map[string]*schema `json:"properties"`
+	Items                *schema            `json:"items"`
+	AdditionalProperties *schema            `json:"additionalProperties"`
+}
+
+type operation struct {
+	OperationID string `json:"operationId"`
+	Summary     string `json:"summary"`
+	Deprecated  bool   `json:"deprecated"`
+	Responses   map[string]struct {
+		Content map[string]struct {
+			Schema *schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+type spec struct {
+	Paths      map[string]map[string]operation `json:"paths"`
+	Components struct {
+		Schemas map[string]*schema `json:"schemas"`
+	} `json:"components"`
+}
+
+func exported(name string) string {
+	parts := strings.FieldsFunc(name, func(r rune) bool { return r == '_' || r == '-' || r == '.' })
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func refName(ref string) string {
+	return ref[strings.LastIndex(ref, "/")+1:]
+}
+
+func goType(s *schema) string {
+	if s.Ref != "" {
+		return refName(s.Ref)
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return "time.Time"
+		}
+		return "string"
+	case "boolean":
+		return "bool"
+	case "number":
+		return "float64"
+	case "integer":
+		if s.Format == "uint64" {
+			return "uint64"
+		}
+		return "int64"
+	case "array":
+		return "[]" + goType(s.Items)
+	case "object":
+		if s.AdditionalProperties != nil {
+			return "map[string]" + goType(s.AdditionalProperties)
+		}
+	}
+	return "interface{}"
+}
+
+func sortedKeys(m map[string]*schema) []string {
+	result := make([]string, 0, len(m))
+	for k := range m {
+		result = append(result, k)
+	}
+	sort.Strings(result)
+	return result
+}
+
+func writeTypes(out *bytes.Buffer, s *spec) {
+	for _, name := range sortedKeys(s.Components.Schemas) {
+		typeSchema := s.Components.Schemas[name]
+		if typeSchema.Deprecated {
+			continue
+		}
+		if typeSchema.Description != "" {
+			fmt.Fprintf(out, "// %s: %s\n", name, typeSchema.Description)
+		}
+		fmt.Fprintf(out, "type %s struct {\n", name)
+		for _, property := range sortedKeys(typeSchema.Properties) {
+			propertySchema := typeSchema.Properties[property]
+			if propertySchema.Description != "" {
+				fmt.Fprintf(out, "// %s\n", propertySchema.Description)
+			}
+			fmt.Fprintf(out, "%s %s `json:\"%s\"`\n", exported(property), goType(propertySchema), property)
+		}
+		fmt.Fprintf(out, "}\n\n")
+	}
+}
+
+//pathArguments returns the go parameters and the expression building the url path for path templates like /world/{country}
+func pathArguments(path string) (string, string) {
+	parameters := ""
+	parts := make([]string, 0)
+	for path != "" {
+		start := strings.Index(path, "{")
+		end := strings.Index(path, "}")
+		if start < 0 || end < start {
+			parts = append(parts, fmt.Sprintf("%q", path))
+			break
+		}
+		name := path[start+1 : end]
+		if start > 0 {
+			parts = append(parts, fmt.Sprintf("%q", path[:start]))
+		}
+		parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", name))
+		parameters += fmt.Sprintf(", %s string", name)
+		path = path[end+1:]
+	}
+	return parameters, strings.Join(parts, "+")
+}
+
+func writeMethods(out *bytes.Buffer, s *spec) {
+	paths := make([]string, 0, len(s.Paths))
+	for path := range s.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	for _, path := range paths {
+		get, ok := s.Paths[path]["get"]
+		if !ok || get.OperationID == "" || get.Deprecated {
+			continue
+		}
+		content, ok := get.Responses["200"].Content["application/json"]
+		if !ok || content.Schema == nil || (content.Schema.Ref == "" && (content.Schema.Items == nil || content.Schema.Items.Ref == "")) {
+			continue
+		}
+		resultType := goType(content.Schema)
+		name := exported(get.OperationID)
+		parameters, pathExpression := pathArguments(path)
+		fmt.Fprintf(out, "// %s returns %s (GET %s)\n", name, strings.ToLower(get.Summary[:1])+get.Summary[1:], path)
+		if content.Schema.Ref != "" {
+			fmt.Fprintf(out, "func (c *Client) %s(ctx context.Context%s) (*%s, error) {\nresult := %s{}\nerr := c.get(ctx, %s, &result)\nif err != nil {\nreturn nil, err\n}\nreturn &result, nil\n}\n\n", name, parameters, resultType, resultType, pathExpression)
+		} else {
+			fmt.Fprintf(out, "func (c *Client) %s(ctx context.Context%s) (%s, error) {\nresult := make(%s, 0)\nerr := c.get(ctx, %s, &result)\nif err != nil {\nreturn nil, err\n}\nreturn result, nil\n}\n\n", name, parameters, resultType, resultType, pathExpression)
+		}
+	}
+}
+
+func generate(specBytes []byte, pkg string) ([]byte, error) {
+	s := &spec{}
+	err := json.Unmarshal(specBytes, s)
+	if err != nil {
+		return nil, err
+	}
+	body := &bytes.Buffer{}
+	writeTypes(body, s)
+	writeMethods(body, s)
+
+	out := &bytes.Buffer{}
+	fmt.Fprintf(out, "// Code generated by cmd/openapi-client from openapi.json. DO NOT EDIT.\n\npackage %s\n\n", pkg)
+	fmt.Fprintf(out, "import (\n\"context\"\n")
+	if bytes.Contains(body.Bytes(), []byte("url.PathEscape")) {
+		fmt.Fprintf(out, "\"net/url\"\n")
+	}
+	if bytes.Contains(body.Bytes(), []byte("time.Time")) {
+		fmt.Fprintf(out, "\"time\"\n")
+	}
+	fmt.Fprintf(out, ")\n\n")
+	out.Write(body.Bytes())
+	return format.Source(out.Bytes())
+}
+
+func main() {
+	specFile := flag.String("spec", "openapi.json", "OpenAPI document to generate the client from")
+	outFile := flag.String("out", "client/api.go", "file to write the client to")
+	pkg := flag.String("package", "client", "package name of the generated code")
+	flag.Parse()
+
+	specBytes, err := ioutil.ReadFile(*specFile)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	source, err := generate(specBytes, *pkg)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	err = ioutil.WriteFile(*outFile, source, 0644)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
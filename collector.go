@@ -0,0 +1,440 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"sync"
+	"time"
+)
+
+//snapshot holds the stats of all scopes collected at the same time
+type snapshot struct {
+	Time       time.Time
+	Total      overallStat
+	Bundesland []bundeslandStat
+	Bezirk     []bezirkStat
+	Age        []ageStat
+	Sex        []sexStat
+	World      []worldStat
+	//Sources are the upstream urls that published new content since the previous snapshot
+	Sources []upstreamChange
+}
+
+type fieldChange struct {
+	Previous float64 `json:"previous"`
+	Current  float64 `json:"current"`
+	Delta    float64 `json:"delta"`
+}
+
+type regionChange struct {
+	Region string `json:"region"`
+	//Changes are the numeric fields that differ from the previous snapshot by their json name
+	Changes map[string]fieldChange `json:"changes"`
+}
+
+//updateEvent describes the changes of one scope between two snapshots
+type updateEvent struct {
+	//ID increases with every event, it is used to resume streams with Last-Event-ID
+	ID      uint64         `json:"id"`
+	Scope   string         `json:"scope"`
+	Time    time.Time      `json:"time"`
+	Regions []regionChange `json:"regions"`
+}
+
+//dataUpdate groups the events of one collection with the upstream urls that changed
+type dataUpdate struct {
+	Time    time.Time
+	Sources []upstreamChange
+	Events  []updateEvent
+}
+
+//eventScopes are the scopes update events are emitted for
+var eventScopes = []string{"total", "bundesland", "bezirk", "world"}
+
+//maxEvents is the number of events kept for resuming streams
+const maxEvents = 500
+
+//maxHistory is the number of daily snapshots kept for comparisons, charts and forecasts, about three years
+const maxHistory = 1100
+
+//collector reads all scopes every refresh interval and publishes the changes to its subscribers
+type collector struct {
+	lock        sync.RWMutex
+	api         *api
+	interval    time.Duration
+	current     *snapshot
+	previous    *snapshot
+	daily       *snapshot
+	history     []*snapshot
+	historyFile string
+	//saveLock serializes the writes of the history file, savedTime is the last day written
+	saveLock    sync.Mutex
+	savedTime   time.Time
+	events      []updateEvent
+	updates     []dataUpdate
+	nextID      uint64
+	subscribers map[chan updateEvent]bool
+	now         func() time.Time
+}
+
+func newCollector(a *api) *collector {
+	return &collector{
+		api:         a,
+		interval:    defaultConfig().refreshInterval(),
+		nextID:      1,
+		subscribers: make(map[chan updateEvent]bool),
+		now:         time.Now,
+	}
+}
+
+func (c *collector) setInterval(interval time.Duration) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.interval = interval
+}
+
+//run collects a snapshot every refresh interval, at least every minute if caching is disabled
+func (c *collector) run() {
+	for {
+		configLock.RLock()
+		s := c.collect()
+		configLock.RUnlock()
+		c.update(s)
+		if err := c.saveHistory(); err != nil {
+			logger.Printf("Saving the history failed: %v", err)
+		}
+
+		c.lock.RLock()
+		interval := c.interval
+		c.lock.RUnlock()
+		if interval <= 0 {
+			interval = defaultConfig().refreshInterval()
+		}
+		time.Sleep(interval)
+	}
+}
+
+//collect reads all scopes, scopes that fail keep the data of the last snapshot
+func (c *collector) collect() *snapshot {
+	c.lock.RLock()
+	result := snapshot{}
+	since := time.Time{}
+	if c.current != nil {
+		result = *c.current
+		since = c.current.Time
+	}
+	result.Time = c.now()
+	c.lock.RUnlock()
+
+	if total, err := c.api.GetOverallStat(); err == nil {
+		result.Total = total
+	} else {
+		logger.Printf("Collecting total failed: %v", err)
+	}
+	if bundesland, err := c.api.GetBundeslandStat(); err == nil {
+		result.Bundesland = bundesland
+	} else {
+		logger.Printf("Collecting bundesland failed: %v", err)
+	}
+	if bezirk, err := c.api.GetBezirkStat(); err == nil {
+		result.Bezirk = bezirk
+	} else {
+		logger.Printf("Collecting bezirk failed: %v", err)
+	}
+	if age, err := c.api.GetAgeStat(); err == nil {
+		result.Age = age
+	} else {
+		logger.Printf("Collecting age failed: %v", err)
+	}
+	if sex, err := c.api.GetSexStat(); err == nil {
+		result.Sex = sex
+	} else {
+		logger.Printf("Collecting sex failed: %v", err)
+	}
+	if world, err := c.api.GetWorldStat(); err == nil {
+		result.World = world
+	} else {
+		logger.Printf("Collecting world failed: %v", err)
+	}
+	result.Sources = upstream.changedSince(since)
+	return &result
+}
+
+//update makes s the current snapshot and publishes the changes to the previous one
+func (c *collector) update(s *snapshot) []updateEvent {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	previous := c.current
+	c.previous = previous
+	c.current = s
+	if previous == nil {
+		c.daily = s
+		//after a restart the last persisted day is the daily snapshot
+		if len(c.history) > 0 && !sameDay(c.history[len(c.history)-1].Time, s.Time) {
+			c.daily = c.history[len(c.history)-1]
+		}
+		return nil
+	}
+	if !sameDay(previous.Time, s.Time) {
+		c.daily = previous
+		c.history = append(c.history, previous)
+		if len(c.history) > maxHistory {
+			c.history = c.history[len(c.history)-maxHistory:]
+		}
+	}
+
+	result := make([]updateEvent, 0)
+	for _, scope := range eventScopes {
+		regions := diffRows(scopeRows(previous, scope), scopeRows(s, scope))
+		if len(regions) == 0 {
+			continue
+		}
+		e := updateEvent{ID: c.nextID, Scope: scope, Time: s.Time, Regions: regions}
+		c.nextID++
+		result = append(result, e)
+		c.events = append(c.events, e)
+		for subscriber := range c.subscribers {
+			//slow subscribers miss events instead of blocking the collector
+			select {
+			case subscriber <- e:
+			default:
+				logger.Printf("Dropped %s event %d for a subscriber with %d pending events", e.Scope, e.ID, len(subscriber))
+			}
+		}
+	}
+	if len(c.events) > maxEvents {
+		c.events = c.events[len(c.events)-maxEvents:]
+	}
+	if len(result) > 0 {
+		c.updates = append(c.updates, dataUpdate{Time: s.Time, Sources: s.Sources, Events: result})
+		if len(c.updates) > maxEvents {
+			c.updates = c.updates[len(c.updates)-maxEvents:]
+		}
+	}
+	return result
+}
+
+func sameDay(a time.Time, b time.Time) bool {
+	a, b = a.Local(), b.Local()
+	return a.Year() == b.Year() && a.YearDay() == b.YearDay()
+}
+
+//scopeRows returns the rows of a scope as a slice of structs with a name
+func scopeRows(s *snapshot, scope string) interface{} {
+	switch scope {
+	case "total":
+		return []interface{}{struct {
+			Name string
+			overallStat
+		}{"Austria", s.Total}}
+	case "bundesland":
+		return s.Bundesland
+	case "bezirk":
+		return s.Bezirk
+	}
+	return s.World
+}
+
+func rowName(v reflect.Value) string {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	return v.FieldByName("Name").String()
+}
+
+//numericFields returns the top level numeric fields of a struct by their json name, embedded structs are included
+func numericFields(v reflect.Value, result map[string]float64) map[string]float64 {
+	for v.Kind() == reflect.Interface {
+		v = v.Elem()
+	}
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		if field.Anonymous {
+			numericFields(v.Field(i), result)
+		} else if isNumeric(field.Type) && field.PkgPath == "" {
+			result[fieldName(field)] = numericValue(v.Field(i))
+		}
+	}
+	return result
+}
+
+//diffRows compares the numeric fields of rows with the same name, new rows are compared against 0
+func diffRows(previous interface{}, current interface{}) []regionChange {
+	before := make(map[string]map[string]float64)
+	for _, row := range rows(previous) {
+		before[rowName(row)] = numericFields(row, make(map[string]float64))
+	}
+	result := make([]regionChange, 0)
+	for _, row := range rows(current) {
+		name := rowName(row)
+		old := before[name]
+		changes := make(map[string]fieldChange)
+		for field, value := range numericFields(row, make(map[string]float64)) {
+			if old[field] != value {
+				changes[field] = fieldChange{Previous: old[field], Current: value, Delta: value - old[field]}
+			}
+		}
+		if len(changes) > 0 {
+			result = append(result, regionChange{Region: name, Changes: changes})
+		}
+	}
+	return result
+}
+
+//subscribe returns a channel receiving all following events
+func (c *collector) subscribe() chan updateEvent {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	result := make(chan updateEvent, 64)
+	c.subscribers[result] = true
+	return result
+}
+
+func (c *collector) unsubscribe(subscriber chan updateEvent) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	delete(c.subscribers, subscriber)
+}
+
+//eventsSince returns the kept events with an id above id
+func (c *collector) eventsSince(id uint64) []updateEvent {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	result := make([]updateEvent, 0)
+	for _, e := range c.events {
+		if e.ID > id {
+			result = append(result, e)
+		}
+	}
+	return result
+}
+
+//dataUpdates returns the kept collections that changed data, oldest first
+func (c *collector) dataUpdates() []dataUpdate {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return append([]dataUpdate{}, c.updates...)
+}
+
+//snapshots returns the current and the previous snapshot, both are nil before the first collection
+func (c *collector) snapshots() (*snapshot, *snapshot) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.current, c.previous
+}
+
+//dailySnapshots returns the current snapshot and the last snapshot of the previous day,
+//which is the first snapshot until the day changes
+func (c *collector) dailySnapshots() (*snapshot, *snapshot) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.current, c.daily
+}
+
+//snapshotAt returns the last daily snapshot taken at or before t, nil if there is none
+func (c *collector) snapshotAt(t time.Time) *snapshot {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	for i := len(c.history) - 1; i >= 0; i-- {
+		if !c.history[i].Time.After(t) {
+			return c.history[i]
+		}
+	}
+	return nil
+}
+
+//dailyHistory returns the daily snapshots followed by the current one, oldest first
+func (c *collector) dailyHistory() []*snapshot {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	result := append([]*snapshot{}, c.history...)
+	if c.current != nil {
+		result = append(result, c.current)
+	}
+	return result
+}
+
+//readHistory reads a json array of daily snapshots, oldest first, a missing file is an empty history
+func readHistory(filename string) ([]*snapshot, error) {
+	result := make([]*snapshot, 0)
+	bytes, err := ioutil.ReadFile(filename)
+	if os.IsNotExist(err) {
+		return result, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, &result)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return result, nil
+}
+
+//writeHistory replaces filename with the daily snapshots, the file is renamed into place so readers never see a partial history
+func writeHistory(filename string, history []*snapshot) error {
+	bytes, err := json.Marshal(history)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(filename+".tmp", bytes, 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(filename+".tmp", filename)
+}
+
+//loadHistory reads the daily snapshots of filename and keeps writing new ones to it
+func (c *collector) loadHistory(filename string) error {
+	history, err := readHistory(filename)
+	if err != nil {
+		return err
+	}
+	if len(history) > maxHistory {
+		history = history[len(history)-maxHistory:]
+	}
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.historyFile = filename
+	c.history = history
+	if len(history) > 0 {
+		c.savedTime = history[len(history)-1].Time
+	}
+	return nil
+}
+
+//appendHistory adds s as the last snapshot of its day and saves the history
+func (c *collector) appendHistory(s *snapshot) error {
+	c.lock.Lock()
+	c.history = append(c.history, s)
+	if len(c.history) > maxHistory {
+		c.history = c.history[len(c.history)-maxHistory:]
+	}
+	c.lock.Unlock()
+	return c.saveHistory()
+}
+
+//saveHistory writes the daily snapshots to the history file once a day newer than savedTime was added
+func (c *collector) saveHistory() error {
+	c.saveLock.Lock()
+	defer c.saveLock.Unlock()
+	c.lock.RLock()
+	filename := c.historyFile
+	history := append([]*snapshot{}, c.history...)
+	savedTime := c.savedTime
+	c.lock.RUnlock()
+	if filename == "" || len(history) == 0 || history[len(history)-1].Time.Equal(savedTime) {
+		return nil
+	}
+	err := writeHistory(filename, history)
+	if err != nil {
+		return err
+	}
+	c.lock.Lock()
+	c.savedTime = history[len(history)-1].Time
+	c.lock.Unlock()
+	return nil
+}
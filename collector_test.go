@@ -0,0 +1,180 @@
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSnapshot(infectedWien uint64, totalInfected uint64) *snapshot {
+	return &snapshot{
+		Time:  time.Date(2020, 3, 30, 8, 0, 0, 0, time.UTC),
+		Total: overallStat{TotalInfected: totalInfected, TotalDead: 32},
+		Bundesland: []bundeslandStat{
+			{Name: "Burgenland", Infected: 120},
+			{Name: "Wien", Infected: infectedWien, Population: 1889100},
+		},
+	}
+}
+
+//testHistory returns a daily snapshot for every row of Wien, starting at the time of testSnapshot.
+//The totals of Austria are the sums of Burgenland and Wien.
+func testHistory(wien ...bundeslandStat) []*snapshot {
+	result := make([]*snapshot, 0, len(wien))
+	for i, w := range wien {
+		s := testSnapshot(0, 0)
+		s.Time = s.Time.Add(time.Duration(i) * 24 * time.Hour)
+		w.Name, w.Population = "Wien", s.Bundesland[1].Population
+		s.Bundesland[1] = w
+		for _, b := range s.Bundesland {
+			s.Total.TotalInfected += b.Infected
+			s.Total.TotalTests += b.Tests
+			s.Total.TotalHospitalized += b.Hospitalized
+			s.Total.TotalIntensiveCare += b.IntensiveCare
+		}
+		result = append(result, s)
+	}
+	return result
+}
+
+func TestCollectorUpdate(t *testing.T) {
+	c := newCollector(nil)
+	events := c.subscribe()
+	defer c.unsubscribe(events)
+
+	assert.Nil(t, c.update(testSnapshot(1500, 1820)))
+	assert.Equal(t, 0, len(c.update(testSnapshot(1500, 1820))))
+
+	result := c.update(testSnapshot(1520, 1840))
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, updateEvent{ID: 1, Scope: "total", Time: result[0].Time, Regions: []regionChange{
+		{Region: "Austria", Changes: map[string]fieldChange{"total_infected": {Previous: 1820, Current: 1840, Delta: 20}}},
+	}}, result[0])
+	assert.Equal(t, "bundesland", result[1].Scope)
+	assert.Equal(t, []regionChange{{Region: "Wien", Changes: map[string]fieldChange{"infected": {Previous: 1500, Current: 1520, Delta: 20}}}}, result[1].Regions)
+	assert.Equal(t, uint64(1), (<-events).ID)
+	assert.Equal(t, uint64(2), (<-events).ID)
+
+	assert.Equal(t, 1, len(c.eventsSince(1)))
+	current, previous := c.snapshots()
+	assert.Equal(t, uint64(1520), current.Bundesland[1].Infected)
+	assert.Equal(t, uint64(1500), previous.Bundesland[1].Infected)
+}
+
+func TestCollectorDaily(t *testing.T) {
+	c := newCollector(nil)
+	first := testSnapshot(1500, 1820)
+	c.update(first)
+	second := testSnapshot(1520, 1840)
+	second.Time = second.Time.Add(time.Hour)
+	c.update(second)
+	current, daily := c.dailySnapshots()
+	assert.Equal(t, second, current)
+	assert.Equal(t, first, daily)
+
+	//the last snapshot before the day changed becomes the daily one
+	third := testSnapshot(1600, 1920)
+	third.Time = second.Time.Add(24 * time.Hour)
+	c.update(third)
+	_, daily = c.dailySnapshots()
+	assert.Equal(t, second, daily)
+
+	assert.Nil(t, c.snapshotAt(second.Time.Add(-time.Minute)))
+	assert.Equal(t, second, c.snapshotAt(third.Time))
+}
+
+func TestCollectorHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history.json")
+
+	c := newCollector(nil)
+	assert.Nil(t, c.loadHistory(file), "a missing file is an empty history")
+	first := testSnapshot(1500, 1820)
+	c.update(first)
+	assert.Nil(t, c.saveHistory())
+	_, err = os.Stat(file)
+	assert.True(t, os.IsNotExist(err), "nothing to save before the day changes")
+
+	second := testSnapshot(1600, 1920)
+	second.Time = first.Time.Add(24 * time.Hour)
+	c.update(second)
+	assert.Nil(t, c.saveHistory())
+
+	//a restarted collector continues with the saved days
+	restarted := newCollector(nil)
+	assert.Nil(t, restarted.loadHistory(file))
+	history := restarted.dailyHistory()
+	if assert.Equal(t, 1, len(history)) {
+		assert.True(t, first.Time.Equal(history[0].Time))
+		assert.Equal(t, uint64(1500), history[0].Bundesland[1].Infected)
+	}
+	third := testSnapshot(1700, 2020)
+	third.Time = second.Time.Add(time.Hour)
+	restarted.update(third)
+	_, daily := restarted.dailySnapshots()
+	assert.True(t, first.Time.Equal(daily.Time))
+
+	assert.Nil(t, ioutil.WriteFile(file, []byte("{"), 0644))
+	assert.NotNil(t, newCollector(nil).loadHistory(file))
+}
+
+func TestCollectorHistoryConcurrentSaves(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history.json")
+
+	c := newCollector(nil)
+	assert.Nil(t, c.loadHistory(file))
+	first := testSnapshot(1500, 1820)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 4; i++ {
+		wg.Add(2)
+		day := testSnapshot(1500, 1820)
+		day.Time = first.Time.Add(time.Duration(i) * 24 * time.Hour)
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, c.appendHistory(day))
+		}()
+		go func() {
+			defer wg.Done()
+			assert.Nil(t, c.saveHistory())
+		}()
+	}
+	wg.Wait()
+	assert.Nil(t, c.saveHistory())
+
+	history, err := readHistory(file)
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(history))
+}
+
+func TestCollect(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	c := newCollector(mockApi)
+	s := c.collect()
+	assert.Equal(t, uint64(1820), s.Total.TotalInfected)
+	assert.Equal(t, 3, len(s.Bundesland))
+	assert.Equal(t, 2, len(s.Bezirk))
+	assert.Equal(t, 4, len(s.World))
+	c.update(s)
+
+	//failing sources keep the data of the last snapshot
+	noRetries := testUpstreamConfig()
+	noRetries.Retries = 0
+	upstream.fetcher.configure(noRetries)
+	defer upstream.fetcher.configure(testUpstreamConfig())
+	closeMock()
+	s = c.collect()
+	assert.Equal(t, 3, len(s.Bundesland))
+	assert.Equal(t, 0, len(c.update(s)))
+}
@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"strings"
+	"time"
+)
+
+type config struct {
+	//Listen is the address the http server binds to
+	Listen string `json:"listen"`
+	//Metadata is an optional csv file merged on top of the embedded metadata.csv
+	Metadata string `json:"metadata"`
+	//Bezirke is an optional csv file merged on top of the embedded bezirke.csv
+	Bezirke string `json:"bezirke"`
+	//AgeGroups is an optional csv file merged on top of the embedded altersgruppen.csv
+	AgeGroups string `json:"age_groups"`
+	//Capacities is a csv file with the hospital beds of every province, required for the capacity api and metrics
+	Capacities string `json:"capacities"`
+	//Boundaries is an optional GeoJSON FeatureCollection merged on top of the embedded grenzen.geojson
+	Boundaries string `json:"boundaries"`
+	//RefreshInterval is the number of seconds responses of the api and metrics are cached before the sources are read again
+	RefreshInterval int `json:"refresh_interval"`
+	//AdminToken enables the /admin endpoints for requests with a matching bearer token
+	AdminToken string `json:"admin_token"`
+	//Sources are the upstream urls of the exporters
+	Sources sources `json:"sources"`
+	//Upstream configures how the sources are read
+	Upstream upstreamConfig `json:"upstream"`
+	//Webhooks are notified by the collector about new data and threshold crossings
+	Webhooks []webhookConfig `json:"webhooks"`
+	//Alerts are rules evaluated on the collected snapshots and the channels notified when they fire
+	Alerts alertConfig `json:"alerts"`
+}
+
+type upstreamConfig struct {
+	//Timeout is the number of seconds a single request may take
+	Timeout float64 `json:"timeout"`
+	//Retries is the number of times a request is repeated after a timeout or a 5xx response
+	Retries int `json:"retries"`
+	//Backoff is the number of seconds to wait before the first retry, it doubles with every retry and is jittered by ±50%
+	Backoff float64 `json:"backoff"`
+	//MaxConcurrent limits the parallel requests per host, 0 means unlimited
+	MaxConcurrent int `json:"max_concurrent"`
+	//RequestsPerSecond limits the request rate per host, 0 means unlimited
+	RequestsPerSecond float64 `json:"requests_per_second"`
+	//UserAgent is sent with every request
+	UserAgent string `json:"user_agent"`
+	//Contact is sent as From header so the operators of a source can reach us
+	Contact string `json:"contact"`
+	//Proxy is the url of a http proxy, the HTTP_PROXY environment variables are used if empty
+	Proxy string `json:"proxy"`
+}
+
+type sources struct {
+	HealthMinistry  string `json:"health_ministry"`
+	SocialMinistry  string `json:"social_ministry"`
+	Hospitalization string `json:"hospitalization"`
+	Ecdc            string `json:"ecdc"`
+	Mathdro         string `json:"mathdro"`
+}
+
+func defaultConfig() *config {
+	return &config{
+		Listen:          ":8282",
+		RefreshInterval: 60,
+		Upstream: upstreamConfig{
+			Timeout:           5,
+			Retries:           2,
+			Backoff:           1,
+			MaxConcurrent:     2,
+			RequestsPerSecond: 5,
+			UserAgent:         "covid19-at (+https://github.com/cinemast/covid19-at)",
+		},
+		Sources: sources{
+			HealthMinistry:  "https://info.gesundheitsministerium.at/data",
+			SocialMinistry:  "https://www.sozialministerium.at/Informationen-zum-Coronavirus/Neuartiges-Coronavirus-(2019-nCov).html",
+			Hospitalization: "https://www.sozialministerium.at/Informationen-zum-Coronavirus/Dashboard/Zahlen-zur-Hospitalisierung",
+			Ecdc:            "https://www.ecdc.europa.eu/en/geographical-distribution-2019-ncov-cases",
+			Mathdro:         "https://covid19.mathdro.id/api/",
+		},
+	}
+}
+
+//loadConfig reads a json config file, missing values are taken from defaultConfig
+func loadConfig(filename string) (*config, error) {
+	result := defaultConfig()
+	if filename == "" {
+		return result, nil
+	}
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	err = json.Unmarshal(bytes, result)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	if result.RefreshInterval < 0 {
+		return nil, fmt.Errorf("%s: refresh_interval needs to be 0 or more seconds", filename)
+	}
+	err = result.Upstream.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	for i, w := range result.Webhooks {
+		err = w.validate()
+		if err != nil {
+			return nil, fmt.Errorf("%s: webhook %d: %v", filename, i, err)
+		}
+	}
+	err = result.Alerts.validate()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", filename, err)
+	}
+	return result, result.Sources.validate()
+}
+
+func (u upstreamConfig) validate() error {
+	if u.Timeout <= 0 {
+		return fmt.Errorf("upstream.timeout needs to be more than 0 seconds")
+	}
+	if u.Retries < 0 || u.Backoff < 0 || u.MaxConcurrent < 0 || u.RequestsPerSecond < 0 {
+		return fmt.Errorf("upstream.retries, backoff, max_concurrent and requests_per_second can not be negative")
+	}
+	if u.Proxy != "" && !strings.HasPrefix(u.Proxy, "http://") && !strings.HasPrefix(u.Proxy, "https://") {
+		return fmt.Errorf("Invalid url for upstream.proxy: %q", u.Proxy)
+	}
+	return nil
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
+
+func (c *config) refreshInterval() time.Duration {
+	return time.Duration(c.RefreshInterval) * time.Second
+}
+
+func (s sources) validate() error {
+	for name, url := range map[string]string{
+		"health_ministry": s.HealthMinistry,
+		"social_ministry": s.SocialMinistry,
+		"hospitalization": s.Hospitalization,
+		"ecdc":            s.Ecdc,
+		"mathdro":         s.Mathdro,
+	} {
+		if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+			return fmt.Errorf("Invalid url for source %s: %q", name, url)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,368 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+)
+
+//gridWidth is the number of columns of a Grafana dashboard
+const gridWidth = 24
+
+//dashboardDatasource is the Prometheus datasource provisioned from config/datasource
+const dashboardDatasource = "Prometheus"
+
+var metricPattern = regexp.MustCompile(`(?:[a-z]+:)?(cov19_[a-z0-9_]+)(:[a-z0-9]+)?`)
+var legendPattern = regexp.MustCompile(`{{\s*([a-z_]+)\s*}}`)
+
+//dashboardLinks are the pages of the sources linked from the panels
+var dashboardLinks = map[string]string{
+	"health_ministry": "https://info.gesundheitsministerium.at",
+	"social_ministry": "https://www.sozialministerium.at/Informationen-zum-Coronavirus/Neuartiges-Coronavirus-(2019-nCov).html",
+	"ecdc":            "https://www.ecdc.europa.eu/en/geographical-distribution-2019-ncov-cases",
+	"mathdro":         "https://covid19.mathdro.id/api",
+}
+
+//mapView is the initial center and zoom of a map panel
+type mapView struct {
+	Latitude  string
+	Longitude string
+	Zoom      string
+}
+
+var mapViews = map[string]mapView{
+	"austria": {"47.5", "13.5", "7"},
+	"wien":    {"48.208735", "16.372015", "12"},
+	"world":   {"30", "10", "1"},
+}
+
+type panelSpec struct {
+	Title string
+	//Type is row, stat, graph, stacked (graph), pie or map
+	Type   string
+	Expr   string
+	Legend string
+	Unit   string
+	Width  int
+	Height int
+	//Source is the key of the link in dashboardLinks
+	Source string
+	//Map is the key of the view in mapViews, the panel shows the label Label of the metric at its latitude and longitude
+	Map   string
+	Label string
+}
+
+type dashboardSpec struct {
+	UID     string
+	Title   string
+	File    string
+	From    string
+	Refresh string
+	Panels  []panelSpec
+}
+
+//dashboards are generated with covid19-at -dashboards config/dashboard
+var dashboards = []dashboardSpec{
+	{
+		UID:     "2fa2-Y_Wz222",
+		Title:   "Covid-19-2",
+		File:    "cov19-2.json",
+		From:    "2020-02-29T23:00:00.000Z",
+		Refresh: "1h",
+		Panels: []panelSpec{
+			{Title: "Austria", Type: "row"},
+			{Title: "Infected", Type: "stat", Expr: "cov19_confirmed", Width: 4, Height: 4, Source: "health_ministry"},
+			{Title: "Tested", Type: "stat", Expr: "cov19_tests", Width: 3, Height: 4, Source: "social_ministry"},
+			{Title: "Deaths", Type: "stat", Expr: "sum(cov19_detail_dead)", Width: 3, Height: 4, Source: "social_ministry"},
+			{Title: "Hospitalized", Type: "stat", Expr: "cov19_hospitalized", Width: 4, Height: 4, Source: "social_ministry"},
+			{Title: "In intensive care", Type: "stat", Expr: "cov19_intensive_care", Width: 4, Height: 4, Source: "social_ministry"},
+			{Title: "Healed", Type: "stat", Expr: `sum(cov19_world_recovered{country="Austria"})`, Width: 3, Height: 4, Source: "mathdro"},
+			{Title: "Fatality rate", Type: "stat", Expr: "sum(cov19_detail_dead) / sum(cov19_detail)", Unit: "percentunit", Width: 3, Height: 4, Source: "social_ministry"},
+			{Title: "Infections by Bezirk", Type: "map", Expr: "cov19_bezirk_infected", Map: "austria", Label: "bezirk", Width: 12, Height: 17, Source: "health_ministry"},
+			{Title: "Infections by Bezirk per 100k population", Type: "map", Expr: "cov19_bezirk_infected_100k", Map: "austria", Label: "bezirk", Width: 12, Height: 17, Source: "health_ministry"},
+			{Title: "Age distribution", Type: "pie", Expr: "cov19_age_distribution", Legend: "{{group}} years", Width: 12, Height: 9, Source: "health_ministry"},
+			{Title: "Sex distribution", Type: "pie", Expr: "cov19_sex_distribution", Legend: "{{sex}}", Width: 12, Height: 9, Source: "health_ministry"},
+			{Title: "Infections by Province (stacked)", Type: "stacked", Expr: `max(cov19_detail{country="Austria"}) by (province)`, Legend: "{{province}}", Width: 12, Height: 10, Source: "social_ministry"},
+			{Title: "Deaths by Province (stacked)", Type: "stacked", Expr: `max(cov19_detail_dead{country="Austria"}) by (province)`, Legend: "{{province}}", Width: 12, Height: 10, Source: "social_ministry"},
+			{Title: "New infections by Province", Type: "graph", Expr: "province:cov19_detail:increase1d", Legend: "{{province}}", Width: 12, Height: 10, Source: "social_ministry"},
+			{Title: "New infections by Bezirk in the last 7 days", Type: "graph", Expr: "topk(10, bezirk:cov19_bezirk_infected:increase7d)", Legend: "{{bezirk}}", Width: 12, Height: 10, Source: "health_ministry"},
+			{Title: "Infections by Province", Type: "graph", Expr: `max(cov19_detail{country="Austria"}) by (province)`, Legend: "{{province}}", Width: 12, Height: 10, Source: "social_ministry"},
+			{Title: "Infections by Bezirk (stacked)", Type: "stacked", Expr: "max(cov19_bezirk_infected) by (bezirk)", Legend: "{{bezirk}}", Width: 12, Height: 10, Source: "health_ministry"},
+			{Title: "Infections by Province", Type: "map", Expr: "cov19_detail", Map: "austria", Label: "province", Width: 8, Height: 14, Source: "social_ministry"},
+			{Title: "Infections by Province per 100k population", Type: "map", Expr: "cov19_detail_infected_per_100k", Map: "austria", Label: "province", Width: 8, Height: 14, Source: "social_ministry"},
+			{Title: "Hospitalized by Province", Type: "map", Expr: "cov19_hospitalized_detail", Map: "austria", Label: "province", Width: 8, Height: 14, Source: "social_ministry"},
+			{Title: "Hospitalized by Province", Type: "graph", Expr: "cov19_hospitalized_detail", Legend: "{{province}}", Width: 12, Height: 8, Source: "social_ministry"},
+			{Title: "Intensive care patients by Province", Type: "graph", Expr: "cov19_intensive_care_detail", Legend: "{{province}}", Width: 12, Height: 8, Source: "social_ministry"},
+			{Title: "Europe", Type: "row"},
+			{Title: "Infections in Europe", Type: "stat", Expr: `sum(cov19_world_infected{continent="Europe"})`, Width: 8, Height: 4, Source: "ecdc"},
+			{Title: "Deaths in Europe", Type: "stat", Expr: `sum(cov19_world_death{continent="Europe"})`, Width: 8, Height: 4, Source: "ecdc"},
+			{Title: "Fatality rate in Europe", Type: "stat", Expr: `sum(cov19_world_death{continent="Europe"}) / sum(cov19_world_infected{continent="Europe"})`, Unit: "percentunit", Width: 8, Height: 4, Source: "ecdc"},
+			{Title: "Infections in Europe (stacked)", Type: "stacked", Expr: `max(cov19_world_infected{continent="Europe"}) by (country) > 0`, Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Deaths in Europe (stacked)", Type: "stacked", Expr: `max(cov19_world_death{continent="Europe"}) by (country) > 0`, Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Infections in Europe", Type: "graph", Expr: `max(cov19_world_infected{continent="Europe"}) by (country) > 0`, Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Deaths in Europe", Type: "graph", Expr: `max(cov19_world_death{continent="Europe"}) by (country) > 0`, Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "New infections in Europe", Type: "graph", Expr: `topk(10, country:cov19_world_infected:increase1d and on (country) cov19_world_infected{continent="Europe"})`, Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Infections per 100k population in Europe", Type: "graph", Expr: `cov19_world_infected_per_100k{continent="Europe"}`, Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "World", Type: "row"},
+			{Title: "Infections worldwide", Type: "stat", Expr: "sum(cov19_world_infected)", Width: 8, Height: 4, Source: "ecdc"},
+			{Title: "Deaths worldwide", Type: "stat", Expr: "sum(cov19_world_death)", Width: 8, Height: 4, Source: "ecdc"},
+			{Title: "Fatality rate worldwide", Type: "stat", Expr: "sum(cov19_world_death) / sum(cov19_world_infected)", Unit: "percentunit", Width: 8, Height: 4, Source: "ecdc"},
+			{Title: "Infections worldwide", Type: "map", Expr: "cov19_world_infected", Map: "world", Label: "country", Width: 12, Height: 16, Source: "ecdc"},
+			{Title: "Infections per 100k population worldwide", Type: "map", Expr: "cov19_world_infected_per_100k", Map: "world", Label: "country", Width: 12, Height: 16, Source: "ecdc"},
+			{Title: "Infections worldwide by country", Type: "graph", Expr: "max(cov19_world_infected) by (country) > 0", Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Infections worldwide by country (stacked)", Type: "stacked", Expr: "max(cov19_world_infected) by (country) > 0", Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Deaths worldwide by country", Type: "graph", Expr: "max(cov19_world_death) by (country) > 0", Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Deaths worldwide by country (stacked)", Type: "stacked", Expr: "max(cov19_world_death) by (country) > 0", Legend: "{{country}}", Width: 12, Height: 10, Source: "ecdc"},
+			{Title: "Infections by continent", Type: "graph", Expr: "sum(cov19_world_infected) by (continent)", Legend: "{{continent}}", Width: 12, Height: 8, Source: "ecdc"},
+			{Title: "Deaths by continent", Type: "graph", Expr: "sum(cov19_world_death) by (continent)", Legend: "{{continent}}", Width: 12, Height: 8, Source: "ecdc"},
+			{Title: "Fatality rate worldwide", Type: "map", Expr: "cov19_world_fatality_rate * 100", Map: "world", Label: "country", Width: 12, Height: 16, Source: "ecdc"},
+			{Title: "Fatality rate by country", Type: "graph", Expr: "cov19_world_fatality_rate", Legend: "{{country}}", Unit: "percentunit", Width: 12, Height: 16, Source: "ecdc"},
+		},
+	},
+	{
+		UID:     "EQRc3W9Wz",
+		Title:   "Covid19-Wien",
+		File:    "covid-wien.json",
+		From:    "now-30d",
+		Refresh: "1h",
+		Panels: []panelSpec{
+			{Title: "Infected in Wien", Type: "stat", Expr: `cov19_detail{province="Wien"}`, Width: 8, Height: 5, Source: "social_ministry"},
+			{Title: "New infections in Wien", Type: "stat", Expr: `province:cov19_detail:increase1d{province="Wien"}`, Width: 8, Height: 5, Source: "social_ministry"},
+			{Title: "Infected in Wien per 100k population", Type: "stat", Expr: `cov19_detail_infected_per_100k{province="Wien"}`, Width: 8, Height: 5, Source: "social_ministry"},
+			{Title: "Infections by Bezirk", Type: "map", Expr: `cov19_bezirk_infected{bezirk=~"Wien .*"}`, Map: "wien", Label: "bezirk", Width: 12, Height: 17, Source: "health_ministry"},
+			{Title: "Infections by Bezirk per 100k population", Type: "map", Expr: `cov19_bezirk_infected_100k{bezirk=~"Wien .*"}`, Map: "wien", Label: "bezirk", Width: 12, Height: 17, Source: "health_ministry"},
+			{Title: "Infections by Bezirk", Type: "graph", Expr: `cov19_bezirk_infected{bezirk=~"Wien .*"}`, Legend: "{{bezirk}}", Width: 12, Height: 8, Source: "health_ministry"},
+			{Title: "Infections by Bezirk per 100k population", Type: "graph", Expr: `cov19_bezirk_infected_100k{bezirk=~"Wien .*"}`, Legend: "{{bezirk}}", Width: 12, Height: 8, Source: "health_ministry"},
+		},
+	},
+}
+
+//validate checks that the panels reference registered metrics, recorded rules and labels
+func (p panelSpec) validate() error {
+	if p.Type == "row" {
+		return nil
+	}
+	if p.Width <= 0 || p.Width > gridWidth || p.Height <= 0 {
+		return fmt.Errorf("Invalid size %dx%d", p.Width, p.Height)
+	}
+	if _, ok := dashboardLinks[p.Source]; p.Source != "" && !ok {
+		return fmt.Errorf("Unknown source %q", p.Source)
+	}
+	records := make(map[string]bool)
+	for _, r := range recordingRules() {
+		records[r.Record] = true
+	}
+	matches := metricPattern.FindAllStringSubmatch(p.Expr, -1)
+	if len(matches) == 0 {
+		return fmt.Errorf("No metric in %q", p.Expr)
+	}
+	for _, m := range matches {
+		info := findMetricInfo(m[1])
+		if info == nil {
+			return fmt.Errorf("Unknown metric %s", m[1])
+		}
+		if m[0] != m[1] && !records[m[0]] {
+			return fmt.Errorf("Unknown recording rule %s", m[0])
+		}
+		for _, l := range legendPattern.FindAllStringSubmatch(p.Legend, -1) {
+			if !info.hasLabel(l[1]) {
+				return fmt.Errorf("%s has no label %s", info.Name, l[1])
+			}
+		}
+		if p.Type == "map" && !(info.hasLabel(p.Label) && info.hasLabel("latitude") && info.hasLabel("longitude")) {
+			return fmt.Errorf("%s has no label %s, latitude and longitude", info.Name, p.Label)
+		}
+	}
+	switch p.Type {
+	case "stat", "graph", "stacked", "pie":
+	case "map":
+		if _, ok := mapViews[p.Map]; !ok {
+			return fmt.Errorf("Unknown map %q", p.Map)
+		}
+	default:
+		return fmt.Errorf("Unknown panel type %q", p.Type)
+	}
+	return nil
+}
+
+func (d dashboardSpec) validate() error {
+	for _, p := range d.Panels {
+		err := p.validate()
+		if err != nil {
+			return fmt.Errorf("%s: %s: %v", d.Title, p.Title, err)
+		}
+	}
+	return nil
+}
+
+func panelLinks(source string) []map[string]interface{} {
+	if source == "" {
+		return []map[string]interface{}{}
+	}
+	return []map[string]interface{}{{"targetBlank": true, "title": "Source", "url": dashboardLinks[source]}}
+}
+
+//panel renders the Grafana json of a panel at the grid position
+func (p panelSpec) panel(id int, x int, y int) map[string]interface{} {
+	result := map[string]interface{}{
+		"id":         id,
+		"title":      p.Title,
+		"datasource": dashboardDatasource,
+		"gridPos":    map[string]int{"x": x, "y": y, "w": p.Width, "h": p.Height},
+	}
+	target := map[string]interface{}{"expr": p.Expr, "legendFormat": p.Legend, "refId": "A"}
+	switch p.Type {
+	case "row":
+		result["type"] = "row"
+		result["collapsed"] = false
+		result["panels"] = []interface{}{}
+		result["gridPos"] = map[string]int{"x": 0, "y": y, "w": gridWidth, "h": 1}
+		delete(result, "datasource")
+		return result
+	case "stat":
+		target["instant"] = true
+		result["type"] = "stat"
+		result["options"] = map[string]interface{}{
+			"colorMode":   "value",
+			"graphMode":   "area",
+			"justifyMode": "auto",
+			"orientation": "auto",
+			"fieldOptions": map[string]interface{}{
+				"calcs":     []string{"last"},
+				"defaults":  map[string]interface{}{"mappings": []interface{}{}, "unit": p.Unit, "thresholds": map[string]interface{}{"mode": "absolute", "steps": []interface{}{map[string]interface{}{"color": "dark-orange", "value": nil}}}},
+				"overrides": []interface{}{},
+				"values":    false,
+			},
+		}
+	case "graph", "stacked":
+		target["interval"] = "1h"
+		result["type"] = "graph"
+		result["stack"] = p.Type == "stacked"
+		result["lines"] = true
+		result["fill"] = 1
+		result["linewidth"] = 1
+		result["nullPointMode"] = "null"
+		result["legend"] = map[string]interface{}{"show": true, "alignAsTable": true, "rightSide": true, "values": true, "current": true}
+		result["tooltip"] = map[string]interface{}{"shared": true, "sort": 0, "value_type": "individual"}
+		result["xaxis"] = map[string]interface{}{"mode": "time", "show": true, "values": []interface{}{}}
+		unit := p.Unit
+		if unit == "" {
+			unit = "short"
+		}
+		result["yaxes"] = []interface{}{
+			map[string]interface{}{"format": unit, "logBase": 1, "show": true},
+			map[string]interface{}{"format": "short", "logBase": 1, "show": false},
+		}
+	case "pie":
+		target["instant"] = true
+		result["type"] = "grafana-piechart-panel"
+		result["pieType"] = "pie"
+		result["legendType"] = "Right side"
+		result["legend"] = map[string]interface{}{"show": true, "percentage": true, "values": true}
+		result["valueName"] = "current"
+		result["format"] = "short"
+	case "map":
+		view := mapViews[p.Map]
+		target["instant"] = true
+		target["format"] = "table"
+		result["type"] = "grafana-worldmap-panel"
+		result["locationData"] = "table"
+		result["mapCenter"] = "custom"
+		result["mapCenterLatitude"] = view.Latitude
+		result["mapCenterLongitude"] = view.Longitude
+		result["initialZoom"] = view.Zoom
+		result["tableQueryOptions"] = map[string]interface{}{
+			"queryType":      "coordinates",
+			"labelField":     p.Label,
+			"latitudeField":  "latitude",
+			"longitudeField": "longitude",
+			"metricField":    "Value",
+			"geohashField":   "geohash",
+		}
+		result["circleMinSize"] = "10"
+		result["circleMaxSize"] = "50"
+		result["colors"] = []string{"#37872D", "#F2CC0C", "#FA6400", "#E02F44", "#C4162A"}
+		result["thresholds"] = "10,20,50,100"
+		result["decimals"] = 0
+		result["maxDataPoints"] = 1
+		result["mouseWheelZoom"] = true
+		result["showLegend"] = true
+		result["valueName"] = "current"
+	}
+	result["targets"] = []interface{}{target}
+	result["links"] = panelLinks(p.Source)
+	return result
+}
+
+//dashboard renders the Grafana json of the dashboard, panels are placed left to right and wrapped at the grid width
+func (d dashboardSpec) dashboard() map[string]interface{} {
+	panels := make([]interface{}, 0, len(d.Panels))
+	x, y, lineHeight := 0, 0, 0
+	for i, p := range d.Panels {
+		if p.Type == "row" || x+p.Width > gridWidth {
+			x, y, lineHeight = 0, y+lineHeight, 0
+		}
+		panels = append(panels, p.panel(i+1, x, y))
+		if p.Type == "row" {
+			y++
+			continue
+		}
+		x += p.Width
+		if p.Height > lineHeight {
+			lineHeight = p.Height
+		}
+	}
+	links := []map[string]interface{}{
+		{"type": "link", "icon": "external link", "targetBlank": true, "title": "Source Code on GitHub", "url": "https://github.com/cinemast/covid19-at"},
+		{"type": "link", "icon": "external link", "targetBlank": true, "title": "Metric Endpoint", "url": "https://covid19.spiessknafl.at/covid19/metrics"},
+		{"type": "link", "icon": "external link", "targetBlank": true, "title": "Impressum", "url": "https://covid19.spiessknafl.at/impressum.html"},
+	}
+	return map[string]interface{}{
+		"uid":           d.UID,
+		"title":         d.Title,
+		"editable":      true,
+		"schemaVersion": 22,
+		"style":         "dark",
+		"tags":          []string{"covid19"},
+		"refresh":       d.Refresh,
+		"time":          map[string]string{"from": d.From, "to": "now"},
+		"timepicker":    map[string]interface{}{"refresh_intervals": []string{"10m", "30m", "1h", "2h", "1d"}},
+		"timezone":      "",
+		"links":         links,
+		"panels":        panels,
+		"templating":    map[string]interface{}{"list": []interface{}{}},
+		"annotations":   map[string]interface{}{"list": []interface{}{}},
+	}
+}
+
+//renderDashboard returns the indented json of the dashboard
+func renderDashboard(d dashboardSpec) ([]byte, error) {
+	err := d.validate()
+	if err != nil {
+		return nil, err
+	}
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	err = encoder.Encode(d.dashboard())
+	return b.Bytes(), err
+}
+
+//writeDashboards writes every dashboard as json file into dir
+func writeDashboards(dir string) error {
+	for _, d := range dashboards {
+		content, err := renderDashboard(d)
+		if err != nil {
+			return err
+		}
+		err = ioutil.WriteFile(filepath.Join(dir, d.File), content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDashboardsReferenceEmittedMetrics(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+	emitted := make(map[string]bool)
+	for _, exporter := range []Exporter{mockApi.he, mockApi.se, mockApi.ee, mockApi.mde} {
+		metrics, err := exporter.GetMetrics()
+		assert.Nil(t, err)
+		for _, m := range metrics {
+			emitted[m.Name] = true
+		}
+	}
+
+	for _, d := range dashboards {
+		assert.Nil(t, d.validate())
+		for _, p := range d.Panels {
+			for _, m := range metricPattern.FindAllStringSubmatch(p.Expr, -1) {
+				assert.True(t, emitted[m[1]], "%s: %s: %s is not emitted", d.Title, p.Title, m[1])
+			}
+		}
+	}
+}
+
+func TestDashboardValidation(t *testing.T) {
+	assert.Nil(t, panelSpec{Title: "Healed", Type: "row"}.validate())
+	assert.Equal(t, "Unknown metric cov19_healed", panelSpec{Type: "stat", Expr: "cov19_healed", Width: 4, Height: 4}.validate().Error())
+	assert.Equal(t, "Unknown recording rule province:cov19_detail:increase30d", panelSpec{Type: "graph", Expr: "province:cov19_detail:increase30d", Width: 4, Height: 4}.validate().Error())
+	assert.Equal(t, "cov19_tests has no label province", panelSpec{Type: "graph", Expr: "cov19_tests", Legend: "{{province}}", Width: 4, Height: 4}.validate().Error())
+	assert.Equal(t, "cov19_age_distribution has no label group, latitude and longitude", panelSpec{Type: "map", Expr: "cov19_age_distribution", Map: "austria", Label: "group", Width: 4, Height: 4}.validate().Error())
+	assert.Equal(t, `Unknown map "mars"`, panelSpec{Type: "map", Expr: "cov19_detail", Map: "mars", Label: "province", Width: 4, Height: 4}.validate().Error())
+	assert.Equal(t, "Invalid size 30x4", panelSpec{Type: "stat", Expr: "cov19_tests", Width: 30, Height: 4}.validate().Error())
+
+	d := dashboardSpec{Title: "Test", Panels: []panelSpec{{Title: "Healed", Type: "stat", Expr: "cov19_healed", Width: 4, Height: 4}}}
+	_, err := renderDashboard(d)
+	assert.Equal(t, "Test: Healed: Unknown metric cov19_healed", err.Error())
+}
+
+func TestDashboardPanels(t *testing.T) {
+	//the panels of the dashboards the generator replaced
+	titles := make(map[string]bool)
+	for _, p := range dashboards[0].Panels {
+		titles[p.Title+" "+p.Type] = true
+	}
+	for _, title := range []string{"Healed stat", "Infections by Province graph", "Infections by Bezirk (stacked) stacked",
+		"Hospitalized by Province map", "Infections in Europe graph", "Deaths in Europe graph",
+		"Infections worldwide by country graph", "Infections worldwide by country (stacked) stacked",
+		"Deaths worldwide by country graph", "Deaths worldwide by country (stacked) stacked", "Fatality rate by country graph"} {
+		assert.True(t, titles[title], title)
+	}
+}
+
+func TestDashboardLayout(t *testing.T) {
+	for _, d := range dashboards {
+		type cell struct{ x, y int }
+		used := make(map[cell]string)
+		for _, p := range d.dashboard()["panels"].([]interface{}) {
+			panel := p.(map[string]interface{})
+			pos := panel["gridPos"].(map[string]int)
+			assert.True(t, pos["x"]+pos["w"] <= gridWidth, panel["title"])
+			for x := pos["x"]; x < pos["x"]+pos["w"]; x++ {
+				for y := pos["y"]; y < pos["y"]+pos["h"]; y++ {
+					other, overlaps := used[cell{x, y}]
+					assert.False(t, overlaps, "%s overlaps %s", panel["title"], other)
+					used[cell{x, y}] = panel["title"].(string)
+				}
+			}
+		}
+	}
+}
+
+func TestDashboardFilesAreGenerated(t *testing.T) {
+	for _, d := range dashboards {
+		content, err := renderDashboard(d)
+		assert.Nil(t, err)
+		committed, err := ioutil.ReadFile(filepath.Join("config/dashboard", d.File))
+		assert.Nil(t, err)
+		assert.Equal(t, string(content), string(committed), "%s is outdated, run make dashboards", d.File)
+
+		var parsed map[string]interface{}
+		assert.Nil(t, json.Unmarshal(committed, &parsed))
+		assert.Equal(t, d.UID, parsed["uid"])
+	}
+
+	dir, err := ioutil.TempDir("", "dashboards")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	assert.Nil(t, writeDashboards(dir))
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Equal(t, len(dashboards), len(files))
+}
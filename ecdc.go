@@ -1,11 +1,10 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
-	"net/http"
 	"strings"
-	"time"
 	"unicode"
 
 	"github.com/PuerkitoBio/goquery"
@@ -22,7 +21,7 @@ type ecdcStat struct {
 }
 
 func newEcdcExporter(lp *metadataProvider) *ecdcExporter {
-	return &ecdcExporter{Url: "https://www.ecdc.europa.eu/en/geographical-distribution-2019-ncov-cases", Mp: lp}
+	return &ecdcExporter{Url: defaultConfig().Sources.Ecdc, Mp: lp}
 }
 
 //GetMetrics parses the ECDC table
@@ -96,15 +95,20 @@ func (e *ecdcExporter) getTags(stats []ecdcStat, i int) map[string]string {
 	return tags
 }
 
+//getEcdcStat returns the cases per country, the result is shared and must not be modified
 func getEcdcStat(url string) ([]ecdcStat, error) {
-	client := http.Client{Timeout: 3 * time.Second}
-	response, err := client.Get(url)
+	result, err := upstream.parse("ecdc", url, parseEcdcStat)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
+	return result.([]ecdcStat), nil
+}
 
-	document, _ := goquery.NewDocumentFromReader(response.Body)
+func parseEcdcStat(body []byte) (interface{}, error) {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
 	rows := document.Find("table").Find("tbody").Find("tr")
 	if rows.Size() == 0 {
 		return nil, errors.New("Could not find table")
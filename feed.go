@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/xml"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"strings"
+	"time"
+)
+
+//feedEntries is the number of updates listed in a feed
+const feedEntries = 50
+
+//feedIDPrefix starts the tag URIs identifying feeds and entries independent of the host they are served from
+const feedIDPrefix = "tag:covid19-at,2020:"
+
+//sourceNames are the upstream sources as shown in feed titles
+var sourceNames = map[string]string{
+	"health_ministry": "Health ministry",
+	"social_ministry": "Social ministry",
+	"hospitalization": "Hospitalization",
+	"ecdc":            "ECDC",
+	"mathdro":         "Mathdro",
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+type atomEntry struct {
+	Title   string   `xml:"title"`
+	ID      string   `xml:"id"`
+	Updated string   `xml:"updated"`
+	Content atomText `xml:"content"`
+}
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	Title   string      `xml:"title"`
+	ID      string      `xml:"id"`
+	Updated string      `xml:"updated"`
+	Author  string      `xml:"author>name"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type rssGUID struct {
+	IsPermaLink bool   `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssItem struct {
+	Title       string  `xml:"title"`
+	GUID        rssGUID `xml:"guid"`
+	PubDate     string  `xml:"pubDate"`
+	Description string  `xml:"description"`
+}
+
+type rssFeed struct {
+	XMLName       xml.Name  `xml:"rss"`
+	Version       string    `xml:"version,attr"`
+	Title         string    `xml:"channel>title"`
+	Link          string    `xml:"channel>link"`
+	Description   string    `xml:"channel>description"`
+	LastBuildDate string    `xml:"channel>lastBuildDate"`
+	Items         []rssItem `xml:"channel>item"`
+}
+
+//feedEntry is a data update rendered for a feed
+type feedEntry struct {
+	id      string
+	time    time.Time
+	title   string
+	content string
+}
+
+//sourceTitle names the upstream urls that changed, e.g. Health ministry SimpleData.js
+func sourceTitle(sources []upstreamChange) string {
+	names := make([]string, 0, len(sources))
+	for _, s := range sources {
+		name, ok := sourceNames[s.Source]
+		if !ok {
+			name = s.Source
+		}
+		names = append(names, name+" "+path.Base(s.URL))
+	}
+	return strings.Join(names, ", ")
+}
+
+//describeChanges lists the changed fields of a region, e.g. infected 1500 → 1520 (+20)
+func describeChanges(r regionChange) string {
+	fields := make([]string, 0, len(r.Changes))
+	for field := range r.Changes {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	changes := make([]string, 0, len(fields))
+	for _, field := range fields {
+		c := r.Changes[field]
+		changes = append(changes, fmt.Sprintf("%s %g → %g (%+g)", field, c.Previous, c.Current, c.Delta))
+	}
+	return r.Region + ": " + strings.Join(changes, ", ")
+}
+
+//newFeedEntries converts the updates to feed entries, newest first.
+//Only the regions accepted by includeRegion are listed, updates without such a region are skipped.
+func newFeedEntries(updates []dataUpdate, includeRegion func(scope string, region string) bool) []feedEntry {
+	result := make([]feedEntry, 0)
+	for i := len(updates) - 1; i >= 0 && len(result) < feedEntries; i-- {
+		u := updates[i]
+		lines := make([]string, 0)
+		scopes := make([]string, 0)
+		for _, e := range u.Events {
+			regions := make([]string, 0)
+			for _, r := range e.Regions {
+				if includeRegion(e.Scope, r.Region) {
+					regions = append(regions, describeChanges(r))
+				}
+			}
+			if len(regions) > 0 {
+				scopes = append(scopes, e.Scope)
+				lines = append(lines, e.Scope+":")
+				lines = append(lines, regions...)
+			}
+		}
+		if len(lines) == 0 {
+			continue
+		}
+		title := "Updated " + strings.Join(scopes, ", ")
+		if len(u.Sources) > 0 {
+			title = "New data from " + sourceTitle(u.Sources)
+		}
+		content := strings.Join(lines, "\n")
+		result = append(result, feedEntry{id: entryID(scopes, u.Time, content), time: u.Time, title: title, content: content})
+	}
+	return result
+}
+
+//entryID identifies an update by its scopes, its time and a hash of the listed changes,
+//so it stays the same across restarts and differs between feeds listing different regions of an update
+func entryID(scopes []string, t time.Time, content string) string {
+	hash := sha256.Sum256([]byte(content))
+	return fmt.Sprintf("%supdate/%s/%s/%x", feedIDPrefix, strings.Join(scopes, ","), t.UTC().Format(time.RFC3339), hash[:8])
+}
+
+//baseURL is the scheme and host the request was sent to
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func feedUpdated(entries []feedEntry) time.Time {
+	if len(entries) > 0 {
+		return entries[0].time
+	}
+	return time.Unix(0, 0)
+}
+
+func writeAtom(w http.ResponseWriter, r *http.Request, title string, entries []feedEntry) {
+	self := baseURL(r) + r.URL.Path
+	feed := atomFeed{
+		Title:   title,
+		ID:      feedIDPrefix + "feed" + r.URL.Path,
+		Updated: feedUpdated(entries).UTC().Format(time.RFC3339),
+		Author:  "covid19-at",
+		Links:   []atomLink{{Href: self, Rel: "self"}, {Href: baseURL(r) + "/report/daily"}},
+	}
+	for _, e := range entries {
+		feed.Entries = append(feed.Entries, atomEntry{
+			Title:   e.title,
+			ID:      e.id,
+			Updated: e.time.UTC().Format(time.RFC3339),
+			Content: atomText{Type: "text", Body: e.content},
+		})
+	}
+	writeXML(w, "application/atom+xml; charset=utf-8", feed)
+}
+
+func writeRSS(w http.ResponseWriter, r *http.Request, title string, entries []feedEntry) {
+	feed := rssFeed{
+		Version:       "2.0",
+		Title:         title,
+		Link:          baseURL(r) + "/report/daily",
+		Description:   "Data updates of the Austrian COVID-19 figures",
+		LastBuildDate: feedUpdated(entries).UTC().Format(time.RFC1123Z),
+	}
+	for _, e := range entries {
+		feed.Items = append(feed.Items, rssItem{
+			Title:       e.title,
+			GUID:        rssGUID{Value: e.id},
+			PubDate:     e.time.UTC().Format(time.RFC1123Z),
+			Description: e.content,
+		})
+	}
+	writeXML(w, "application/rss+xml; charset=utf-8", feed)
+}
+
+func writeXML(w http.ResponseWriter, contentType string, v interface{}) {
+	bytes, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(xml.Header))
+	w.Write(bytes)
+}
+
+func allRegions(scope string, region string) bool {
+	return true
+}
+
+func (c *collector) handleAtom(w http.ResponseWriter, r *http.Request) {
+	writeAtom(w, r, "COVID-19 in Austria", newFeedEntries(c.dataUpdates(), allRegions))
+}
+
+func (c *collector) handleRSS(w http.ResponseWriter, r *http.Request) {
+	writeRSS(w, r, "COVID-19 in Austria", newFeedEntries(c.dataUpdates(), allRegions))
+}
+
+//findBundesland returns the spelling of austriaRegions for name, "" for unknown Bundesländer
+func findBundesland(name string) string {
+	for _, p := range austriaRegions[1:] {
+		if normalizeName(p) == normalizeName(name) {
+			return p
+		}
+	}
+	return ""
+}
+
+//handleBundeslandFeed serves /feed/bundesland/{name}.atom with the changes of a Bundesland and its Bezirke
+func (c *collector) handleBundeslandFeed(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/feed/bundesland/")
+	if !strings.HasSuffix(name, ".atom") {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimSuffix(name, ".atom")
+	province := findBundesland(name)
+	if province == "" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("Unknown Bundesland %q", name)))
+		return
+	}
+
+	bezirke := make(map[string]bool)
+	if current, _ := c.snapshots(); current != nil {
+		for _, b := range current.Bezirk {
+			if normalizeName(b.Province) == normalizeName(province) {
+				bezirke[b.Name] = true
+			}
+		}
+	}
+	includeRegion := func(scope string, region string) bool {
+		return (scope == "bundesland" && normalizeName(region) == normalizeName(province)) || (scope == "bezirk" && bezirke[region])
+	}
+	writeAtom(w, r, "COVID-19 in "+province, newFeedEntries(c.dataUpdates(), includeRegion))
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"encoding/xml"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func newFeedCollector() *collector {
+	c := newCollector(nil)
+	first := testSnapshot(1500, 1820)
+	first.Bezirk = []bezirkStat{{Name: "Innsbruck-Land", Province: "Tirol", Infected: 150}, {Name: "Wien(Stadt)", Province: "Wien", Infected: 1500}}
+	c.update(first)
+
+	second := testSnapshot(1520, 1840)
+	second.Time = first.Time.Add(time.Hour)
+	second.Bezirk = []bezirkStat{{Name: "Innsbruck-Land", Province: "Tirol", Infected: 150}, {Name: "Wien(Stadt)", Province: "Wien", Infected: 1520}}
+	second.Sources = []upstreamChange{{"health_ministry", "https://info.gesundheitsministerium.at/data/SimpleData.js", second.Time}}
+	c.update(second)
+
+	third := testSnapshot(1520, 1840)
+	third.Time = second.Time.Add(time.Hour)
+	third.Bezirk = []bezirkStat{{Name: "Innsbruck-Land", Province: "Tirol", Infected: 160}, {Name: "Wien(Stadt)", Province: "Wien", Infected: 1520}}
+	c.update(third)
+	return c
+}
+
+func getFeed(t *testing.T, h http.HandlerFunc, path string) (*http.Response, string) {
+	ts := httptest.NewServer(h)
+	defer ts.Close()
+	response, err := ts.Client().Get(ts.URL + path)
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(response.Body)
+	return response, string(body)
+}
+
+func TestAtomFeed(t *testing.T) {
+	c := newFeedCollector()
+	response, body := getFeed(t, c.handleAtom, "/feed.atom")
+	assert.Equal(t, "application/atom+xml; charset=utf-8", response.Header.Get("Content-Type"))
+	feed := atomFeed{}
+	assert.Nil(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Equal(t, 2, len(feed.Entries))
+	assert.Equal(t, "2020-03-30T10:00:00Z", feed.Updated)
+	assert.Equal(t, "Updated bezirk", feed.Entries[0].Title)
+	assert.Equal(t, "bezirk:\nInnsbruck-Land: infected 150 → 160 (+10)", feed.Entries[0].Content.Body)
+	assert.Equal(t, "New data from Health ministry SimpleData.js", feed.Entries[1].Title)
+	assert.Equal(t, "total:\nAustria: total_infected 1820 → 1840 (+20)\nbundesland:\nWien: infected 1500 → 1520 (+20)\nbezirk:\nWien(Stadt): infected 1500 → 1520 (+20)", feed.Entries[1].Content.Body)
+	assert.Equal(t, "tag:covid19-at,2020:feed/feed.atom", feed.ID)
+	assert.True(t, strings.HasPrefix(feed.Entries[1].ID, "tag:covid19-at,2020:update/total,bundesland,bezirk/2020-03-30T09:00:00Z/"), feed.Entries[1].ID)
+
+	//ids stay the same after a restart and do not depend on the host
+	restarted := newFeedCollector()
+	restarted.nextID = 100
+	request := httptest.NewRequest("GET", "http://other.example/feed.atom", nil)
+	recorder := httptest.NewRecorder()
+	restarted.handleAtom(recorder, request)
+	other := atomFeed{}
+	assert.Nil(t, xml.Unmarshal(recorder.Body.Bytes(), &other))
+	assert.Equal(t, feed.ID, other.ID)
+	assert.Equal(t, feed.Entries[0].ID, other.Entries[0].ID)
+	assert.Equal(t, feed.Entries[1].ID, other.Entries[1].ID)
+	assert.NotEqual(t, feed.Entries[0].ID, feed.Entries[1].ID)
+}
+
+func TestRSSFeed(t *testing.T) {
+	c := newFeedCollector()
+	response, body := getFeed(t, c.handleRSS, "/feed.rss")
+	assert.Equal(t, "application/rss+xml; charset=utf-8", response.Header.Get("Content-Type"))
+	feed := rssFeed{}
+	assert.Nil(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Equal(t, "2.0", feed.Version)
+	assert.Equal(t, 2, len(feed.Items))
+	assert.Equal(t, "Mon, 30 Mar 2020 10:00:00 +0000", feed.Items[0].PubDate)
+	assert.False(t, feed.Items[0].GUID.IsPermaLink)
+	assert.True(t, strings.HasPrefix(feed.Items[0].GUID.Value, "tag:covid19-at,2020:update/bezirk/2020-03-30T10:00:00Z/"), feed.Items[0].GUID.Value)
+}
+
+func TestBundeslandFeed(t *testing.T) {
+	c := newFeedCollector()
+	_, body := getFeed(t, c.handleBundeslandFeed, "/feed/bundesland/wien.atom")
+	feed := atomFeed{}
+	assert.Nil(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Equal(t, "COVID-19 in Wien", feed.Title)
+	if assert.Equal(t, 1, len(feed.Entries)) {
+		assert.Equal(t, "bundesland:\nWien: infected 1500 → 1520 (+20)\nbezirk:\nWien(Stadt): infected 1500 → 1520 (+20)", feed.Entries[0].Content.Body)
+	}
+
+	_, body = getFeed(t, c.handleBundeslandFeed, "/feed/bundesland/Tirol.atom")
+	feed = atomFeed{}
+	assert.Nil(t, xml.Unmarshal([]byte(body), &feed))
+	assert.Equal(t, 1, len(feed.Entries))
+
+	response, body := getFeed(t, c.handleBundeslandFeed, "/feed/bundesland/Bayern.atom")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+	assert.Equal(t, `Unknown Bundesland "Bayern"`, body)
+	response, _ = getFeed(t, c.handleBundeslandFeed, "/feed/bundesland/Wien.rss")
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
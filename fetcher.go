@@ -0,0 +1,194 @@
+package main
+
+import (
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+//hostLimit holds the concurrency and rate limit state of a single host
+type hostLimit struct {
+	slots chan struct{}
+	next  time.Time
+}
+
+type hostStats struct {
+	requests        map[string]uint64
+	retries         uint64
+	durationSeconds float64
+	durationCount   uint64
+}
+
+//fetcher sends the requests to the sources. It retries timeouts and 5xx responses with exponential backoff
+//and limits the number of parallel requests and the request rate per host.
+type fetcher struct {
+	lock   sync.Mutex
+	config upstreamConfig
+	client *http.Client
+	hosts  map[string]*hostLimit
+	stats  map[string]*hostStats
+	now    func() time.Time
+	sleep  func(time.Duration)
+	random func() float64
+}
+
+func newFetcher(c upstreamConfig) *fetcher {
+	f := &fetcher{stats: make(map[string]*hostStats), now: time.Now, sleep: time.Sleep, random: rand.Float64}
+	f.configure(c)
+	return f
+}
+
+//configure applies c to all following requests, requests in flight keep their settings
+func (f *fetcher) configure(c upstreamConfig) {
+	proxy := http.ProxyFromEnvironment
+	if c.Proxy != "" {
+		if proxyURL, err := url.Parse(c.Proxy); err == nil {
+			proxy = http.ProxyURL(proxyURL)
+		}
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	f.config = c
+	f.client = &http.Client{Timeout: seconds(c.Timeout), Transport: transport}
+	f.hosts = make(map[string]*hostLimit)
+}
+
+func (f *fetcher) hostLimit(host string) *hostLimit {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	limit, ok := f.hosts[host]
+	if !ok {
+		limit = &hostLimit{}
+		if f.config.MaxConcurrent > 0 {
+			limit.slots = make(chan struct{}, f.config.MaxConcurrent)
+		}
+		f.hosts[host] = limit
+	}
+	return limit
+}
+
+//waitForRate blocks until the next request to host is allowed by requests_per_second
+func (f *fetcher) waitForRate(host string, limit *hostLimit) {
+	f.lock.Lock()
+	if f.config.RequestsPerSecond <= 0 {
+		f.lock.Unlock()
+		return
+	}
+	now := f.now()
+	if limit.next.Before(now) {
+		limit.next = now
+	}
+	wait := limit.next.Sub(now)
+	limit.next = limit.next.Add(seconds(1 / f.config.RequestsPerSecond))
+	f.lock.Unlock()
+	if wait > 0 {
+		f.sleep(wait)
+	}
+}
+
+//backoff returns the jittered wait before retry number attempt+1
+func (f *fetcher) backoff(attempt int) time.Duration {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	return seconds(f.config.Backoff * math.Pow(2, float64(attempt)) * (0.5 + f.random()))
+}
+
+func (f *fetcher) record(host string, status string, duration time.Duration, retry bool) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	stats, ok := f.stats[host]
+	if !ok {
+		stats = &hostStats{requests: make(map[string]uint64)}
+		f.stats[host] = stats
+	}
+	stats.requests[status]++
+	stats.durationSeconds += duration.Seconds()
+	stats.durationCount++
+	if retry {
+		stats.retries++
+	}
+}
+
+func retryable(response *http.Response, err error) bool {
+	return err != nil || response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+}
+
+//do sends a GET request with the configured User-Agent and From headers, retrying it if the source fails
+func (f *fetcher) do(request *http.Request) (*http.Response, error) {
+	host := request.URL.Host
+	limit := f.hostLimit(host)
+	if limit.slots != nil {
+		limit.slots <- struct{}{}
+		defer func() { <-limit.slots }()
+	}
+
+	f.lock.Lock()
+	client := f.client
+	retries := f.config.Retries
+	request.Header.Set("User-Agent", f.config.UserAgent)
+	if f.config.Contact != "" {
+		request.Header.Set("From", f.config.Contact)
+	}
+	f.lock.Unlock()
+
+	for attempt := 0; ; attempt++ {
+		f.waitForRate(host, limit)
+		start := f.now()
+		response, err := client.Do(request)
+		status := "error"
+		if err == nil {
+			status = strconv.Itoa(response.StatusCode)
+		}
+		retry := retryable(response, err) && attempt < retries
+		f.record(host, status, f.now().Sub(start), retry)
+		if !retry {
+			return response, err
+		}
+		if response != nil {
+			io.Copy(ioutil.Discard, response.Body)
+			response.Body.Close()
+		}
+		f.sleep(f.backoff(attempt))
+	}
+}
+
+//GetMetrics returns the requests, retries and latencies per host
+func (f *fetcher) GetMetrics() (metrics, error) {
+	f.lock.Lock()
+	defer f.lock.Unlock()
+	hosts := make([]string, 0, len(f.stats))
+	for host := range f.stats {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	result := make(metrics, 0)
+	for _, host := range hosts {
+		stats := f.stats[host]
+		statuses := make([]string, 0, len(stats.requests))
+		for status := range stats.requests {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		for _, status := range statuses {
+			tags := map[string]string{"host": host, "status": status}
+			result = append(result, metric{Name: "cov19_upstream_requests_total", Value: float64(stats.requests[status]), Tags: &tags})
+		}
+		tags := map[string]string{"host": host}
+		result = append(result,
+			metric{Name: "cov19_upstream_retries_total", Value: float64(stats.retries), Tags: &tags},
+			metric{Name: "cov19_upstream_request_duration_seconds_sum", Value: stats.durationSeconds, Tags: &tags},
+			metric{Name: "cov19_upstream_request_duration_seconds_count", Value: float64(stats.durationCount), Tags: &tags},
+		)
+	}
+	return result, nil
+}
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//testUpstreamConfig reads the test servers without rate limit, concurrency limit and backoff
+func testUpstreamConfig() upstreamConfig {
+	c := defaultConfig().Upstream
+	c.Backoff = 0
+	c.MaxConcurrent = 0
+	c.RequestsPerSecond = 0
+	return c
+}
+
+//useTestFetcher installs a fetcher with testUpstreamConfig that never sleeps, so the limits of a reloaded config do not slow down the tests
+func useTestFetcher() {
+	upstream.fetcher = newFetcher(testUpstreamConfig())
+	upstream.fetcher.sleep = func(time.Duration) {}
+}
+
+func newTestFetcher(c upstreamConfig) (*fetcher, *[]time.Duration) {
+	f := newFetcher(c)
+	sleeps := make([]time.Duration, 0)
+	f.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	f.random = func() float64 { return 0.5 }
+	return f, &sleeps
+}
+
+func TestFetcherRetries(t *testing.T) {
+	requests := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		assert.Equal(t, "covid19-at-test", r.Header.Get("User-Agent"))
+		assert.Equal(t, "ops@example.com", r.Header.Get("From"))
+		if requests < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer ts.Close()
+
+	c := defaultConfig().Upstream
+	c.UserAgent = "covid19-at-test"
+	c.Contact = "ops@example.com"
+	c.RequestsPerSecond = 0
+	f, sleeps := newTestFetcher(c)
+	request, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	response, err := f.do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, []time.Duration{time.Second, 2 * time.Second}, *sleeps)
+
+	host := strings.TrimPrefix(ts.URL, "http://")
+	result, _ := f.GetMetrics()
+	assert.Nil(t, result.checkMetric("cov19_upstream_requests_total", "status=503", func(x float64) bool { return x == 2 }))
+	assert.Nil(t, result.checkMetric("cov19_upstream_requests_total", "status=200", func(x float64) bool { return x == 1 }))
+	assert.Nil(t, result.checkMetric("cov19_upstream_retries_total", "host="+host, func(x float64) bool { return x == 2 }))
+	assert.Nil(t, result.checkMetric("cov19_upstream_request_duration_seconds_count", "host="+host, func(x float64) bool { return x == 3 }))
+
+	//the last response is returned when all retries failed
+	requests = -10
+	request, _ = http.NewRequest(http.MethodGet, ts.URL, nil)
+	response, err = f.do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+	assert.Equal(t, -7, requests)
+}
+
+func TestFetcherRateLimit(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	c := defaultConfig().Upstream
+	c.RequestsPerSecond = 2
+	f, sleeps := newTestFetcher(c)
+	now := time.Unix(1585555200, 0)
+	f.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		request, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		_, err := f.do(request)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []time.Duration{500 * time.Millisecond, time.Second}, *sleeps)
+}
+
+func TestFetcherDefaultLimits(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ts.Close()
+
+	f, sleeps := newTestFetcher(defaultConfig().Upstream)
+	now := time.Unix(1585555200, 0)
+	f.now = func() time.Time { return now }
+	for i := 0; i < 3; i++ {
+		request, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+		_, err := f.do(request)
+		assert.Nil(t, err)
+	}
+	assert.Equal(t, []time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, *sleeps, "5 requests per second")
+	assert.Equal(t, 2, cap(f.hostLimit(strings.TrimPrefix(ts.URL, "http://")).slots), "2 parallel requests")
+}
+
+func TestFetcherConcurrency(t *testing.T) {
+	lock := sync.Mutex{}
+	running, maxRunning := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lock.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		lock.Unlock()
+		time.Sleep(10 * time.Millisecond)
+		lock.Lock()
+		running--
+		lock.Unlock()
+	}))
+	defer ts.Close()
+
+	c := defaultConfig().Upstream
+	c.MaxConcurrent = 2
+	c.RequestsPerSecond = 0
+	f := newFetcher(c)
+	wg := sync.WaitGroup{}
+	for i := 0; i < 6; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			request, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+			response, err := f.do(request)
+			if assert.Nil(t, err) {
+				response.Body.Close()
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, 2, maxRunning)
+}
+
+func TestUpstreamConfig(t *testing.T) {
+	c := defaultConfig().Upstream
+	assert.Nil(t, c.validate())
+	c.Proxy = "localhost:3128"
+	assert.EqualError(t, c.validate(), `Invalid url for upstream.proxy: "localhost:3128"`)
+	c.Proxy = ""
+	c.Timeout = 0
+	assert.EqualError(t, c.validate(), "upstream.timeout needs to be more than 0 seconds")
+}
@@ -0,0 +1,324 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+//forecastHorizon is the maximum number of days forecasted
+const forecastHorizon = 14
+
+//forecastWindow is the number of most recent days the models are fitted to
+const forecastWindow = 14
+
+//forecastZ is the quantile of the normal distribution for 95% prediction intervals
+const forecastZ = 1.96
+
+//forecastMetrics are the chartMetrics that are forecasted, hospitalized and intensive care come from getHospitalizedStats
+var forecastMetrics = []string{"new_cases", "hospitalized", "intensive_care"}
+
+//forecastValue is the prediction of a day with its prediction interval, all values are at least 0
+type forecastValue struct {
+	Value float64
+	Lower float64
+	Upper float64
+}
+
+//forecastModel predicts the horizon days after values, nil if there are less than 3 values
+type forecastModel struct {
+	Name     string
+	forecast func(values []float64, horizon int) []forecastValue
+}
+
+var forecastModels = []forecastModel{
+	{"log_linear", logLinearForecast},
+	{"exponential_smoothing", exponentialSmoothingForecast},
+}
+
+func newForecastValue(value float64, lower float64, upper float64) forecastValue {
+	return forecastValue{math.Max(value, 0), math.Max(lower, 0), math.Max(upper, 0)}
+}
+
+//logLinearForecast fits exponential growth by least squares on the logarithm of the values,
+//the interval is the prediction interval of the regression
+func logLinearForecast(values []float64, horizon int) []forecastValue {
+	n := float64(len(values))
+	if len(values) < 3 {
+		return nil
+	}
+	meanT, meanY := (n-1)/2, 0.0
+	logs := make([]float64, 0, len(values))
+	for _, v := range values {
+		logs = append(logs, math.Log(math.Max(v, 0)+1))
+		meanY += logs[len(logs)-1] / n
+	}
+	sxx, sxy := 0.0, 0.0
+	for t, y := range logs {
+		sxx += (float64(t) - meanT) * (float64(t) - meanT)
+		sxy += (float64(t) - meanT) * (y - meanY)
+	}
+	slope := sxy / sxx
+	intercept := meanY - slope*meanT
+	sse := 0.0
+	for t, y := range logs {
+		sse += math.Pow(y-intercept-slope*float64(t), 2)
+	}
+	s := math.Sqrt(sse / (n - 2))
+
+	result := make([]forecastValue, 0, horizon)
+	for h := 1; h <= horizon; h++ {
+		t := n - 1 + float64(h)
+		y := intercept + slope*t
+		e := forecastZ * s * math.Sqrt(1+1/n+(t-meanT)*(t-meanT)/sxx)
+		result = append(result, newForecastValue(math.Exp(y)-1, math.Exp(y-e)-1, math.Exp(y+e)-1))
+	}
+	return result
+}
+
+//smoothingSteps are the smoothing factors of level and trend exponentialSmoothingForecast chooses from
+var smoothingSteps = []float64{0.05, 0.1, 0.15, 0.2, 0.25, 0.3, 0.35, 0.4, 0.45, 0.5, 0.55, 0.6, 0.65, 0.7, 0.75, 0.8, 0.85, 0.9, 0.95}
+
+//holt smoothes the values with Holt's linear method and returns the last level and trend
+//with the sum of the squared one step errors
+func holt(values []float64, alpha float64, beta float64) (float64, float64, float64) {
+	level, trend := values[0], values[1]-values[0]
+	sse := 0.0
+	for _, v := range values[1:] {
+		sse += math.Pow(v-level-trend, 2)
+		previous := level
+		level = alpha*v + (1-alpha)*(level+trend)
+		trend = beta*(level-previous) + (1-beta)*trend
+	}
+	return level, trend, sse
+}
+
+//exponentialSmoothingForecast continues the level and trend of Holt's linear method with the smoothing factors
+//of the least one step squared error, the interval grows with the square root of the horizon from the one step errors
+func exponentialSmoothingForecast(values []float64, horizon int) []forecastValue {
+	if len(values) < 3 {
+		return nil
+	}
+	level, trend, sse := holt(values, smoothingSteps[0], smoothingSteps[0])
+	for _, alpha := range smoothingSteps {
+		for _, beta := range smoothingSteps {
+			if l, t, e := holt(values, alpha, beta); e < sse {
+				level, trend, sse = l, t, e
+			}
+		}
+	}
+	s := math.Sqrt(sse / float64(len(values)-1))
+
+	result := make([]forecastValue, 0, horizon)
+	for h := 1; h <= horizon; h++ {
+		y := level + float64(h)*trend
+		e := forecastZ * s * math.Sqrt(float64(h))
+		result = append(result, newForecastValue(y, y-e, y+e))
+	}
+	return result
+}
+
+type forecastPoint struct {
+	Date  time.Time `json:"date"`
+	Value float64   `json:"value"`
+	Lower float64   `json:"lower"`
+	Upper float64   `json:"upper"`
+}
+
+type forecastSeries struct {
+	Metric   string          `json:"metric"`
+	Model    string          `json:"model"`
+	Forecast []forecastPoint `json:"forecast"`
+}
+
+type bundeslandForecast struct {
+	Bundesland string `json:"bundesland"`
+	//Time is the time of the last snapshot the models are fitted to
+	Time   time.Time        `json:"time"`
+	Series []forecastSeries `json:"series"`
+}
+
+//newBundeslandForecast fits every model to the last forecastWindow days of every forecastMetric of province
+func newBundeslandForecast(history []*snapshot, province string, horizon int) *bundeslandForecast {
+	result := &bundeslandForecast{Bundesland: province, Series: make([]forecastSeries, 0)}
+	for _, name := range forecastMetrics {
+		dates, values := chartMetrics[name].series(history, province, forecastWindow)
+		if len(dates) > 0 {
+			result.Time = dates[len(dates)-1]
+		}
+		for _, model := range forecastModels {
+			series := forecastSeries{Metric: name, Model: model.Name, Forecast: make([]forecastPoint, 0)}
+			for i, f := range model.forecast(values, horizon) {
+				date := dates[len(dates)-1].Add(time.Duration(i+1) * 24 * time.Hour)
+				series.Forecast = append(series.Forecast, forecastPoint{date, f.Value, f.Lower, f.Upper})
+			}
+			result.Series = append(result.Series, series)
+		}
+	}
+	return result
+}
+
+//forecastExporter exposes the 7 and 14 day forecasts of every Bundesland
+type forecastExporter struct {
+	c *collector
+}
+
+func newForecastExporter(c *collector) *forecastExporter {
+	return &forecastExporter{c: c}
+}
+
+func (e *forecastExporter) GetMetrics() (metrics, error) {
+	result := make(metrics, 0)
+	history := e.c.dailyHistory()
+	if len(history) == 0 {
+		return result, nil
+	}
+	for _, b := range history[len(history)-1].Bundesland {
+		forecast := newBundeslandForecast(history, b.Name, forecastHorizon)
+		for _, s := range forecast.Series {
+			for _, days := range []int{7, 14} {
+				if len(s.Forecast) < days {
+					continue
+				}
+				p := s.Forecast[days-1]
+				tags := map[string]string{"province": b.Name, "series": s.Metric, "model": s.Model, "days": strconv.Itoa(days)}
+				result = append(result,
+					metric{Name: "cov19_forecast", Tags: &tags, Value: p.Value},
+					metric{Name: "cov19_forecast_lower", Tags: &tags, Value: p.Lower},
+					metric{Name: "cov19_forecast_upper", Tags: &tags, Value: p.Upper})
+			}
+		}
+	}
+	return result, nil
+}
+
+func (e *forecastExporter) Health() []error {
+	return nil
+}
+
+//handleBundeslandForecast serves /api/v1/bundesland/{name}/forecast?days=14
+func (c *collector) handleBundeslandForecast(w http.ResponseWriter, r *http.Request) {
+	name := strings.TrimPrefix(r.URL.Path, "/api/v1/bundesland/")
+	if !strings.HasSuffix(name, "/forecast") {
+		http.NotFound(w, r)
+		return
+	}
+	name = strings.TrimSuffix(name, "/forecast")
+	province := findBundesland(name)
+	if province == "" {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("Unknown Bundesland %q", name)))
+		return
+	}
+	days := forecastHorizon
+	if d := r.URL.Query().Get("days"); d != "" {
+		var err error
+		days, err = strconv.Atoi(d)
+		if err != nil || days < 1 || days > forecastHorizon {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(fmt.Sprintf("days must be between 1 and %d", forecastHorizon)))
+			return
+		}
+	}
+	history := c.dailyHistory()
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	writeJson(w, func() (interface{}, error) { return newBundeslandForecast(history, province, days), nil })
+}
+
+//backtestError accumulates the errors of the forecasts of a metric, model and horizon
+type backtestError struct {
+	forecasts     int
+	absoluteError float64
+	//percentageError and percentages skip days with an actual value of 0
+	percentageError float64
+	percentages     int
+	covered         int
+}
+
+//backtest forecasts every day of the history from the forecastWindow days before it and compares the
+//forecasts of the horizons with the actual values. The keys are metric, model and horizon.
+func backtest(history []*snapshot, horizons []int) map[[3]string]*backtestError {
+	result := make(map[[3]string]*backtestError)
+	if len(history) == 0 {
+		return result
+	}
+	max := 0
+	for _, h := range horizons {
+		if h > max {
+			max = h
+		}
+	}
+	for _, b := range history[len(history)-1].Bundesland {
+		for _, name := range forecastMetrics {
+			_, values := chartMetrics[name].series(history, b.Name, len(history))
+			for origin := 3; origin < len(values); origin++ {
+				window := values[int(math.Max(0, float64(origin-forecastWindow))):origin]
+				for _, model := range forecastModels {
+					forecast := model.forecast(window, max)
+					for _, h := range horizons {
+						if origin+h-1 >= len(values) {
+							continue
+						}
+						key := [3]string{name, model.Name, strconv.Itoa(h)}
+						if result[key] == nil {
+							result[key] = &backtestError{}
+						}
+						e, f, actual := result[key], forecast[h-1], values[origin+h-1]
+						e.forecasts++
+						e.absoluteError += math.Abs(f.Value - actual)
+						if actual != 0 {
+							e.percentageError += math.Abs(f.Value-actual) / actual
+							e.percentages++
+						}
+						if actual >= f.Lower && actual <= f.Upper {
+							e.covered++
+						}
+					}
+				}
+			}
+		}
+	}
+	return result
+}
+
+//writeBacktest reads the daily snapshots written to the -history file and writes the mean absolute error,
+//the mean absolute percentage error and the share of actual values within the prediction interval
+func writeBacktest(historyFile string, w io.Writer) error {
+	if _, err := os.Stat(historyFile); err != nil {
+		return err
+	}
+	history, err := readHistory(historyFile)
+	if err != nil {
+		return err
+	}
+	horizons := []int{1, 7, 14}
+	errors := backtest(history, horizons)
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "metric\tmodel\tdays\tforecasts\tmae\tmape\tcoverage")
+	for _, name := range forecastMetrics {
+		for _, model := range forecastModels {
+			for _, h := range horizons {
+				e, ok := errors[[3]string{name, model.Name, strconv.Itoa(h)}]
+				if !ok {
+					continue
+				}
+				mape := "-"
+				if e.percentages > 0 {
+					mape = fmt.Sprintf("%.1f%%", e.percentageError/float64(e.percentages)*100)
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%.1f\t%s\t%.1f%%\n", name, model.Name, h, e.forecasts, e.absoluteError/float64(e.forecasts), mape, float64(e.covered)/float64(e.forecasts)*100)
+			}
+		}
+	}
+	return tw.Flush()
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//forecastDays are the infections of Wien with 10 more hospitalized every day
+var forecastDays = []bundeslandStat{
+	{Infected: 100, Hospitalized: 10}, {Infected: 110, Hospitalized: 20}, {Infected: 130, Hospitalized: 30},
+	{Infected: 160, Hospitalized: 40}, {Infected: 200, Hospitalized: 50},
+}
+
+func TestLogLinearForecast(t *testing.T) {
+	assert.Nil(t, logLinearForecast([]float64{1, 2}, 7))
+
+	values := make([]float64, 0)
+	for i := 0; i < 10; i++ {
+		values = append(values, math.Exp(0.1*float64(i))-1)
+	}
+	forecast := logLinearForecast(values, 7)
+	assert.Equal(t, 7, len(forecast))
+	assert.InDelta(t, math.Exp(1.0)-1, forecast[0].Value, 0.0001)
+	assert.InDelta(t, math.Exp(1.6)-1, forecast[6].Value, 0.0001)
+	assert.InDelta(t, forecast[6].Value, forecast[6].Lower, 0.0001, "exact fit")
+
+	forecast = logLinearForecast([]float64{100, 120, 90, 130, 110}, 3)
+	assert.True(t, forecast[0].Lower < forecast[0].Value && forecast[0].Value < forecast[0].Upper)
+	assert.True(t, forecast[2].Upper-forecast[2].Lower > forecast[0].Upper-forecast[0].Lower, "the interval widens")
+}
+
+func TestExponentialSmoothingForecast(t *testing.T) {
+	assert.Nil(t, exponentialSmoothingForecast([]float64{1}, 7))
+
+	forecast := exponentialSmoothingForecast([]float64{10, 20, 30, 40}, 2)
+	assert.Equal(t, []forecastValue{{50, 50, 50}, {60, 60, 60}}, forecast)
+
+	forecast = exponentialSmoothingForecast([]float64{40, 30, 20, 10}, 3)
+	assert.Equal(t, forecastValue{0, 0, 0}, forecast[2], "counts are not negative")
+
+	//the smoothing factors are fitted to the one step errors
+	values := []float64{100, 140, 90, 150, 95, 145, 100, 150}
+	_, _, fixed := holt(values, 0.5, 0.3)
+	best := fixed
+	for _, alpha := range smoothingSteps {
+		for _, beta := range smoothingSteps {
+			if _, _, sse := holt(values, alpha, beta); sse < best {
+				best = sse
+			}
+		}
+	}
+	assert.True(t, best < fixed)
+	forecast = exponentialSmoothingForecast(values, 1)
+	assert.InDelta(t, forecastZ*math.Sqrt(best/7), forecast[0].Upper-forecast[0].Value, 0.0001)
+}
+
+func TestBundeslandForecast(t *testing.T) {
+	history := testHistory(forecastDays...)
+	forecast := newBundeslandForecast(history, "Wien", 7)
+	assert.Equal(t, "Wien", forecast.Bundesland)
+	assert.Equal(t, history[4].Time, forecast.Time)
+	assert.Equal(t, 6, len(forecast.Series))
+
+	hospitalized := forecast.Series[3]
+	assert.Equal(t, "hospitalized", hospitalized.Metric)
+	assert.Equal(t, "exponential_smoothing", hospitalized.Model)
+	assert.Equal(t, 7, len(hospitalized.Forecast))
+	assert.Equal(t, history[4].Time.Add(24*time.Hour), hospitalized.Forecast[0].Date)
+	assert.Equal(t, 60.0, hospitalized.Forecast[0].Value)
+
+	forecast = newBundeslandForecast(history[:2], "Wien", 7)
+	assert.Empty(t, forecast.Series[0].Forecast)
+	bytes, _ := json.Marshal(forecast.Series[0])
+	assert.Contains(t, string(bytes), `"forecast":[]`)
+}
+
+func TestForecastExporter(t *testing.T) {
+	c := newCollector(nil)
+	e := newForecastExporter(c)
+	metrics, err := e.GetMetrics()
+	assert.Nil(t, err)
+	assert.Empty(t, metrics)
+
+	history := testHistory(forecastDays...)
+	c.history, c.current = history[:4], history[4]
+	metrics, _ = e.GetMetrics()
+	found := false
+	for _, m := range metrics {
+		tags := *m.Tags
+		if m.Name == "cov19_forecast" && tags["province"] == "Wien" && tags["series"] == "hospitalized" && tags["model"] == "exponential_smoothing" && tags["days"] == "7" {
+			assert.Equal(t, 120.0, m.Value)
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestBundeslandForecastHandler(t *testing.T) {
+	c := newCollector(nil)
+	ts := httptest.NewServer(http.HandlerFunc(c.handleBundeslandForecast))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL + "/api/v1/bundesland/wien/forecast")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	history := testHistory(forecastDays...)
+	c.history, c.current = history[:4], history[4]
+	response, err = ts.Client().Get(ts.URL + "/api/v1/bundesland/wien/forecast?days=3")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	result := bundeslandForecast{}
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&result))
+	assert.Equal(t, "Wien", result.Bundesland)
+	assert.Equal(t, 3, len(result.Series[0].Forecast))
+
+	for path, status := range map[string]int{
+		"/api/v1/bundesland/Bayern/forecast":       http.StatusNotFound,
+		"/api/v1/bundesland/Wien":                  http.StatusNotFound,
+		"/api/v1/bundesland/Wien/forecast?days=15": http.StatusBadRequest,
+		"/api/v1/bundesland/Wien/forecast?days=x":  http.StatusBadRequest,
+	} {
+		response, err = ts.Client().Get(ts.URL + path)
+		assert.Nil(t, err)
+		assert.Equal(t, status, response.StatusCode, path)
+	}
+}
+
+func TestBacktest(t *testing.T) {
+	days := make([]bundeslandStat, 0)
+	for i := 0; i < 10; i++ {
+		days = append(days, bundeslandStat{Infected: uint64(100 + 10*i), Hospitalized: uint64(10 * (i + 1))})
+	}
+	history := testHistory(days...)
+	errors := backtest(history, []int{1, 7})
+	linear := errors[[3]string{"new_cases", "exponential_smoothing", "1"}]
+	assert.Equal(t, 12, linear.forecasts, "6 days of Burgenland and Wien")
+	assert.Equal(t, 0.0, linear.absoluteError)
+	assert.Equal(t, 12, linear.covered)
+	assert.Nil(t, errors[[3]string{"new_cases", "exponential_smoothing", "14"}])
+
+	dir, err := ioutil.TempDir("", "backtest")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "history.json")
+	assert.Nil(t, writeHistory(file, history))
+	var out strings.Builder
+	assert.Nil(t, writeBacktest(file, &out))
+	assert.Contains(t, out.String(), "metric")
+	assert.Contains(t, out.String(), "hospitalized    exponential_smoothing  1     14         0.0    0.0%     100.0%")
+
+	assert.NotNil(t, writeBacktest(filepath.Join(dir, "missing.json"), &out))
+}
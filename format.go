@@ -0,0 +1,291 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type responseFormat string
+
+const (
+	formatJson   responseFormat = "json"
+	formatCsv    responseFormat = "csv"
+	formatNdjson responseFormat = "ndjson"
+)
+
+var formatContentTypes = map[responseFormat]string{
+	formatJson:   "application/json; charset=utf-8",
+	formatCsv:    "text/csv; charset=utf-8",
+	formatNdjson: "application/x-ndjson; charset=utf-8",
+}
+
+var mediaTypeFormats = map[string]responseFormat{
+	"application/json":     formatJson,
+	"text/csv":             formatCsv,
+	"application/x-ndjson": formatNdjson,
+	"application/ndjson":   formatNdjson,
+	"application/*":        formatJson,
+	"text/*":               formatCsv,
+	"*/*":                  formatJson,
+}
+
+//acceptedType is a media range of the Accept header with its quality
+type acceptedType struct {
+	mediaType string
+	quality   float64
+}
+
+//specificity ranks exact media types before type/* and */*
+func (a acceptedType) specificity() int {
+	if a.mediaType == "*/*" {
+		return 0
+	}
+	if strings.HasSuffix(a.mediaType, "/*") {
+		return 1
+	}
+	return 2
+}
+
+//parseAccept returns the media ranges of an Accept header ordered by quality and specificity, ranges with q=0 are left out
+func parseAccept(accept string) []acceptedType {
+	result := make([]acceptedType, 0)
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(mediaRange))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			quality, err = strconv.ParseFloat(q, 64)
+			if err != nil || quality < 0 || quality > 1 {
+				continue
+			}
+		}
+		if quality > 0 {
+			result = append(result, acceptedType{mediaType, quality})
+		}
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].quality != result[j].quality {
+			return result[i].quality > result[j].quality
+		}
+		return result[i].specificity() > result[j].specificity()
+	})
+	return result
+}
+
+//negotiateFormat picks the response format from the format query parameter or the Accept header
+func negotiateFormat(r *http.Request) (responseFormat, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		if _, ok := formatContentTypes[responseFormat(format)]; ok {
+			return responseFormat(format), nil
+		}
+		return "", fmt.Errorf("Unsupported format %q, use json, csv or ndjson", format)
+	}
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return formatJson, nil
+	}
+	for _, a := range parseAccept(accept) {
+		if format, ok := mediaTypeFormats[a.mediaType]; ok {
+			return format, nil
+		}
+	}
+	return "", fmt.Errorf("None of the accepted types %q is supported, use application/json, text/csv or application/x-ndjson", accept)
+}
+
+//writeResponse writes the result of f as json, csv or ndjson depending on the request
+func writeResponse(w http.ResponseWriter, r *http.Request, f func() (interface{}, error)) {
+	format, err := negotiateFormat(r)
+	if err != nil {
+		w.WriteHeader(http.StatusNotAcceptable)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if format == formatJson {
+		writeJson(w, f)
+		return
+	}
+	result, err := f()
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Add("Content-type", formatContentTypes[format])
+	if format == formatCsv {
+		err = writeCsv(w, result)
+	} else {
+		err = writeNdjson(w, result)
+	}
+	if err != nil {
+		logger.Printf("Writing %s failed: %v", format, err)
+	}
+}
+
+//rows returns the elements of a slice or v itself as the only row
+func rows(v interface{}) []reflect.Value {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Slice && value.Kind() != reflect.Array {
+		return []reflect.Value{value}
+	}
+	result := make([]reflect.Value, value.Len())
+	for i := range result {
+		result[i] = value.Index(i)
+	}
+	return result
+}
+
+//writeNdjson writes one json document per row and flushes after every line
+func writeNdjson(w http.ResponseWriter, v interface{}) error {
+	encoder := json.NewEncoder(w)
+	flusher, _ := w.(http.Flusher)
+	for _, row := range rows(v) {
+		err := encoder.Encode(row.Interface())
+		if err != nil {
+			return err
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+	return nil
+}
+
+//writeCsv writes a header and one line per row, nested fields are joined with a dot.
+//The header of an empty list has the columns of its element type.
+func writeCsv(w io.Writer, v interface{}) error {
+	columns := make([]string, 0)
+	known := make(map[string]bool)
+	addColumn := func(column string) {
+		if !known[column] {
+			known[column] = true
+			columns = append(columns, column)
+		}
+	}
+	records := make([]map[string]string, 0)
+	for _, row := range rows(v) {
+		record := make(map[string]string)
+		flatten("", row, record, addColumn)
+		records = append(records, record)
+	}
+	if value := reflect.ValueOf(v); value.Kind() == reflect.Slice && value.Len() == 0 && value.Type().Elem().Kind() == reflect.Struct {
+		flatten("", reflect.Zero(value.Type().Elem()), make(map[string]string), addColumn)
+	}
+
+	writer := csv.NewWriter(w)
+	err := writer.Write(columns)
+	if err != nil {
+		return err
+	}
+	for _, record := range records {
+		line := make([]string, len(columns))
+		for i, column := range columns {
+			line[i] = record[column]
+		}
+		err = writer.Write(line)
+		if err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+//fieldName returns the name of a struct field as it appears in json
+func fieldName(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("json"), ",")[0]
+	if tag == "-" {
+		return ""
+	}
+	if tag != "" {
+		return tag
+	}
+	return field.Name
+}
+
+func joinColumn(prefix string, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func flatten(prefix string, v reflect.Value, record map[string]string, addColumn func(string)) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			addColumn(prefix)
+			return
+		}
+		v = v.Elem()
+	}
+	if r, ok := v.Interface().(sparseRow); ok {
+		for _, f := range r {
+			flatten(joinColumn(prefix, f.name), reflect.ValueOf(f.value), record, addColumn)
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if t, ok := v.Interface().(time.Time); ok {
+			addColumn(prefix)
+			record[prefix] = t.Format(time.RFC3339)
+			return
+		}
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := fieldName(field)
+			if field.PkgPath != "" || name == "" {
+				continue
+			}
+			if field.Anonymous && field.Tag.Get("json") == "" {
+				flatten(prefix, v.Field(i), record, addColumn)
+			} else {
+				flatten(joinColumn(prefix, name), v.Field(i), record, addColumn)
+			}
+		}
+	case reflect.Map:
+		keys := make([]string, 0, v.Len())
+		values := make(map[string]reflect.Value, v.Len())
+		for _, k := range v.MapKeys() {
+			key := fmt.Sprint(k.Interface())
+			keys = append(keys, key)
+			values[key] = v.MapIndex(k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			flatten(joinColumn(prefix, k), values[k], record, addColumn)
+		}
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			flatten(joinColumn(prefix, strconv.Itoa(i)), v.Index(i), record, addColumn)
+		}
+	default:
+		addColumn(prefix)
+		record[prefix] = formatValue(v)
+	}
+}
+
+func formatValue(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(v.Float(), 'f', -1, 64)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10)
+	case reflect.Bool:
+		return strconv.FormatBool(v.Bool())
+	}
+	return fmt.Sprint(v.Interface())
+}
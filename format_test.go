@@ -0,0 +1,107 @@
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateFormat(t *testing.T) {
+	request := httptest.NewRequest("GET", "/api/bezirk", nil)
+	format, err := negotiateFormat(request)
+	assert.Nil(t, err)
+	assert.Equal(t, formatJson, format)
+
+	request.Header.Set("Accept", "text/html, text/csv;q=0.9")
+	format, err = negotiateFormat(request)
+	assert.Nil(t, err)
+	assert.Equal(t, formatCsv, format)
+
+	request.Header.Set("Accept", "text/html,application/xhtml+xml,*/*;q=0.8")
+	format, _ = negotiateFormat(request)
+	assert.Equal(t, formatJson, format)
+
+	request.Header.Set("Accept", "text/html")
+	_, err = negotiateFormat(request)
+	assert.NotNil(t, err)
+
+	request.Header.Set("Accept", "text/csv;q=0.1, application/json")
+	format, _ = negotiateFormat(request)
+	assert.Equal(t, formatJson, format)
+
+	request.Header.Set("Accept", "text/*")
+	format, _ = negotiateFormat(request)
+	assert.Equal(t, formatCsv, format)
+
+	request.Header.Set("Accept", "*/*;q=0.5, application/x-ndjson;q=0.5")
+	format, _ = negotiateFormat(request)
+	assert.Equal(t, formatNdjson, format, "exact types before wildcards of the same quality")
+
+	request.Header.Set("Accept", "application/json;q=0")
+	_, err = negotiateFormat(request)
+	assert.NotNil(t, err)
+
+	request = httptest.NewRequest("GET", "/api/bezirk?format=ndjson", nil)
+	request.Header.Set("Accept", "text/csv")
+	format, _ = negotiateFormat(request)
+	assert.Equal(t, formatNdjson, format)
+
+	request = httptest.NewRequest("GET", "/api/bezirk?format=xml", nil)
+	_, err = negotiateFormat(request)
+	assert.EqualError(t, err, `Unsupported format "xml", use json, csv or ndjson`)
+}
+
+func TestWriteCsv(t *testing.T) {
+	buffer := bytes.Buffer{}
+	err := writeCsv(&buffer, []bezirkStat{
+		{Name: "Innsbruck-Land", Location: apiLocation{Lat: 47.5, Long: 11.25}, Population: 179318, Infected: 150},
+		{Name: "Wels, Stadt", Infected: 3},
+	})
+	assert.Nil(t, err)
+	assert.Equal(t, "name,province,location.latitude,location.longitude,population,infected,infected_per_100k\nInnsbruck-Land,,47.5,11.25,179318,150,0\n\"Wels, Stadt\",,0,0,0,3,0\n", buffer.String())
+
+	buffer.Reset()
+	err = writeCsv(&buffer, overallStat{TotalInfected: 10, AgeDistributionInfection: map[string]uint64{"<5": 1, ">84": 2}})
+	assert.Nil(t, err)
+	assert.Equal(t, "total_infected,total_dead,total_hospitalized,total_intensive_care,total_tests,age_distribution_infection.<5,age_distribution_infection.>84\n10,0,0,0,0,1,2\n", buffer.String())
+
+	buffer.Reset()
+	err = writeCsv(&buffer, []capacityStat{})
+	assert.Nil(t, err)
+	assert.True(t, strings.HasPrefix(buffer.String(), "name,beds,intensive_care_beds,normal_care,"), buffer.String())
+}
+
+func TestApiFormats(t *testing.T) {
+	a, closeMock := newMockApi(t)
+	defer closeMock()
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeResponse(w, r, func() (interface{}, error) { return a.GetBezirkStat() })
+	}))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL + "?format=ndjson")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/x-ndjson; charset=utf-8", response.Header.Get("Content-type"))
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, `{"name":"Innsbruck-Land","province":"Tirol","location":{"latitude":47.121792,"longitude":11.342985},"population":179318,"infected":150,"infected_per_100k":83.65027493057028}
+{"name":"Atlantis","province":"","location":{"latitude":0,"longitude":0},"population":0,"infected":3,"infected_per_100k":0}
+`, string(body))
+
+	request, _ := http.NewRequest("GET", ts.URL, nil)
+	request.Header.Set("Accept", "text/csv")
+	response, err = ts.Client().Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", response.Header.Get("Content-type"))
+	body, _ = ioutil.ReadAll(response.Body)
+	assert.Equal(t, "name,province,location.latitude,location.longitude,population,infected,infected_per_100k\nInnsbruck-Land,Tirol,47.121792,11.342985,179318,150,83.65027493057028\nAtlantis,,0,0,0,3,0\n", string(body))
+
+	request.Header.Set("Accept", "application/xml")
+	response, err = ts.Client().Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotAcceptable, response.StatusCode)
+}
@@ -0,0 +1,176 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+)
+
+type geoJSONGeometry struct {
+	Type        string          `json:"type"`
+	Coordinates json.RawMessage `json:"coordinates"`
+}
+
+type geoJSONFeature struct {
+	Type       string                 `json:"type"`
+	Geometry   *geoJSONGeometry       `json:"geometry"`
+	Properties map[string]interface{} `json:"properties"`
+}
+
+type geoJSONFeatureCollection struct {
+	Type     string           `json:"type"`
+	Features []geoJSONFeature `json:"features"`
+}
+
+//polygon is a list of rings, the first one is the outline and the others are holes
+type polygon [][][2]float64
+
+//boundaryProvider holds the boundary geometries of provinces and districts by name
+type boundaryProvider struct {
+	data map[string]*geoJSONGeometry
+	//names are the names of the features by their normalized name
+	names map[string]string
+}
+
+//newBoundaryProvider returns the simplified boundaries bundled with the binary
+func newBoundaryProvider() *boundaryProvider {
+	result, err := loadBoundaryProvider("")
+	if err != nil {
+		panic(err)
+	}
+	return result
+}
+
+//loadBoundaryProvider reads the embedded grenzen.geojson and merges the features of override on top of it
+func loadBoundaryProvider(override string) (*boundaryProvider, error) {
+	bytes, err := embeddedMetadata.ReadFile("grenzen.geojson")
+	if err != nil {
+		return nil, err
+	}
+	result := &boundaryProvider{data: make(map[string]*geoJSONGeometry), names: make(map[string]string)}
+	err = result.parse("grenzen.geojson", bytes)
+	if err != nil {
+		return nil, err
+	}
+	if override != "" {
+		bytes, err = ioutil.ReadFile(override)
+		if err != nil {
+			return nil, err
+		}
+		err = result.parse(override, bytes)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+func (b *boundaryProvider) parse(filename string, bytes []byte) error {
+	collection := geoJSONFeatureCollection{}
+	err := json.Unmarshal(bytes, &collection)
+	if err != nil {
+		return fmt.Errorf("%s: %v", filename, err)
+	}
+	for i, f := range collection.Features {
+		name, ok := f.Properties["name"].(string)
+		if !ok || f.Geometry == nil {
+			return fmt.Errorf("%s: feature %d needs a name property and a geometry", filename, i)
+		}
+		_, err := f.Geometry.polygons()
+		if err != nil {
+			return fmt.Errorf("%s: feature %s: %v", filename, name, err)
+		}
+		b.data[normalizeName(name)] = f.Geometry
+		b.names[normalizeName(name)] = name
+	}
+	return nil
+}
+
+//getGeometry returns the boundary of a province or district, nil if unknown
+func (b *boundaryProvider) getGeometry(name string) *geoJSONGeometry {
+	return b.data[normalizeName(name)]
+}
+
+//polygons returns the rings of a Polygon or MultiPolygon geometry
+func (g *geoJSONGeometry) polygons() ([]polygon, error) {
+	switch g.Type {
+	case "Polygon":
+		p := polygon{}
+		err := json.Unmarshal(g.Coordinates, &p)
+		return []polygon{p}, err
+	case "MultiPolygon":
+		result := make([]polygon, 0)
+		err := json.Unmarshal(g.Coordinates, &result)
+		return result, err
+	}
+	return nil, fmt.Errorf("Unsupported geometry type %s", g.Type)
+}
+
+func pointGeometry(l apiLocation) *geoJSONGeometry {
+	coordinates, _ := json.Marshal([2]float64{l.Long, l.Lat})
+	return &geoJSONGeometry{Type: "Point", Coordinates: coordinates}
+}
+
+//toProperties converts a stat to the property map of a feature, using the same names as the json api
+func toProperties(stat interface{}) (map[string]interface{}, error) {
+	bytes, err := json.Marshal(stat)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	err = json.Unmarshal(bytes, &result)
+	return result, err
+}
+
+func (b *boundaryProvider) newFeature(name string, location apiLocation, stat interface{}) (geoJSONFeature, error) {
+	properties, err := toProperties(stat)
+	if err != nil {
+		return geoJSONFeature{}, err
+	}
+	geometry := b.getGeometry(name)
+	if geometry == nil {
+		geometry = pointGeometry(location)
+	}
+	return geoJSONFeature{Type: "Feature", Geometry: geometry, Properties: properties}, nil
+}
+
+func (a *api) GetBundeslandGeoJSON() (geoJSONFeatureCollection, error) {
+	stats, err := a.GetBundeslandStat()
+	if err != nil {
+		return geoJSONFeatureCollection{}, err
+	}
+	result := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, 0, len(stats))}
+	for _, s := range stats {
+		feature, err := a.boundaries.newFeature(s.Name, s.Location, s)
+		if err != nil {
+			return geoJSONFeatureCollection{}, err
+		}
+		if s.Population > 0 {
+			feature.Properties["infection_rate"] = infectionRate(s.Infected, s.Population)
+		}
+		if s.Infected > 0 {
+			feature.Properties["fatality_rate"] = fatalityRate(s.Infected, s.Dead)
+		}
+		result.Features = append(result.Features, feature)
+	}
+	return result, nil
+}
+
+func (a *api) GetBezirkGeoJSON() (geoJSONFeatureCollection, error) {
+	stats, err := a.GetBezirkStat()
+	if err != nil {
+		return geoJSONFeatureCollection{}, err
+	}
+	result := geoJSONFeatureCollection{Type: "FeatureCollection", Features: make([]geoJSONFeature, 0, len(stats))}
+	for _, s := range stats {
+		feature, err := a.boundaries.newFeature(s.Name, s.Location, s)
+		if err != nil {
+			return geoJSONFeatureCollection{}, err
+		}
+		if s.Population > 0 {
+			feature.Properties["infection_rate"] = infectionRate(s.Infected, s.Population)
+		}
+		result.Features = append(result.Features, feature)
+	}
+	return result, nil
+}
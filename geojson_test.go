@@ -0,0 +1,94 @@
+package main
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBoundaries(t *testing.T) {
+	boundaries := newBoundaryProvider()
+	for _, province := range austriaRegions[1:] {
+		geometry := boundaries.getGeometry(province)
+		assert.NotNil(t, geometry, province)
+		polygons, err := geometry.polygons()
+		assert.Nil(t, err)
+		assert.True(t, len(polygons) > 0)
+	}
+
+	polygons, _ := boundaries.getGeometry("Tirol").polygons()
+	assert.Equal(t, 2, len(polygons))
+	polygons, _ = boundaries.getGeometry("Niederösterreich").polygons()
+	assert.Equal(t, 2, len(polygons[0]))
+	assert.Nil(t, boundaries.getGeometry("xxxxx"))
+}
+
+func TestBezirkBoundaries(t *testing.T) {
+	boundaries := newBoundaryProvider()
+	missing := make([]string, 0)
+	districts := 0
+	for name, m := range newBezirkeMetadataProvider().data {
+		//bezirke.csv lists the provinces too
+		if normalizeName(m.province) == name {
+			continue
+		}
+		districts++
+		geometry := boundaries.getGeometry(name)
+		if geometry == nil || (geometry.Type != "Polygon" && geometry.Type != "MultiPolygon") {
+			missing = append(missing, m.country)
+		}
+	}
+	if len(missing) == districts {
+		t.Skip("grenzen.geojson has no district polygons yet, they are generated with make boundaries")
+	}
+	assert.Equal(t, []string{}, missing, "districts of bezirke.csv without a polygon")
+}
+
+func TestBoundariesOverride(t *testing.T) {
+	filename := writeTempMetadata(t, `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Innsbruck-Land"},"geometry":{"type":"Polygon","coordinates":[[[11.0,47.0],[11.5,47.0],[11.5,47.3],[11.0,47.0]]]}}]}`)
+	defer os.Remove(filename)
+	boundaries, err := loadBoundaryProvider(filename)
+	assert.Nil(t, err)
+	assert.NotNil(t, boundaries.getGeometry("Innsbruck-Land"))
+	assert.NotNil(t, boundaries.getGeometry("Wien"))
+
+	filename = writeTempMetadata(t, `{"type":"FeatureCollection","features":[{"type":"Feature","properties":{"name":"Wien"},"geometry":{"type":"Line","coordinates":[]}}]}`)
+	defer os.Remove(filename)
+	_, err = loadBoundaryProvider(filename)
+	assert.EqualError(t, err, filename+": feature Wien: Unsupported geometry type Line")
+}
+
+func TestBundeslandGeoJSON(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	result, err := mockApi.GetBundeslandGeoJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, "FeatureCollection", result.Type)
+	assert.Equal(t, 3, len(result.Features))
+	for _, f := range result.Features {
+		assert.Equal(t, "Feature", f.Type)
+		assert.Contains(t, []string{"Polygon", "MultiPolygon"}, f.Geometry.Type)
+		if f.Properties["name"] == "Wien" {
+			assert.Equal(t, float64(1500), f.Properties["infected"])
+			assert.Equal(t, float64(25), f.Properties["intensive_care"])
+			assert.Equal(t, 0.02, f.Properties["fatality_rate"])
+			assert.InDelta(t, 79.4, f.Properties["infected_per_100k"], 0.1)
+		}
+	}
+}
+
+func TestBezirkGeoJSON(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	result, err := mockApi.GetBezirkGeoJSON()
+	assert.Nil(t, err)
+	assert.Equal(t, 2, len(result.Features))
+	assert.Equal(t, "Point", result.Features[0].Geometry.Type)
+	assert.Equal(t, "[11.342985,47.121792]", string(result.Features[0].Geometry.Coordinates))
+	assert.NotNil(t, result.Features[0].Properties["infected_per_100k"])
+	assert.Equal(t, float64(0), result.Features[1].Properties["infected_per_100k"])
+	assert.Nil(t, result.Features[1].Properties["infection_rate"])
+}
@@ -1,8 +1,9 @@
 module github.com/cinemast/covid19-at
 
-go 1.14
+go 1.16
 
 require (
 	github.com/PuerkitoBio/goquery v1.5.1
+	github.com/andybalholm/brotli v1.1.0
 	github.com/stretchr/testify v1.5.1
 )
@@ -3,15 +3,14 @@ package main
 import (
 	"encoding/json"
 	"fmt"
-	"io/ioutil"
-	"net/http"
 	"regexp"
-	"time"
+	"sort"
 )
 
 type healthMinistryExporter struct {
-	mp  *metadataProvider
-	url string
+	mp   *metadataProvider
+	ages *agePopulationProvider
+	url  string
 }
 
 type ministryStat []struct {
@@ -19,8 +18,25 @@ type ministryStat []struct {
 	Y     uint64
 }
 
+//readMinistryStat reads the array of a javascript file of the health ministry, it is only parsed when the file changed
+func readMinistryStat(url string) (ministryStat, error) {
+	result, err := upstream.parse("health_ministry", url, func(body []byte) (interface{}, error) {
+		arrayString, err := readArray(body)
+		if err != nil {
+			return nil, err
+		}
+		stats := ministryStat{}
+		err = json.Unmarshal([]byte(arrayString), &stats)
+		return stats, err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return result.(ministryStat), nil
+}
+
 func newHealthMinistryExporter() *healthMinistryExporter {
-	return &healthMinistryExporter{mp: newMetadataProviderWithFilename("bezirke.csv"), url: "https://info.gesundheitsministerium.at/data"}
+	return &healthMinistryExporter{mp: newBezirkeMetadataProvider(), ages: newAgePopulationProvider(), url: defaultConfig().Sources.HealthMinistry}
 }
 
 func checkTags(result metrics, field string) []error {
@@ -41,6 +57,7 @@ func (h *healthMinistryExporter) GetMetrics() (metrics, error) {
 
 	result, err := h.getAgeMetrics()
 	metrics = append(metrics, result...)
+	metrics = append(metrics, h.getAgePopulationMetrics()...)
 
 	result, err = h.getGeschlechtsVerteilung()
 	metrics = append(metrics, result...)
@@ -108,19 +125,22 @@ func (h *healthMinistryExporter) getTags(location string, fieldName string, data
 }
 
 func (h *healthMinistryExporter) getBezirkStat() ([]bezirkStat, error) {
-	arrayString, err := readArrayFromGet(h.url + "/Bezirke.js")
-	if err != nil {
-		return nil, err
-	}
-	bezirkeStats := ministryStat{}
-	err = json.Unmarshal([]byte(arrayString), &bezirkeStats)
+	bezirkeStats, err := readMinistryStat(h.url + "/Bezirke.js")
 	if err != nil {
 		return nil, err
 	}
 	result := make([]bezirkStat, 0)
 	for _, s := range bezirkeStats {
-		data := h.mp.getMetadata(s.Label)
-		result = append(result, bezirkStat{s.Label, apiLocaiton{Lat: data.location.lat, Long: data.location.long}, data.population, s.Y})
+		stat := bezirkStat{Name: s.Label, Infected: s.Y}
+		if data := h.mp.getMetadata(s.Label); data != nil {
+			stat.Province = data.province
+			stat.Location = apiLocation{Lat: data.location.lat, Long: data.location.long}
+			stat.Population = data.population
+		}
+		if stat.Population > 0 {
+			stat.InfectedPer100k = infection100k(stat.Infected, stat.Population)
+		}
+		result = append(result, stat)
 	}
 	return result, nil
 }
@@ -185,12 +205,7 @@ func (h *healthMinistryExporter) getBundeslandInfectedMetric() (metrics, error)
 }
 
 func (h *healthMinistryExporter) getBundeslandInfected() (map[string]uint64, error) {
-	arrayString, err := readArrayFromGet(h.url + "/Bundesland.js")
-	if err != nil {
-		return nil, err
-	}
-	bundeslandStats := ministryStat{}
-	err = json.Unmarshal([]byte(arrayString), &bundeslandStats)
+	bundeslandStats, err := readMinistryStat(h.url + "/Bundesland.js")
 	if err != nil {
 		return nil, err
 	}
@@ -203,12 +218,7 @@ func (h *healthMinistryExporter) getBundeslandInfected() (map[string]uint64, err
 }
 
 func (h *healthMinistryExporter) getAgeStat() (map[string]uint64, error) {
-	arrayString, err := readArrayFromGet(h.url + "/Altersverteilung.js")
-	if err != nil {
-		return nil, err
-	}
-	ageStats := ministryStat{}
-	err = json.Unmarshal([]byte(arrayString), &ageStats)
+	ageStats, err := readMinistryStat(h.url + "/Altersverteilung.js")
 	if err != nil {
 		return nil, err
 	}
@@ -225,43 +235,72 @@ func (h *healthMinistryExporter) getAgeMetrics() (metrics, error) {
 		return nil, err
 	}
 	result := make(metrics, 0)
-	for k, v := range ageMetrics {
-		tags := &map[string]string{"country": "Austria", "group": k}
-		result = append(result, metric{"cov19_age_distribution", tags, float64(v)})
+	for _, s := range h.ages.getAgeStats("Austria", ageMetrics) {
+		tags := &map[string]string{"country": "Austria", "group": s.Group}
+		result = append(result, metric{"cov19_age_distribution", tags, float64(s.Infected)})
+		if s.Population > 0 {
+			result = append(result, metric{"cov19_age_infected_per_100k", tags, s.InfectedPer100k})
+		}
 	}
 	return result, nil
 }
 
-func (h *healthMinistryExporter) getGeschlechtsVerteilung() (metrics, error) {
-	arrayString, err := readArrayFromGet(h.url + "/Geschlechtsverteilung.js")
+func (h *healthMinistryExporter) getAgePopulationMetrics() metrics {
+	result := make(metrics, 0)
+	for _, region := range h.ages.regions {
+		for _, group := range h.ages.groups {
+			tags := map[string]string{"country": "Austria", "group": group}
+			if normalizeName(region) != normalizeName("Austria") {
+				tags["province"] = region
+			}
+			result = append(result, metric{"cov19_age_population", &tags, float64(h.ages.getPopulation(region, group))})
+		}
+	}
+	return result
+}
+
+//getSexStat returns the confirmed infections by sex
+func (h *healthMinistryExporter) getSexStat() (map[string]uint64, error) {
+	sexStats, err := readMinistryStat(h.url + "/Geschlechtsverteilung.js")
 	if err != nil {
 		return nil, err
 	}
-	ageStats := ministryStat{}
-	err = json.Unmarshal([]byte(arrayString), &ageStats)
+	result := make(map[string]uint64)
+	for _, s := range sexStats {
+		result[s.Label] = s.Y
+	}
+	return result, nil
+}
+
+func (h *healthMinistryExporter) getGeschlechtsVerteilung() (metrics, error) {
+	sexStats, err := h.getSexStat()
 	if err != nil {
 		return nil, err
 	}
+	sexes := make([]string, 0, len(sexStats))
+	for sex := range sexStats {
+		sexes = append(sexes, sex)
+	}
+	sort.Strings(sexes)
 	result := make(metrics, 0)
-	for _, s := range ageStats {
-		tags := &map[string]string{"country": "Austria", "sex": s.Label}
-		result = append(result, metric{"cov19_sex_distribution", tags, float64(s.Y)})
+	for _, sex := range sexes {
+		tags := &map[string]string{"country": "Austria", "sex": sex}
+		result = append(result, metric{"cov19_sex_distribution", tags, float64(sexStats[sex])})
 	}
 	return result, nil
 }
 
 func (h *healthMinistryExporter) getSimpleData() (metrics, []error) {
-	client := http.Client{Timeout: 5 * time.Second}
 	errors := make([]error, 0)
-	response, err := client.Get(h.url + "/SimpleData.js")
+	parsed, err := upstream.parse("health_ministry", h.url+"/SimpleData.js", func(lines []byte) (interface{}, error) {
+		return regexp.MustCompile(`Erkrankungen = ([0-9]+)`).FindStringSubmatch(string(lines)), nil
+	})
 	result := make(metrics, 0)
 	if err != nil {
 		return nil, []error{err}
 	}
-	defer response.Body.Close()
-	lines, err := ioutil.ReadAll(response.Body)
 
-	erkrankungenMatch := regexp.MustCompile(`Erkrankungen = ([0-9]+)`).FindStringSubmatch(string(lines))
+	erkrankungenMatch := parsed.([]string)
 	if len(erkrankungenMatch) != 2 {
 		errors = append(errors, fmt.Errorf("Could not find \"Bestätigte Fälle\""))
 	} else {
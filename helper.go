@@ -2,11 +2,8 @@ package main
 
 import (
 	"errors"
-	"io/ioutil"
-	"net/http"
 	"strconv"
 	"strings"
-	"time"
 )
 
 func atoi(s string) uint64 {
@@ -47,17 +44,8 @@ func infection100k(infections uint64, population uint64) float64 {
 	return infectionRate(infections, population) * float64(100000)
 }
 
-func readArrayFromGet(url string) (string, error) {
-	client := http.Client{Timeout: 5 * time.Second}
-	response, err := client.Get(url)
-	if err != nil {
-		return "", err
-	}
-	defer response.Body.Close()
-	json, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return "", err
-	}
+//readArray returns the first to the last bracket of a javascript file
+func readArray(json []byte) (string, error) {
 	jsonString := string(json)
 	arrayBegin := strings.Index(jsonString, "[")
 	if arrayBegin == -1 {
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"net/http"
+)
+
+//The unversioned /api routes are deprecated in favour of /api/v1. They keep returning
+//the field names of the Go structs they were generated from before the api was versioned.
+
+type legacyLocation struct {
+	Lat  float64
+	Long float64
+}
+
+type legacyBundeslandStat struct {
+	Name          string
+	Location      legacyLocation
+	Population    uint64
+	Infected      uint64
+	Dead          uint64
+	Hospitalized  uint64
+	IntensiveCare uint64
+}
+
+type legacyBezirkStat struct {
+	Name       string
+	Location   legacyLocation
+	Population uint64
+	Infected   uint64
+}
+
+type legacyOverallStat struct {
+	TotalInfected            uint64
+	TotalDead                uint64
+	TotalHospitalized        uint64
+	TotalIntensiveCare       uint64
+	AgeDistributionInfection map[string]uint64
+}
+
+type legacyAgeStat struct {
+	Group           string
+	Infected        uint64
+	InfectedShare   float64
+	Population      uint64
+	PopulationShare float64
+	InfectedPer100k float64
+}
+
+//legacyPropertyNames maps the GeoJSON properties of /api/v1 to the ones of the unversioned api
+var legacyPropertyNames = map[string]string{
+	"name":              "Name",
+	"location":          "Location",
+	"latitude":          "Lat",
+	"longitude":         "Long",
+	"population":        "Population",
+	"infected":          "Infected",
+	"dead":              "Dead",
+	"hospitalized":      "Hospitalized",
+	"intensive_care":    "IntensiveCare",
+	"province":          "Province",
+	"infected_per_100k": "InfectedPer100k",
+	"infection_rate":    "InfectionRate",
+	"dead_per_100k":     "DeadPer100k",
+	"fatality_rate":     "FatalityRate",
+}
+
+func toLegacyLocation(l apiLocation) legacyLocation {
+	return legacyLocation{Lat: l.Lat, Long: l.Long}
+}
+
+func toLegacyBundeslandStats(stats []bundeslandStat) []legacyBundeslandStat {
+	result := make([]legacyBundeslandStat, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, legacyBundeslandStat{s.Name, toLegacyLocation(s.Location), s.Population, s.Infected, s.Dead, s.Hospitalized, s.IntensiveCare})
+	}
+	return result
+}
+
+func toLegacyBezirkStats(stats []bezirkStat) []legacyBezirkStat {
+	result := make([]legacyBezirkStat, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, legacyBezirkStat{s.Name, toLegacyLocation(s.Location), s.Population, s.Infected})
+	}
+	return result
+}
+
+func toLegacyOverallStat(s overallStat) legacyOverallStat {
+	return legacyOverallStat{s.TotalInfected, s.TotalDead, s.TotalHospitalized, s.TotalIntensiveCare, s.AgeDistributionInfection}
+}
+
+func toLegacyAgeStats(stats []ageStat) []legacyAgeStat {
+	result := make([]legacyAgeStat, 0, len(stats))
+	for _, s := range stats {
+		result = append(result, legacyAgeStat{s.Group, s.Infected, s.InfectedShare, s.Population, s.PopulationShare, s.InfectedPer100k})
+	}
+	return result
+}
+
+func toLegacyProperties(properties map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(properties))
+	for k, v := range properties {
+		if nested, ok := v.(map[string]interface{}); ok {
+			v = toLegacyProperties(nested)
+		}
+		if legacyName, ok := legacyPropertyNames[k]; ok {
+			k = legacyName
+		}
+		result[k] = v
+	}
+	return result
+}
+
+func toLegacyGeoJSON(collection geoJSONFeatureCollection) geoJSONFeatureCollection {
+	for i, f := range collection.Features {
+		collection.Features[i].Properties = toLegacyProperties(f.Properties)
+	}
+	return collection
+}
+
+//deprecated marks the response of an unversioned route and points to its successor in /api/v1
+func deprecated(successor string, h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+successor+`>; rel="successor-version"`)
+		h(w, r)
+	}
+}
+
+func handleApiBundesland(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, func() (interface{}, error) {
+		stats, err := a.GetBundeslandStat()
+		return toLegacyBundeslandStats(stats), err
+	})
+}
+
+func handleApiBezirk(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, func() (interface{}, error) {
+		stats, err := a.GetBezirkStat()
+		return toLegacyBezirkStats(stats), err
+	})
+}
+
+func handleApiTotal(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, func() (interface{}, error) {
+		stat, err := a.GetOverallStat()
+		return toLegacyOverallStat(stat), err
+	})
+}
+
+func handleApiAge(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, func() (interface{}, error) {
+		stats, err := a.GetAgeStat()
+		return toLegacyAgeStats(stats), err
+	})
+}
+
+func handleApiBundeslandGeoJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJsonWithContentType(w, "application/geo+json", func() (interface{}, error) {
+		collection, err := a.GetBundeslandGeoJSON()
+		return toLegacyGeoJSON(collection), err
+	})
+}
+
+func handleApiBezirkGeoJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJsonWithContentType(w, "application/geo+json", func() (interface{}, error) {
+		collection, err := a.GetBezirkGeoJSON()
+		return toLegacyGeoJSON(collection), err
+	})
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLegacyApi(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+	defaultApi := a
+	a = mockApi
+	defer func() { a = defaultApi }()
+
+	ts := httptest.NewServer(deprecated("/api/v1/bezirk", handleApiBezirk))
+	defer ts.Close()
+	response, err := ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "true", response.Header.Get("Deprecation"))
+	assert.Equal(t, `</api/v1/bezirk>; rel="successor-version"`, response.Header.Get("Link"))
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, `[{"Name":"Innsbruck-Land","Location":{"Lat":47.121792,"Long":11.342985},"Population":179318,"Infected":150},{"Name":"Atlantis","Location":{"Lat":0,"Long":0},"Population":0,"Infected":3}]`, string(body))
+}
+
+func TestLegacyGeoJSON(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	collection, err := mockApi.GetBundeslandGeoJSON()
+	assert.Nil(t, err)
+	legacy := toLegacyGeoJSON(collection)
+	for _, f := range legacy.Features {
+		assert.NotNil(t, f.Properties["Name"])
+		assert.NotNil(t, f.Properties["IntensiveCare"])
+		assert.NotNil(t, f.Properties["FatalityRate"])
+		assert.NotNil(t, f.Properties["Location"].(map[string]interface{})["Lat"])
+		assert.Nil(t, f.Properties["name"])
+	}
+}
+
+func TestLegacyHandlers(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+	defaultApi := a
+	a = mockApi
+	defer func() { a = defaultApi }()
+
+	for _, h := range []http.HandlerFunc{handleApiBundesland, handleApiTotal, handleApiAge, handleApiBundeslandGeoJSON, handleApiBezirkGeoJSON} {
+		recorder := httptest.NewRecorder()
+		h(recorder, httptest.NewRequest("GET", "/", nil))
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.NotContains(t, recorder.Body.String(), `"infected"`)
+	}
+}
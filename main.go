@@ -2,26 +2,60 @@ package main
 
 import (
 	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 )
 
 var logger = log.New(os.Stdout, "covid19-at", 0)
 var mp = newMetadataProvider()
 var he = newHealthMinistryExporter()
 var se = newSocialMinistryExporter(mp)
+var ee = newEcdcExporter(mp)
+var mde = newMathdroExporter()
+var rl = newReloader("")
 var exporters = []Exporter{
 	he,
 	se,
-	newEcdcExporter(mp),
-	newMathdroExporter(),
+	ee,
+	mde,
+	rl,
+	upstream,
+	wh,
+	ae,
+	fe,
+	ce,
+	te,
 }
 
-var a = newApi(he, se)
+var a = newApi(he, se, ee, mde)
+
+var responses = newResponseCache(defaultConfig().refreshInterval())
+
+var cl = newCollector(a)
+
+var wh = newWebhookDispatcher()
+
+var ae = newAlertEngine()
+
+var fe = newForecastExporter(cl)
+
+var cp = newCapacityProvider()
+
+var ce = newCapacityExporter(cl, cp)
+
+var te = newTestExporter(cl)
 
 func writeJson(w http.ResponseWriter, f func() (interface{}, error)) {
+	writeJsonWithContentType(w, "application/json; charset=utf-8", f)
+}
+
+func writeJsonWithContentType(w http.ResponseWriter, contentType string, f func() (interface{}, error)) {
 	result, err := f()
 	if err != nil {
 		w.WriteHeader(500)
@@ -32,30 +66,75 @@ func writeJson(w http.ResponseWriter, f func() (interface{}, error)) {
 			w.WriteHeader(500)
 			w.Write([]byte(err.Error()))
 		} else {
-			w.Header().Add("Content-type", "application/json; charset=utf-8")
+			w.Header().Add("Content-type", contentType)
 			w.Write(bytes)
 		}
 	}
 }
 
-func handleApiBundesland(w http.ResponseWriter, _ *http.Request) {
-	writeJson(w, func() (interface{}, error) { return a.GetBundeslandStat() })
+func handleApiV1Bundesland(w http.ResponseWriter, r *http.Request) {
+	writeListResponse(w, r, bundeslandStat{}, func() (interface{}, error) { return a.GetBundeslandStat() })
+}
+
+func handleApiV1Bezirk(w http.ResponseWriter, r *http.Request) {
+	writeListResponse(w, r, bezirkStat{}, func() (interface{}, error) { return a.GetBezirkStat() })
+}
+
+func handleApiV1Total(w http.ResponseWriter, r *http.Request) {
+	writeResponse(w, r, func() (interface{}, error) { return a.GetOverallStat() })
+}
+
+func handleApiV1Age(w http.ResponseWriter, r *http.Request) {
+	writeListResponse(w, r, ageStat{}, func() (interface{}, error) { return a.GetAgeStat() })
+}
+
+func handleApiV1World(w http.ResponseWriter, r *http.Request) {
+	writeListResponse(w, r, worldStat{}, func() (interface{}, error) { return a.GetWorldStat() })
+}
+
+func handleApiV1Country(w http.ResponseWriter, r *http.Request) {
+	country := strings.TrimPrefix(r.URL.Path, "/api/v1/world/")
+	stat, err := a.GetCountryStat(country)
+	if err != nil {
+		w.WriteHeader(500)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	if stat == nil {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(fmt.Sprintf("Unknown country %q", country)))
+		return
+	}
+	writeResponse(w, r, func() (interface{}, error) { return *stat, nil })
+}
+
+func handleApiV1Continent(w http.ResponseWriter, r *http.Request) {
+	writeListResponse(w, r, continentStat{}, func() (interface{}, error) { return a.GetContinentStat() })
 }
 
-func handleApiBezirk(w http.ResponseWriter, _ *http.Request) {
-	writeJson(w, func() (interface{}, error) { return a.GetBezirkStat() })
+func handleApiV1BundeslandGeoJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJsonWithContentType(w, "application/geo+json", func() (interface{}, error) { return a.GetBundeslandGeoJSON() })
 }
 
-func handleApiTotal(w http.ResponseWriter, _ *http.Request) {
-	writeJson(w, func() (interface{}, error) { return a.GetOverallStat() })
+func handleApiV1BezirkGeoJSON(w http.ResponseWriter, _ *http.Request) {
+	writeJsonWithContentType(w, "application/geo+json", func() (interface{}, error) { return a.GetBezirkGeoJSON() })
+}
+
+//exporterName is the type name of an exporter, e.g. healthMinistryExporter
+func exporterName(e Exporter) string {
+	return strings.TrimPrefix(fmt.Sprintf("%T", e), "*main.")
 }
 
 func handleMetrics(w http.ResponseWriter, _ *http.Request) {
 	for _, e := range exporters {
 		metrics, err := e.GetMetrics()
+		scrapeError := 0.0
 		if err == nil {
 			writeMetrics(metrics, w)
+		} else {
+			scrapeError = 1
 		}
+		writeMetrics([]metric{{Name: "cov19_scrape_error", Tags: &map[string]string{"exporter": exporterName(e)}, Value: scrapeError}}, w)
 	}
 }
 
@@ -77,11 +156,120 @@ func handleHealth(w http.ResponseWriter, _ *http.Request) {
 	}
 }
 
+func reloadOnSignal(r *reloader) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGHUP)
+	for range signals {
+		err := r.reload()
+		if err != nil {
+			logger.Printf("Reload failed: %v", err)
+		} else {
+			logger.Print("Reloaded configuration")
+		}
+	}
+}
+
 func main() {
-	http.HandleFunc("/metrics", handleMetrics)
-	http.HandleFunc("/health", handleHealth)
-	http.HandleFunc("/api/bundesland", handleApiBundesland)
-	http.HandleFunc("/api/bezirk", handleApiBezirk)
-	http.HandleFunc("/api/total", handleApiTotal)
-	http.ListenAndServe(":8282", nil)
+	configFile := flag.String("config", "", "path to json config file")
+	report := flag.String("report", "", "print the daily report (markdown, html or text) and exit")
+	snapshotFile := flag.String("snapshot", "", "json file with the snapshot the report changes are computed against, updated once a day")
+	rules := flag.Bool("rules", false, "print the Prometheus recording and alerting rules and exit")
+	dashboardDir := flag.String("dashboards", "", "write the Grafana dashboards into the directory and exit")
+	simulation := flag.String("simulate", "", "run the SEIR simulation from the current data, print it as json or csv and exit")
+	parametersFile := flag.String("parameters", "", "json file with the parameters of -simulate")
+	historyFile := flag.String("history", "", "json file the daily snapshots are kept in across restarts, updated once a day")
+	backtestFile := flag.String("backtest", "", "json file with daily snapshots like -history, print the historical error of the forecasts and exit")
+	flag.Parse()
+
+	if *rules {
+		err := writeRules(os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if *dashboardDir != "" {
+		err := writeDashboards(*dashboardDir)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if *backtestFile != "" {
+		err := writeBacktest(*backtestFile, os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+
+	rl.filename = *configFile
+	err := rl.reload()
+	if err != nil {
+		logger.Fatal(err)
+	}
+	if *historyFile != "" {
+		err = cl.loadHistory(*historyFile)
+		if err != nil {
+			logger.Fatal(err)
+		}
+	}
+	if *report != "" {
+		logger.SetOutput(os.Stderr)
+		err = writeDailyReport(cl, *report, *snapshotFile, os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	if *simulation != "" {
+		logger.SetOutput(os.Stderr)
+		err = writeSimulation(cl, *simulation, *parametersFile, os.Stdout)
+		if err != nil {
+			logger.Fatal(err)
+		}
+		return
+	}
+	go reloadOnSignal(rl)
+	go cl.run()
+	go wh.run(cl)
+	go ae.run(cl)
+
+	http.HandleFunc("/", onlyRoot(responses.cached(withConfigLock(cl.handleIndex))))
+	http.HandleFunc("/metrics", responses.cached(withConfigLock(handleMetrics)))
+	http.HandleFunc("/health", withConfigLock(handleHealth))
+	http.HandleFunc("/api/v1/bundesland", responses.cached(withConfigLock(handleApiV1Bundesland)))
+	http.HandleFunc("/api/v1/bezirk", responses.cached(withConfigLock(handleApiV1Bezirk)))
+	http.HandleFunc("/api/v1/total", responses.cached(withConfigLock(handleApiV1Total)))
+	http.HandleFunc("/api/v1/age", responses.cached(withConfigLock(handleApiV1Age)))
+	http.HandleFunc("/api/v1/world", responses.cached(withConfigLock(handleApiV1World)))
+	http.HandleFunc("/api/v1/world/", responses.cached(withConfigLock(handleApiV1Country)))
+	http.HandleFunc("/api/v1/continent", responses.cached(withConfigLock(handleApiV1Continent)))
+	http.HandleFunc("/api/v1/capacity", responses.cached(withConfigLock(ce.handleApiV1Capacity)))
+	http.HandleFunc("/api/v1/tests", responses.cached(withConfigLock(te.handleApiV1Tests)))
+	http.HandleFunc("/api/v1/bundesland.geojson", responses.cached(withConfigLock(handleApiV1BundeslandGeoJSON)))
+	http.HandleFunc("/api/v1/bezirk.geojson", responses.cached(withConfigLock(handleApiV1BezirkGeoJSON)))
+	http.HandleFunc("/api/v1/bundesland/", responses.cached(withConfigLock(cl.handleBundeslandForecast)))
+	//the simulation is posted, its result depends on the parameters in the body and can not be cached by url
+	http.HandleFunc("/api/v1/simulate", withConfigLock(cl.handleSimulate))
+	http.HandleFunc("/api/v1/stream", cl.handleStream)
+	http.HandleFunc("/report/daily", responses.cached(cl.handleDailyReport))
+	http.HandleFunc("/feed.atom", responses.cached(cl.handleAtom))
+	http.HandleFunc("/feed.rss", responses.cached(cl.handleRSS))
+	http.HandleFunc("/feed/bundesland/", responses.cached(cl.handleBundeslandFeed))
+	http.HandleFunc("/chart/", responses.cached(withConfigLock(cl.handleChart)))
+	http.HandleFunc("/map/", responses.cached(withConfigLock(cl.handleMap)))
+	http.HandleFunc("/api/openapi.json", responses.cached(handleOpenAPI))
+	http.HandleFunc("/api/bundesland", responses.cached(deprecated("/api/v1/bundesland", withConfigLock(handleApiBundesland))))
+	http.HandleFunc("/api/bezirk", responses.cached(deprecated("/api/v1/bezirk", withConfigLock(handleApiBezirk))))
+	http.HandleFunc("/api/total", responses.cached(deprecated("/api/v1/total", withConfigLock(handleApiTotal))))
+	http.HandleFunc("/api/age", responses.cached(deprecated("/api/v1/age", withConfigLock(handleApiAge))))
+	http.HandleFunc("/api/bundesland.geojson", responses.cached(deprecated("/api/v1/bundesland.geojson", withConfigLock(handleApiBundeslandGeoJSON))))
+	http.HandleFunc("/api/bezirk.geojson", responses.cached(deprecated("/api/v1/bezirk.geojson", withConfigLock(handleApiBezirkGeoJSON))))
+	http.HandleFunc("/admin/reload", rl.handleReload)
+	http.HandleFunc("/admin/config", rl.handleStatus)
+	http.HandleFunc("/admin/webhooks", wh.handleDeliveries)
+	http.HandleFunc("/admin/webhooks/test", wh.handlePing)
+	http.HandleFunc("/admin/alerts", ae.handleAlerts)
+	logger.Fatal(http.ListenAndServe(rl.config.Listen, nil))
 }
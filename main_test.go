@@ -4,12 +4,18 @@ import (
 	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"os"
 	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func TestMain(m *testing.M) {
+	useTestFetcher()
+	os.Exit(m.Run())
+}
+
 func TestHealth(t *testing.T) {
 	ts := httptest.NewServer(http.HandlerFunc(handleHealth))
 	defer ts.Close()
@@ -74,6 +80,7 @@ func TestMetrics(t *testing.T) {
 	assert.True(t, strings.Contains(metricResult, "cov19_world_death"))
 	assert.True(t, strings.Contains(metricResult, "cov19_detail"))
 	assert.True(t, strings.Contains(metricResult, "cov19_detail_dead"))
+	assert.True(t, strings.Contains(metricResult, `cov19_scrape_error{exporter="healthMinistryExporter"}`))
 }
 
 func TestApiOverall(t *testing.T) {
@@ -2,9 +2,6 @@ package main
 
 import (
 	"encoding/json"
-	"io/ioutil"
-	"net/http"
-	"time"
 )
 
 type mathdroExporter struct {
@@ -20,7 +17,7 @@ type recoveredStats []struct {
 }
 
 func newMathdroExporter() *mathdroExporter {
-	return &mathdroExporter{url: "https://covid19.mathdro.id/api/"}
+	return &mathdroExporter{url: defaultConfig().Sources.Mathdro}
 }
 
 func (me *mathdroExporter) GetMetrics() (metrics, error) {
@@ -48,22 +45,15 @@ func (me *mathdroExporter) Health() []error {
 	return nil
 }
 
+//getRecoveredStats returns the recovered cases per country and province, the result is shared and must not be modified
 func (me *mathdroExporter) getRecoveredStats() (recoveredStats, error) {
-	client := http.Client{Timeout: 5 * time.Second}
-	response, err := client.Get(me.url + "recovered")
+	result, err := upstream.parse("mathdro", me.url+"recovered", func(jsonString []byte) (interface{}, error) {
+		recoveredStats := make(recoveredStats, 0)
+		err := json.Unmarshal(jsonString, &recoveredStats)
+		return recoveredStats, err
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
-	jsonString, err := ioutil.ReadAll(response.Body)
-	if err != nil {
-		return nil, err
-	}
-
-	recoveredStats := make(recoveredStats, 0)
-	err = json.Unmarshal(jsonString, &recoveredStats)
-	if err != nil {
-		return nil, err
-	}
-	return recoveredStats, nil
+	return result.(recoveredStats), nil
 }
@@ -1,13 +1,19 @@
 package main
 
 import (
+	"embed"
 	"encoding/csv"
-	"log"
+	"fmt"
+	"io"
 	"os"
 	"regexp"
+	"strconv"
 	"strings"
 )
 
+//go:embed metadata.csv bezirke.csv altersgruppen.csv grenzen.geojson
+var embeddedMetadata embed.FS
+
 type metadataProvider struct {
 	data map[string]metaData
 }
@@ -21,6 +27,8 @@ type metaData struct {
 	location   location
 	country    string
 	population uint64
+	//province is the Bundesland of a district, empty for countries
+	province string
 }
 
 func normalizeName(name string) string {
@@ -29,28 +37,93 @@ func normalizeName(name string) string {
 	return result
 }
 
+//newMetadataProvider returns the countries and provinces bundled with the binary
 func newMetadataProvider() *metadataProvider {
-	return newMetadataProviderWithFilename("metadata.csv")
+	return mustLoadEmbeddedMetadata("metadata.csv")
+}
+
+//newBezirkeMetadataProvider returns the districts bundled with the binary
+func newBezirkeMetadataProvider() *metadataProvider {
+	return mustLoadEmbeddedMetadata("bezirke.csv")
+}
+
+func mustLoadEmbeddedMetadata(name string) *metadataProvider {
+	result, err := loadMetadataProvider(name, "")
+	if err != nil {
+		panic(err)
+	}
+	return result
 }
 
-func newMetadataProviderWithFilename(filename string) *metadataProvider {
+//loadMetadataProvider reads the embedded file name and merges the rows of override on top of it
+func loadMetadataProvider(name string, override string) (*metadataProvider, error) {
+	file, err := embeddedMetadata.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	data, err := parseMetadata(name, file)
+	if err != nil {
+		return nil, err
+	}
+	if override != "" {
+		overrideProvider, err := newMetadataProviderWithFilename(override)
+		if err != nil {
+			return nil, err
+		}
+		for k, v := range overrideProvider.data {
+			data[k] = v
+		}
+	}
+	return &metadataProvider{data: data}, nil
+}
+
+//newMetadataProviderWithFilename reads metadata from a csv file on disk
+func newMetadataProviderWithFilename(filename string) (*metadataProvider, error) {
 	csvFile, err := os.Open(filename)
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, err
 	}
-	r := csv.NewReader(csvFile)
+	defer csvFile.Close()
+	data, err := parseMetadata(filename, csvFile)
+	if err != nil {
+		return nil, err
+	}
+	return &metadataProvider{data: data}, nil
+}
+
+func parseMetadata(filename string, reader io.Reader) (map[string]metaData, error) {
+	r := csv.NewReader(reader)
+	r.FieldsPerRecord = -1
 	records, err := r.ReadAll()
 	if err != nil {
-		log.Print(err)
-		return nil
+		return nil, fmt.Errorf("%s: %v", filename, err)
 	}
 	data := make(map[string]metaData, len(records))
 
-	for _, row := range records {
-		data[normalizeName(row[0])] = metaData{location{atof(row[2]), atof(row[3])}, row[0], atoi(row[1])}
+	for i, row := range records {
+		if len(row) != 4 && len(row) != 5 {
+			return nil, fmt.Errorf("%s:%d: expected name,population,latitude,longitude[,province]", filename, i+1)
+		}
+		population, err := strconv.ParseUint(row[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid population %q", filename, i+1, row[1])
+		}
+		lat, err := strconv.ParseFloat(row[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid latitude %q", filename, i+1, row[2])
+		}
+		long, err := strconv.ParseFloat(row[3], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: invalid longitude %q", filename, i+1, row[3])
+		}
+		province := ""
+		if len(row) == 5 {
+			province = row[4]
+		}
+		data[normalizeName(row[0])] = metaData{location{lat, long}, row[0], population, province}
 	}
-	return &metadataProvider{data: data}
+	return data, nil
 }
 
 func (l *metadataProvider) getMetadata(location string) *metaData {
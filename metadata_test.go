@@ -1,6 +1,8 @@
 package main
 
 import (
+	"io/ioutil"
+	"os"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -54,5 +56,44 @@ func TestUnknownLocation(t *testing.T) {
 	assert.Equal(t, uint64(0), p.getPopulation("xxxxx"))
 	assert.Nil(t, p.getMetadata("xxxxx"))
 
-	assert.Nil(t, newMetadataProviderWithFilename("someinvalidfile"))
+	result, err := newMetadataProviderWithFilename("someinvalidfile")
+	assert.Nil(t, result)
+	assert.NotNil(t, err)
+}
+
+func writeTempMetadata(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "metadata*.csv")
+	assert.Nil(t, err)
+	defer file.Close()
+	_, err = file.WriteString(content)
+	assert.Nil(t, err)
+	return file.Name()
+}
+
+func TestEmbeddedMetadata(t *testing.T) {
+	assert.NotNil(t, newMetadataProvider().getLocation("Austria"))
+	assert.NotNil(t, newBezirkeMetadataProvider().getLocation("Innsbruck-Land"))
+}
+
+func TestMetadataOverride(t *testing.T) {
+	filename := writeTempMetadata(t, "Wien,2000000,48.2,16.3\nAtlantis,42,1.000000,2.000000\n")
+	defer os.Remove(filename)
+
+	result, err := loadMetadataProvider("metadata.csv", filename)
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(2000000), result.getPopulation("Wien"))
+	assert.Equal(t, uint64(42), result.getPopulation("Atlantis"))
+	assert.Equal(t, uint64(391700), result.getPopulation("Vorarlberg"))
+}
+
+func TestMetadataOverrideErrors(t *testing.T) {
+	filename := writeTempMetadata(t, "Wien,many,48.2,16.3\n")
+	defer os.Remove(filename)
+
+	result, err := loadMetadataProvider("metadata.csv", filename)
+	assert.Nil(t, result)
+	assert.EqualError(t, err, filename+":1: invalid population \"many\"")
+
+	_, err = loadMetadataProvider("metadata.csv", "someinvalidfile")
+	assert.NotNil(t, err)
 }
@@ -0,0 +1,15 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+)
+
+//openapiSpec documents the json api, openapi_test.go checks it against the api types
+//go:embed openapi.json
+var openapiSpec []byte
+
+func handleOpenAPI(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Add("Content-type", "application/json; charset=utf-8")
+	w.Write(openapiSpec)
+}
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cinemast/covid19-at/client"
+	"github.com/cinemast/covid19-at/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+type openapiSchema struct {
+	Ref                  string                    `json:"$ref"`
+	Type                 string                    `json:"type"`
+	Format               string                    `json:"format"`
+	Description          string                    `json:"description"`
+	Properties           map[string]*openapiSchema `json:"properties"`
+	Items                *openapiSchema            `json:"items"`
+	AdditionalProperties *openapiSchema            `json:"additionalProperties"`
+}
+
+type openapiDocument struct {
+	Paths map[string]map[string]struct {
+		Responses map[string]struct {
+			Ref     string `json:"$ref"`
+			Content map[string]struct {
+				Schema *openapiSchema `json:"schema"`
+			} `json:"content"`
+		} `json:"responses"`
+	} `json:"paths"`
+	Components struct {
+		Schemas map[string]*openapiSchema `json:"schemas"`
+	} `json:"components"`
+}
+
+//apiSchemas maps the schemas of openapi.json to the types returned by the handlers
+var apiSchemas = map[string]interface{}{
+	"Location":       apiLocation{},
+	"BundeslandStat": bundeslandStat{},
+	"BezirkStat":     bezirkStat{},
+	"OverallStat":    overallStat{},
+	"AgeStat":        ageStat{},
+	"WorldStat":      worldStat{},
+	"ContinentStat":  continentStat{},
+	"UpdateEvent":    updateEvent{},
+	"RegionChange":   regionChange{},
+	"FieldChange":    fieldChange{},
+	"CapacityStat":   capacityStat{},
+	"TestStat":       testStat{},
+
+	"BundeslandForecast": bundeslandForecast{},
+	"ForecastSeries":     forecastSeries{},
+	"ForecastPoint":      forecastPoint{},
+
+	"SimulationParameters": simulation.Parameters{},
+	"Intervention":         simulation.Intervention{},
+	"SimulationDay":        simulation.Day{},
+
+	"LegacyLocation":       legacyLocation{},
+	"LegacyBundeslandStat": legacyBundeslandStat{},
+	"LegacyBezirkStat":     legacyBezirkStat{},
+	"LegacyOverallStat":    legacyOverallStat{},
+	"LegacyAgeStat":        legacyAgeStat{},
+}
+
+var clientSchemas = map[string]interface{}{
+	"Location":       client.Location{},
+	"BundeslandStat": client.BundeslandStat{},
+	"BezirkStat":     client.BezirkStat{},
+	"OverallStat":    client.OverallStat{},
+	"AgeStat":        client.AgeStat{},
+	"WorldStat":      client.WorldStat{},
+	"ContinentStat":  client.ContinentStat{},
+	"UpdateEvent":    client.UpdateEvent{},
+	"RegionChange":   client.RegionChange{},
+	"FieldChange":    client.FieldChange{},
+	"CapacityStat":   client.CapacityStat{},
+	"TestStat":       client.TestStat{},
+
+	"BundeslandForecast": client.BundeslandForecast{},
+	"ForecastSeries":     client.ForecastSeries{},
+	"ForecastPoint":      client.ForecastPoint{},
+
+	"SimulationParameters": client.SimulationParameters{},
+	"Intervention":         client.Intervention{},
+	"SimulationDay":        client.SimulationDay{},
+}
+
+func loadOpenAPI(t *testing.T) *openapiDocument {
+	document := &openapiDocument{}
+	assert.Nil(t, json.Unmarshal(openapiSpec, document))
+	return document
+}
+
+//schemaMatchesType checks if a property schema describes t, schemas maps $ref names to types
+func schemaMatchesType(s *openapiSchema, t reflect.Type, schemas map[string]interface{}) bool {
+	if s.Ref != "" {
+		v, ok := schemas[s.Ref[strings.LastIndex(s.Ref, "/")+1:]]
+		return ok && reflect.TypeOf(v) == t
+	}
+	switch s.Type {
+	case "string":
+		if s.Format == "date-time" {
+			return t == reflect.TypeOf(time.Time{})
+		}
+		return t.Kind() == reflect.String
+	case "number":
+		return t.Kind() == reflect.Float64
+	case "integer":
+		return t.Kind() == reflect.Uint64 || t.Kind() == reflect.Int64 || t.Kind() == reflect.Int
+	case "boolean":
+		return t.Kind() == reflect.Bool
+	case "array":
+		return t.Kind() == reflect.Slice && schemaMatchesType(s.Items, t.Elem(), schemas)
+	case "object":
+		return t.Kind() == reflect.Map && schemaMatchesType(s.AdditionalProperties, t.Elem(), schemas)
+	}
+	return false
+}
+
+func checkSchema(t *testing.T, name string, s *openapiSchema, schemas map[string]interface{}) {
+	typ := reflect.TypeOf(schemas[name])
+	fields := make([]string, 0)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldJSONName := fieldName(field)
+		fields = append(fields, fieldJSONName)
+		property, ok := s.Properties[fieldJSONName]
+		if assert.True(t, ok, "%s.%s is missing in openapi.json", name, fieldJSONName) {
+			assert.True(t, schemaMatchesType(property, field.Type, schemas), "%s.%s has type %s in openapi.json", name, fieldJSONName, property.Type)
+		}
+	}
+	properties := make([]string, 0)
+	for property := range s.Properties {
+		properties = append(properties, property)
+	}
+	sort.Strings(fields)
+	sort.Strings(properties)
+	assert.Equal(t, properties, fields, name)
+}
+
+func TestOpenAPISchemas(t *testing.T) {
+	document := loadOpenAPI(t)
+	assert.Equal(t, len(apiSchemas), len(document.Components.Schemas))
+	for name := range apiSchemas {
+		s, ok := document.Components.Schemas[name]
+		if assert.True(t, ok, name) {
+			checkSchema(t, name, s, apiSchemas)
+		}
+	}
+}
+
+func TestOpenAPIClientSchemas(t *testing.T) {
+	document := loadOpenAPI(t)
+	for name := range clientSchemas {
+		s, ok := document.Components.Schemas[name]
+		if assert.True(t, ok, name) {
+			checkSchema(t, name, s, clientSchemas)
+		}
+	}
+}
+
+func TestOpenAPIReferences(t *testing.T) {
+	document := loadOpenAPI(t)
+	for path, methods := range document.Paths {
+		for _, operation := range methods {
+			for _, response := range operation.Responses {
+				for _, content := range response.Content {
+					s := content.Schema
+					if s.Items != nil {
+						s = s.Items
+					}
+					if s.Ref != "" {
+						_, ok := document.Components.Schemas[s.Ref[strings.LastIndex(s.Ref, "/")+1:]]
+						assert.True(t, ok, "%s references unknown schema %s", path, s.Ref)
+					}
+				}
+			}
+		}
+	}
+}
+
+func TestClient(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+	defaultApi := a
+	a = mockApi
+	defer func() { a = defaultApi }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/bundesland", handleApiV1Bundesland)
+	mux.HandleFunc("/api/v1/bezirk", handleApiV1Bezirk)
+	mux.HandleFunc("/api/v1/total", handleApiV1Total)
+	mux.HandleFunc("/api/v1/age", handleApiV1Age)
+	mux.HandleFunc("/api/v1/world/", handleApiV1Country)
+	mux.HandleFunc("/api/openapi.json", handleOpenAPI)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	c := client.New(ts.URL)
+	bezirke, err := c.GetBezirk(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "Innsbruck-Land", bezirke[0].Name)
+	assert.Equal(t, 47.121792, bezirke[0].Location.Latitude)
+
+	bundesland, err := c.GetBundesland(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(bundesland))
+
+	total, err := c.GetTotal(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(1820), total.TotalInfected)
+	assert.Equal(t, uint64(1800), total.AgeDistributionInfection["15-24"])
+
+	ages, err := c.GetAge(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, "<5", ages[0].Group)
+
+	usa, err := c.GetCountry(context.Background(), "United States of America")
+	assert.Nil(t, err)
+	assert.Equal(t, uint64(102), usa.Recovered)
+	_, err = c.GetCountry(context.Background(), "Lilliput")
+	assert.NotNil(t, err)
+
+	response, err := ts.Client().Get(ts.URL + "/api/openapi.json")
+	assert.Nil(t, err)
+	assert.Equal(t, "application/json; charset=utf-8", response.Header.Get("Content-type"))
+}
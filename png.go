@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//glyphs is a 3x5 pixel font for the labels of rasterized charts, lower case letters are drawn upper case
+var glyphs = map[rune][5]string{
+	'0': {"###", "#.#", "#.#", "#.#", "###"},
+	'1': {".#.", "##.", ".#.", ".#.", "###"},
+	'2': {"###", "..#", "###", "#..", "###"},
+	'3': {"###", "..#", ".##", "..#", "###"},
+	'4': {"#.#", "#.#", "###", "..#", "..#"},
+	'5': {"###", "#..", "###", "..#", "###"},
+	'6': {"###", "#..", "###", "#.#", "###"},
+	'7': {"###", "..#", ".#.", ".#.", ".#."},
+	'8': {"###", "#.#", "###", "#.#", "###"},
+	'9': {"###", "#.#", "###", "..#", "###"},
+	'A': {".#.", "#.#", "###", "#.#", "#.#"},
+	'B': {"##.", "#.#", "##.", "#.#", "##."},
+	'C': {".##", "#..", "#..", "#..", ".##"},
+	'D': {"##.", "#.#", "#.#", "#.#", "##."},
+	'E': {"###", "#..", "##.", "#..", "###"},
+	'F': {"###", "#..", "##.", "#..", "#.."},
+	'G': {".##", "#..", "#.#", "#.#", ".##"},
+	'H': {"#.#", "#.#", "###", "#.#", "#.#"},
+	'I': {"###", ".#.", ".#.", ".#.", "###"},
+	'J': {"..#", "..#", "..#", "#.#", ".#."},
+	'K': {"#.#", "#.#", "##.", "#.#", "#.#"},
+	'L': {"#..", "#..", "#..", "#..", "###"},
+	'M': {"#.#", "###", "###", "#.#", "#.#"},
+	'N': {"##.", "#.#", "#.#", "#.#", "#.#"},
+	'O': {".#.", "#.#", "#.#", "#.#", ".#."},
+	'P': {"##.", "#.#", "##.", "#..", "#.."},
+	'Q': {".#.", "#.#", "#.#", "##.", ".##"},
+	'R': {"##.", "#.#", "##.", "#.#", "#.#"},
+	'S': {".##", "#..", ".#.", "..#", "##."},
+	'T': {"###", ".#.", ".#.", ".#.", ".#."},
+	'U': {"#.#", "#.#", "#.#", "#.#", "###"},
+	'V': {"#.#", "#.#", "#.#", "#.#", ".#."},
+	'W': {"#.#", "#.#", "###", "###", "#.#"},
+	'X': {"#.#", "#.#", ".#.", "#.#", "#.#"},
+	'Y': {"#.#", "#.#", ".#.", ".#.", ".#."},
+	'Z': {"###", "..#", ".#.", "#..", "###"},
+	'Ä': {"#.#", ".#.", "###", "#.#", "#.#"},
+	'Ö': {"#.#", ".#.", "#.#", "#.#", ".#."},
+	'Ü': {"#.#", "...", "#.#", "#.#", "###"},
+	'.': {"...", "...", "...", "...", ".#."},
+	',': {"...", "...", "...", ".#.", "#.."},
+	':': {"...", ".#.", "...", ".#.", "..."},
+	'-': {"...", "...", "###", "...", "..."},
+	'–': {"...", "...", "###", "...", "..."},
+	'+': {"...", ".#.", "###", ".#.", "..."},
+	'/': {"..#", "..#", ".#.", "#..", "#.."},
+	'%': {"#.#", "..#", ".#.", "#..", "#.#"},
+	'(': {".#.", "#..", "#..", "#..", ".#."},
+	')': {".#.", "..#", "..#", "..#", ".#."},
+}
+
+//parseColor reads #rrggbb, ok is false for none or other values
+func parseColor(s string) (color.RGBA, bool) {
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, false
+	}
+	v, err := strconv.ParseUint(s[1:], 16, 32)
+	if err != nil {
+		return color.RGBA{}, false
+	}
+	return color.RGBA{uint8(v >> 16), uint8(v >> 8), uint8(v), 255}, true
+}
+
+//fillRings fills the rings with the evenodd rule by scanning the pixel rows through their center
+func fillRings(img *image.RGBA, rings [][][2]float64, c color.RGBA) {
+	bounds := img.Bounds()
+	top, bottom := math.Inf(1), math.Inf(-1)
+	for _, ring := range rings {
+		for _, point := range ring {
+			top, bottom = math.Min(top, point[1]), math.Max(bottom, point[1])
+		}
+	}
+	if bottom < top {
+		return
+	}
+	first := int(math.Max(math.Floor(top), float64(bounds.Min.Y)))
+	last := int(math.Min(math.Ceil(bottom), float64(bounds.Max.Y)))
+	for y := first; y < last; y++ {
+		scan := float64(y) + 0.5
+		crossings := make([]float64, 0)
+		for _, ring := range rings {
+			for i := range ring {
+				a, b := ring[i], ring[(i+1)%len(ring)]
+				if (a[1] <= scan) == (b[1] <= scan) {
+					continue
+				}
+				crossings = append(crossings, a[0]+(scan-a[1])/(b[1]-a[1])*(b[0]-a[0]))
+			}
+		}
+		sort.Float64s(crossings)
+		for i := 0; i+1 < len(crossings); i += 2 {
+			from := int(math.Max(math.Round(crossings[i]), float64(bounds.Min.X)))
+			to := int(math.Min(math.Round(crossings[i+1]), float64(bounds.Max.X)))
+			for x := from; x < to; x++ {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+//strokeLine draws the open polyline as one quad per segment
+func strokeLine(img *image.RGBA, points [][2]float64, width float64, c color.RGBA) {
+	half := math.Max(width, 1) / 2
+	for i := 0; i+1 < len(points); i++ {
+		a, b := points[i], points[i+1]
+		length := math.Hypot(b[0]-a[0], b[1]-a[1])
+		if length == 0 {
+			continue
+		}
+		//normal of the segment scaled to half the line width
+		nx, ny := -(b[1]-a[1])/length*half, (b[0]-a[0])/length*half
+		fillRings(img, [][][2]float64{{{a[0] + nx, a[1] + ny}, {b[0] + nx, b[1] + ny}, {b[0] - nx, b[1] - ny}, {a[0] - nx, a[1] - ny}}}, c)
+	}
+}
+
+//drawText draws s with the glyphs scaled to about size pixels, the baseline is at y
+func drawText(img *image.RGBA, x float64, y float64, size float64, anchor string, s string, c color.RGBA) {
+	scale := math.Max(1, math.Round(size/6))
+	runes := []rune(strings.ToUpper(s))
+	width := float64(len(runes))*4*scale - scale
+	switch anchor {
+	case "middle":
+		x -= width / 2
+	case "end":
+		x -= width
+	}
+	top := y - 5*scale
+	for i, r := range runes {
+		glyph, ok := glyphs[r]
+		if !ok {
+			continue
+		}
+		left := x + float64(i)*4*scale
+		for row, line := range glyph {
+			for column, pixel := range line {
+				if pixel != '#' {
+					continue
+				}
+				for dy := 0.0; dy < scale; dy++ {
+					for dx := 0.0; dx < scale; dx++ {
+						img.SetRGBA(int(left+float64(column)*scale+dx), int(top+float64(row)*scale+dy), c)
+					}
+				}
+			}
+		}
+	}
+}
+
+//png rasterizes the canvas on a white background, titles are not drawn
+func (c *canvas) png() ([]byte, error) {
+	img := image.NewRGBA(image.Rect(0, 0, int(math.Ceil(c.width)), int(math.Ceil(c.height))))
+	white := color.RGBA{255, 255, 255, 255}
+	fillRings(img, [][][2]float64{{{0, 0}, {c.width, 0}, {c.width, c.height}, {0, c.height}}}, white)
+	for _, s := range c.shapes {
+		fill, hasFill := parseColor(s.fill)
+		stroke, hasStroke := parseColor(s.stroke)
+		switch s.kind {
+		case "path", "rect":
+			if hasFill {
+				fillRings(img, s.rings, fill)
+			}
+			if hasStroke {
+				for _, ring := range s.rings {
+					strokeLine(img, append(append([][2]float64{}, ring...), ring[0]), s.width, stroke)
+				}
+			}
+		case "polyline":
+			if hasStroke {
+				strokeLine(img, s.rings[0], s.width, stroke)
+			}
+		case "text":
+			drawText(img, s.rings[0][0][0], s.rings[0][0][1], s.size, s.anchor, s.text, color.RGBA{34, 34, 34, 255})
+		}
+	}
+	var b bytes.Buffer
+	err := png.Encode(&b, img)
+	return b.Bytes(), err
+}
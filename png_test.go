@@ -0,0 +1,37 @@
+package main
+
+import (
+	"bytes"
+	"image/color"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCanvasPng(t *testing.T) {
+	c := newCanvas("test", 40, 30)
+	c.path([][][2]float64{{{0, 0}, {20, 0}, {20, 20}, {0, 20}}, {{5, 5}, {15, 5}, {15, 15}, {5, 15}}}, "#ff0000", "none", "")
+	c.polyline([][2]float64{{25, 5}, {35, 5}}, "#0000ff", 2)
+	c.text(25, 28, 6, "start", "1")
+
+	b, err := c.png()
+	assert.Nil(t, err)
+	img, err := png.Decode(bytes.NewReader(b))
+	assert.Nil(t, err)
+	assert.Equal(t, 40, img.Bounds().Dx())
+	assert.Equal(t, 30, img.Bounds().Dy())
+
+	rgba := func(x int, y int) color.RGBA { return color.RGBAModel.Convert(img.At(x, y)).(color.RGBA) }
+	assert.Equal(t, color.RGBA{255, 0, 0, 255}, rgba(2, 2))
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, rgba(10, 10), "the hole is not filled")
+	assert.Equal(t, color.RGBA{0, 0, 255, 255}, rgba(30, 5))
+	assert.Equal(t, color.RGBA{34, 34, 34, 255}, rgba(26, 23), "the stem of the 1")
+	assert.Equal(t, color.RGBA{255, 255, 255, 255}, rgba(38, 28))
+
+	color, ok := parseColor("#E02F44")
+	assert.True(t, ok)
+	assert.Equal(t, uint8(0xE0), color.R)
+	_, ok = parseColor("none")
+	assert.False(t, ok)
+}
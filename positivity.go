@@ -0,0 +1,98 @@
+package main
+
+import (
+	"math"
+	"net/http"
+)
+
+type testStat struct {
+	Name string `json:"name"`
+	//Tests are the tests performed since the start of the pandemic
+	Tests uint64 `json:"tests"`
+	//NewTests and NewCases are the increases per day since the previous daily snapshot, 0 if unknown.
+	//After missed days the increase is divided by the days since that snapshot.
+	NewTests int64 `json:"new_tests"`
+	NewCases int64 `json:"new_cases"`
+	//Days are the days since the previous daily snapshot with tests, 0 if there is none
+	Days int64 `json:"days"`
+	//PositivityRate is the share of the new tests that were positive, new cases / new tests, 0 without new tests
+	PositivityRate float64 `json:"positivity_rate"`
+}
+
+//newTestStats compares the tests and infections of Austria and the provinces in the last two snapshots of the history.
+//Snapshots without tests are skipped, so the first day with tests is not counted as new tests.
+//The positivity rate is computed before the increases are divided by the days.
+func newTestStats(history []*snapshot) []testStat {
+	result := make([]testStat, 0, len(austriaRegions))
+	for _, region := range austriaRegions {
+		province := region
+		if region == austriaRegions[0] {
+			province = ""
+		}
+		infected, tests, snapshots := make([]uint64, 0, 2), make([]uint64, 0, 2), make([]*snapshot, 0, 2)
+		for _, s := range history {
+			t, ok := chartMetrics["tests"].value(s, province)
+			if !ok || t == 0 {
+				continue
+			}
+			i, _ := chartMetrics["infected"].value(s, province)
+			infected, tests, snapshots = append(infected, uint64(i)), append(tests, uint64(t)), append(snapshots, s)
+		}
+		if len(tests) == 0 {
+			continue
+		}
+		stat := testStat{Name: region, Tests: tests[len(tests)-1]}
+		if len(tests) > 1 {
+			stat.NewTests = difference(tests[len(tests)-1], tests[len(tests)-2])
+			stat.NewCases = difference(infected[len(infected)-1], infected[len(infected)-2])
+			stat.Days = int64(calendarDays(snapshots[len(snapshots)-2].Time, snapshots[len(snapshots)-1].Time))
+		}
+		if stat.NewTests > 0 && stat.NewCases >= 0 {
+			stat.PositivityRate = float64(stat.NewCases) / float64(stat.NewTests)
+		}
+		if stat.Days > 1 {
+			stat.NewTests = int64(math.Round(float64(stat.NewTests) / float64(stat.Days)))
+			stat.NewCases = int64(math.Round(float64(stat.NewCases) / float64(stat.Days)))
+		}
+		result = append(result, stat)
+	}
+	return result
+}
+
+//testExporter exposes the daily tests and the positivity rate of the provinces and of Austria as province="Austria"
+type testExporter struct {
+	c *collector
+}
+
+func newTestExporter(c *collector) *testExporter {
+	return &testExporter{c: c}
+}
+
+func (e *testExporter) GetMetrics() (metrics, error) {
+	result := make(metrics, 0)
+	for _, s := range newTestStats(e.c.dailyHistory()) {
+		if s.NewTests <= 0 {
+			continue
+		}
+		tags := &map[string]string{"province": s.Name}
+		result = append(result,
+			metric{Name: "cov19_tests_daily", Tags: tags, Value: float64(s.NewTests)},
+			metric{Name: "cov19_positivity_rate", Tags: tags, Value: s.PositivityRate})
+	}
+	return result, nil
+}
+
+func (e *testExporter) Health() []error {
+	return nil
+}
+
+//handleApiV1Tests serves the tests and positivity rate of Austria and the provinces
+func (e *testExporter) handleApiV1Tests(w http.ResponseWriter, r *http.Request) {
+	history := e.c.dailyHistory()
+	if len(history) == 0 {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	writeListResponse(w, r, testStat{}, func() (interface{}, error) { return newTestStats(history), nil })
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTestStats(t *testing.T) {
+	assert.Empty(t, newTestStats(testHistory(bundeslandStat{Hospitalized: 800, IntensiveCare: 200})))
+
+	stats := newTestStats(testHistory(bundeslandStat{Infected: 1400, Tests: 8000}, bundeslandStat{Infected: 1500, Tests: 9000}))
+	assert.Equal(t, 2, len(stats), "Burgenland has no tests")
+	assert.Equal(t, testStat{Name: "Austria", Tests: 9000, NewTests: 1000, NewCases: 100, Days: 1, PositivityRate: 0.1}, stats[0])
+	assert.Equal(t, testStat{Name: "Wien", Tests: 9000, NewTests: 1000, NewCases: 100, Days: 1, PositivityRate: 0.1}, stats[1])
+
+	stats = newTestStats(testHistory(bundeslandStat{Infected: 1400}, bundeslandStat{Infected: 1500, Tests: 9000}))
+	assert.Equal(t, testStat{Name: "Wien", Tests: 9000}, stats[1], "no new tests on the first day with tests")
+
+	stats = newTestStats(testHistory(bundeslandStat{Infected: 1400, Tests: 9000}, bundeslandStat{Infected: 1500, Tests: 9000}))
+	assert.Equal(t, 0.0, stats[1].PositivityRate)
+
+	//the increases after a missed day are divided by the elapsed days
+	missed := testHistory(bundeslandStat{Infected: 1400, Tests: 8000}, bundeslandStat{}, bundeslandStat{Infected: 1600, Tests: 10000})
+	stats = newTestStats(missed)
+	assert.Equal(t, testStat{Name: "Wien", Tests: 10000, NewTests: 1000, NewCases: 100, Days: 2, PositivityRate: 0.1}, stats[1])
+}
+
+func TestTestExporter(t *testing.T) {
+	e := newTestExporter(newCollector(nil))
+	metrics, _ := e.GetMetrics()
+	assert.Empty(t, metrics)
+
+	history := testHistory(bundeslandStat{Infected: 1400, Tests: 8000}, bundeslandStat{Infected: 1450, Tests: 8500})
+	e.c.history, e.c.current = history[:1], history[1]
+	metrics, _ = e.GetMetrics()
+	assert.Nil(t, metrics.checkMetric("cov19_tests_daily", "province=Wien", func(x float64) bool { return x == 500 }))
+	assert.Nil(t, metrics.checkMetric("cov19_positivity_rate", "province=Wien", func(x float64) bool { return x == 0.1 }))
+	assert.Nil(t, metrics.checkMetric("cov19_tests_daily", "province=Austria", func(x float64) bool { return x == 500 }))
+	assert.Nil(t, metrics.findMetric("cov19_positivity_rate", "province=Burgenland"))
+}
+
+func TestTestHandler(t *testing.T) {
+	e := newTestExporter(newCollector(nil))
+	ts := httptest.NewServer(http.HandlerFunc(e.handleApiV1Tests))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	history := testHistory(bundeslandStat{Infected: 1400, Tests: 8000}, bundeslandStat{Infected: 1500, Tests: 8400})
+	e.c.history, e.c.current = history[:1], history[1]
+	response, err = ts.Client().Get(ts.URL + "?sort=-name&top=1")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	stats := make([]testStat, 0)
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&stats))
+	assert.Equal(t, 1, len(stats))
+	assert.Equal(t, "Wien", stats[0].Name)
+	assert.Equal(t, 0.25, stats[0].PositivityRate)
+}
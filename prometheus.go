@@ -18,6 +18,7 @@ type CovidStat struct {
 	location string
 	infected uint64
 	deaths   uint64
+	tests    uint64
 }
 
 type Exporter interface {
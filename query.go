@@ -0,0 +1,275 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//reservedParameters are query parameters that do not refer to a field of the listed type
+var reservedParameters = map[string]bool{
+	"format": true,
+	"sort":   true,
+	"top":    true,
+	"fields": true,
+}
+
+//listQuery filters, sorts, limits and projects the rows of a list endpoint.
+//Fields are referenced by their json name:
+//	?province=Tirol&province=Wien   rows whose string field equals one of the values, case insensitive
+//	?min_infected=100&max_dead=10   bounds on numeric fields, inclusive
+//	?sort=-infected_per_100k        sort by a field, descending with a leading -
+//	?top=10                         only the first rows after sorting
+//	?fields=name,infected           only the given fields
+type listQuery struct {
+	elem       reflect.Type
+	equal      map[int][]string
+	min        map[int]float64
+	max        map[int]float64
+	sortField  int
+	descending bool
+	top        int
+	fields     []int
+}
+
+//sparseField is a single field of a sparseRow
+type sparseField struct {
+	name  string
+	value interface{}
+}
+
+//sparseRow is a row reduced to the fields selected with ?fields=, it keeps their order in json and csv
+type sparseRow []sparseField
+
+func (r sparseRow) MarshalJSON() ([]byte, error) {
+	buffer := bytes.Buffer{}
+	buffer.WriteByte('{')
+	for i, f := range r {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		name, _ := json.Marshal(f.name)
+		buffer.Write(name)
+		buffer.WriteByte(':')
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+//fieldIndex returns the index of the field of t with the given json name
+func fieldIndex(t reflect.Type, name string) (int, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath == "" && fieldName(field) == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+func isNumeric(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func numericValue(v reflect.Value) float64 {
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint())
+	}
+	return float64(v.Int())
+}
+
+//parseListQuery reads the query parameters for a list of elem, fields unknown to elem are an error
+func parseListQuery(values url.Values, elem interface{}) (*listQuery, error) {
+	q := &listQuery{
+		elem:      reflect.TypeOf(elem),
+		equal:     make(map[int][]string),
+		min:       make(map[int]float64),
+		max:       make(map[int]float64),
+		sortField: -1,
+	}
+	for key, v := range values {
+		if reservedParameters[key] {
+			continue
+		}
+		if err := q.parseFilter(key, v); err != nil {
+			return nil, err
+		}
+	}
+
+	if s := values.Get("sort"); s != "" {
+		q.descending = strings.HasPrefix(s, "-")
+		s = strings.TrimPrefix(s, "-")
+		i, ok := fieldIndex(q.elem, s)
+		if !ok || (!isNumeric(q.elem.Field(i).Type) && q.elem.Field(i).Type.Kind() != reflect.String) {
+			return nil, fmt.Errorf("Cannot sort by %q", s)
+		}
+		q.sortField = i
+	}
+
+	if top := values.Get("top"); top != "" {
+		n, err := strconv.Atoi(top)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("Invalid top %q, needs to be a positive number", top)
+		}
+		q.top = n
+	}
+
+	if fields := values.Get("fields"); fields != "" {
+		for _, name := range strings.Split(fields, ",") {
+			i, ok := fieldIndex(q.elem, strings.TrimSpace(name))
+			if !ok {
+				return nil, fmt.Errorf("Unknown field %q", name)
+			}
+			for _, selected := range q.fields {
+				if selected == i {
+					return nil, fmt.Errorf("Duplicate field %q", name)
+				}
+			}
+			q.fields = append(q.fields, i)
+		}
+	}
+	return q, nil
+}
+
+func (q *listQuery) parseFilter(key string, values []string) error {
+	for _, bound := range []struct {
+		prefix string
+		target map[int]float64
+	}{{"min_", q.min}, {"max_", q.max}} {
+		if !strings.HasPrefix(key, bound.prefix) {
+			continue
+		}
+		if i, ok := fieldIndex(q.elem, strings.TrimPrefix(key, bound.prefix)); ok && isNumeric(q.elem.Field(i).Type) {
+			value, err := strconv.ParseFloat(values[0], 64)
+			if err != nil {
+				return fmt.Errorf("Invalid number %q for %s", values[0], key)
+			}
+			bound.target[i] = value
+			return nil
+		}
+	}
+	i, ok := fieldIndex(q.elem, key)
+	if !ok || q.elem.Field(i).Type.Kind() != reflect.String {
+		return fmt.Errorf("Unknown query parameter %q", key)
+	}
+	q.equal[i] = values
+	return nil
+}
+
+func (q *listQuery) matches(v reflect.Value) bool {
+	for i, values := range q.equal {
+		found := false
+		for _, value := range values {
+			if strings.EqualFold(v.Field(i).String(), value) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for i, min := range q.min {
+		if numericValue(v.Field(i)) < min {
+			return false
+		}
+	}
+	for i, max := range q.max {
+		if numericValue(v.Field(i)) > max {
+			return false
+		}
+	}
+	return true
+}
+
+func (q *listQuery) less(a reflect.Value, b reflect.Value) bool {
+	a, b = a.Field(q.sortField), b.Field(q.sortField)
+	if q.descending {
+		a, b = b, a
+	}
+	if a.Kind() == reflect.String {
+		return a.String() < b.String()
+	}
+	return numericValue(a) < numericValue(b)
+}
+
+//apply returns the rows of list that match the query, list needs to be a slice of the elem type
+func (q *listQuery) apply(list interface{}) interface{} {
+	value := reflect.ValueOf(list)
+	selected := make([]reflect.Value, 0, value.Len())
+	for i := 0; i < value.Len(); i++ {
+		if q.matches(value.Index(i)) {
+			selected = append(selected, value.Index(i))
+		}
+	}
+	if q.sortField >= 0 {
+		sort.SliceStable(selected, func(i, j int) bool { return q.less(selected[i], selected[j]) })
+	}
+	if q.top > 0 && len(selected) > q.top {
+		selected = selected[:q.top]
+	}
+
+	if len(q.fields) > 0 && len(selected) == 0 {
+		//an empty struct slice of the selected fields keeps the csv header
+		fields := make([]reflect.StructField, 0, len(q.fields))
+		for _, i := range q.fields {
+			field := q.elem.Field(i)
+			field.Anonymous, field.Index, field.Offset = false, nil, 0
+			fields = append(fields, field)
+		}
+		return reflect.MakeSlice(reflect.SliceOf(reflect.StructOf(fields)), 0, 0).Interface()
+	}
+	if len(q.fields) > 0 {
+		result := make([]sparseRow, 0, len(selected))
+		for _, v := range selected {
+			r := make(sparseRow, 0, len(q.fields))
+			for _, i := range q.fields {
+				r = append(r, sparseField{fieldName(q.elem.Field(i)), v.Field(i).Interface()})
+			}
+			result = append(result, r)
+		}
+		return result
+	}
+	result := reflect.MakeSlice(reflect.SliceOf(q.elem), 0, len(selected))
+	for _, v := range selected {
+		result = reflect.Append(result, v)
+	}
+	return result.Interface()
+}
+
+//writeListResponse writes the result of f like writeResponse after applying the query parameters of r
+func writeListResponse(w http.ResponseWriter, r *http.Request, elem interface{}, f func() (interface{}, error)) {
+	q, err := parseListQuery(r.URL.Query(), elem)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	writeResponse(w, r, func() (interface{}, error) {
+		result, err := f()
+		if err != nil {
+			return nil, err
+		}
+		return q.apply(result), nil
+	})
+}
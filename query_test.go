@@ -0,0 +1,106 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var queryTestStats = []bezirkStat{
+	{Name: "Innsbruck-Land", Province: "Tirol", Population: 179318, Infected: 150, InfectedPer100k: 83.6},
+	{Name: "Landeck", Province: "Tirol", Population: 44155, Infected: 300, InfectedPer100k: 679.4},
+	{Name: "Wien(Stadt)", Province: "Wien", Population: 1897491, Infected: 1500, InfectedPer100k: 79.1},
+	{Name: "Graz(Stadt)", Province: "Steiermark", Population: 289440, Infected: 90, InfectedPer100k: 31.1},
+}
+
+func queryNames(t *testing.T, query string) []string {
+	values, _ := url.ParseQuery(query)
+	q, err := parseListQuery(values, bezirkStat{})
+	assert.Nil(t, err, query)
+	names := make([]string, 0)
+	for _, s := range q.apply(queryTestStats).([]bezirkStat) {
+		names = append(names, s.Name)
+	}
+	return names
+}
+
+func TestListQuery(t *testing.T) {
+	assert.Equal(t, []string{"Innsbruck-Land", "Landeck", "Wien(Stadt)", "Graz(Stadt)"}, queryNames(t, ""))
+	assert.Equal(t, []string{"Innsbruck-Land", "Landeck"}, queryNames(t, "province=tirol"))
+	assert.Equal(t, []string{"Landeck", "Wien(Stadt)"}, queryNames(t, "province=Tirol&province=Wien&min_infected=200"))
+	assert.Equal(t, []string{"Innsbruck-Land", "Graz(Stadt)"}, queryNames(t, "min_population=100000&max_population=300000"))
+	assert.Equal(t, []string{"Landeck", "Innsbruck-Land", "Wien(Stadt)"}, queryNames(t, "sort=-infected_per_100k&top=3"))
+	assert.Equal(t, []string{"Graz(Stadt)", "Innsbruck-Land", "Landeck", "Wien(Stadt)"}, queryNames(t, "sort=name&format=csv"))
+	assert.Equal(t, []string{}, queryNames(t, "province=Vorarlberg"))
+
+	for query, message := range map[string]string{
+		"sort=location":     `Cannot sort by "location"`,
+		"top=0":             `Invalid top "0", needs to be a positive number`,
+		"min_infected=many": `Invalid number "many" for min_infected`,
+		"min_name=a":        `Unknown query parameter "min_name"`,
+		"infected=3":        `Unknown query parameter "infected"`,
+		"fields=name,xyz":   `Unknown field "xyz"`,
+		"fields=name,name":  `Duplicate field "name"`,
+	} {
+		values, _ := url.ParseQuery(query)
+		_, err := parseListQuery(values, bezirkStat{})
+		assert.EqualError(t, err, message, query)
+	}
+}
+
+func TestListQueryFields(t *testing.T) {
+	values, _ := url.ParseQuery("fields=infected,name&top=2")
+	q, err := parseListQuery(values, bezirkStat{})
+	assert.Nil(t, err)
+	result := q.apply(queryTestStats)
+
+	encoded, err := json.Marshal(result)
+	assert.Nil(t, err)
+	assert.Equal(t, `[{"infected":150,"name":"Innsbruck-Land"},{"infected":300,"name":"Landeck"}]`, string(encoded))
+
+	buffer := bytes.Buffer{}
+	assert.Nil(t, writeCsv(&buffer, result))
+	assert.Equal(t, "infected,name\n150,Innsbruck-Land\n300,Landeck\n", buffer.String())
+
+	values, _ = url.ParseQuery("fields=infected,name&min_infected=1000000")
+	q, _ = parseListQuery(values, bezirkStat{})
+	result = q.apply(queryTestStats)
+	encoded, _ = json.Marshal(result)
+	assert.Equal(t, "[]", string(encoded))
+	buffer.Reset()
+	assert.Nil(t, writeCsv(&buffer, result))
+	assert.Equal(t, "infected,name\n", buffer.String(), "the header of an empty selection")
+}
+
+func TestListResponse(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		writeListResponse(w, r, bezirkStat{}, func() (interface{}, error) { return queryTestStats, nil })
+	}))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL + "?province=Steiermark&fields=name&format=ndjson")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "{\"name\":\"Graz(Stadt)\"}\n", string(body))
+
+	response, err = ts.Client().Get(ts.URL + "?bundesland=Tirol")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+	body, _ = ioutil.ReadAll(response.Body)
+	assert.Equal(t, `Unknown query parameter "bundesland"`, string(body))
+
+	//duplicate fields can not be built into the empty selection
+	for _, format := range []string{"json", "csv"} {
+		response, err = ts.Client().Get(ts.URL + "?province=Vorarlberg&fields=name,name&format=" + format)
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, response.StatusCode, format)
+		body, _ = ioutil.ReadAll(response.Body)
+		assert.Equal(t, `Duplicate field "name"`, string(body), format)
+	}
+}
@@ -0,0 +1,80 @@
+package main
+
+//metricInfo describes a metric exposed on /metrics
+type metricInfo struct {
+	Name string
+	//Type is gauge or counter
+	Type string
+	Help string
+	//Labels are the names of the labels, metrics with latitude and longitude can be shown on a map
+	Labels []string
+	//Region is the label of the region for cumulative counts of cases, daily and weekly increases are recorded per region
+	Region string
+}
+
+//metricRegistry lists all metrics of the exporters
+var metricRegistry = []metricInfo{
+	{Name: "cov19_confirmed", Type: "gauge", Help: "Confirmed infections in Austria"},
+	{Name: "cov19_tests", Type: "gauge", Help: "Tests performed in Austria"},
+	{Name: "cov19_tests_detail", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Tests performed per province", Region: "province"},
+	{Name: "cov19_tests_daily", Type: "gauge", Labels: []string{"province"}, Help: "Tests performed since the previous day per province, Austria is province=\"Austria\""},
+	{Name: "cov19_positivity_rate", Type: "gauge", Labels: []string{"province"}, Help: "Share of the tests since the previous day that were positive per province, Austria is province=\"Austria\""},
+	{Name: "cov19_hospitalized", Type: "gauge", Help: "Patients in hospital in Austria"},
+	{Name: "cov19_intensive_care", Type: "gauge", Help: "Patients in intensive care in Austria"},
+	{Name: "cov19_detail", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Confirmed infections per province", Region: "province"},
+	{Name: "cov19_detail_infected_per_100k", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Confirmed infections per 100.000 inhabitants per province", Region: "province"},
+	{Name: "cov19_detail_infection_rate", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Share of the population infected per province"},
+	{Name: "cov19_detail_dead", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Deaths per province", Region: "province"},
+	{Name: "cov19_detail_fatality_rate", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Share of the infected that died per province"},
+	{Name: "cov19_hospitalized_detail", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Patients in hospital per province"},
+	{Name: "cov19_intensive_care_detail", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Patients in intensive care per province"},
+	{Name: "cov19_bezirk_infected", Type: "gauge", Labels: []string{"country", "bezirk", "latitude", "longitude"}, Help: "Confirmed infections per district", Region: "bezirk"},
+	{Name: "cov19_bezirk_infected_100k", Type: "gauge", Labels: []string{"country", "bezirk", "latitude", "longitude"}, Help: "Confirmed infections per 100.000 inhabitants per district", Region: "bezirk"},
+	{Name: "cov19_age_distribution", Type: "gauge", Labels: []string{"country", "group"}, Help: "Confirmed infections per age group"},
+	{Name: "cov19_age_infected_per_100k", Type: "gauge", Labels: []string{"country", "group"}, Help: "Confirmed infections per 100.000 inhabitants per age group"},
+	{Name: "cov19_age_population", Type: "gauge", Labels: []string{"country", "province", "group"}, Help: "Inhabitants per age group"},
+	{Name: "cov19_sex_distribution", Type: "gauge", Labels: []string{"country", "sex"}, Help: "Confirmed infections per sex"},
+	{Name: "cov19_world_infected", Type: "gauge", Labels: []string{"continent", "country", "latitude", "longitude"}, Help: "Confirmed infections per country", Region: "country"},
+	{Name: "cov19_world_infected_per_100k", Type: "gauge", Labels: []string{"continent", "country", "latitude", "longitude"}, Help: "Confirmed infections per 100.000 inhabitants per country", Region: "country"},
+	{Name: "cov19_world_infection_rate", Type: "gauge", Labels: []string{"continent", "country", "latitude", "longitude"}, Help: "Share of the population infected per country"},
+	{Name: "cov19_world_death", Type: "gauge", Labels: []string{"continent", "country", "latitude", "longitude"}, Help: "Deaths per country", Region: "country"},
+	{Name: "cov19_world_fatality_rate", Type: "gauge", Labels: []string{"continent", "country", "latitude", "longitude"}, Help: "Share of the infected that died per country"},
+	{Name: "cov19_world_recovered", Type: "gauge", Labels: []string{"country", "province", "latitude", "longitude"}, Help: "Recovered per country", Region: "country"},
+	{Name: "cov19_config_reload_success", Type: "gauge", Help: "1 if the last config reload succeeded"},
+	{Name: "cov19_config_last_reload_timestamp_seconds", Type: "gauge", Help: "Time of the last config reload"},
+	{Name: "cov19_config_file_info", Type: "gauge", Labels: []string{"file", "sha256"}, Help: "sha256 of the loaded config and metadata files"},
+	{Name: "cov19_upstream_last_changed_timestamp_seconds", Type: "gauge", Labels: []string{"source", "url"}, Help: "Time an upstream url last published new content"},
+	{Name: "cov19_upstream_requests_total", Type: "counter", Labels: []string{"host", "status"}, Help: "Requests to the upstream hosts by status"},
+	{Name: "cov19_upstream_retries_total", Type: "counter", Labels: []string{"host"}, Help: "Retried requests to the upstream hosts"},
+	{Name: "cov19_upstream_request_duration_seconds_sum", Type: "counter", Labels: []string{"host"}, Help: "Total duration of the requests to the upstream hosts"},
+	{Name: "cov19_upstream_request_duration_seconds_count", Type: "counter", Labels: []string{"host"}, Help: "Number of timed requests to the upstream hosts"},
+	{Name: "cov19_webhook_deliveries_total", Type: "counter", Labels: []string{"url", "result"}, Help: "Logged webhook deliveries by result"},
+	{Name: "cov19_alert_active", Type: "gauge", Labels: []string{"rule", "region", "state"}, Help: "Pending and firing alerts"},
+	{Name: "cov19_beds", Type: "gauge", Labels: []string{"province"}, Help: "Normal care beds available for COVID-19 patients"},
+	{Name: "cov19_intensive_care_beds", Type: "gauge", Labels: []string{"province"}, Help: "Intensive care beds available for COVID-19 patients"},
+	{Name: "cov19_occupancy_ratio", Type: "gauge", Labels: []string{"province"}, Help: "Share of the normal care beds occupied by COVID-19 patients"},
+	{Name: "cov19_intensive_care_occupancy_ratio", Type: "gauge", Labels: []string{"province"}, Help: "Share of the intensive care beds occupied by COVID-19 patients"},
+	{Name: "cov19_remaining_beds", Type: "gauge", Labels: []string{"province"}, Help: "Free normal care beds, negative if there are more patients than beds"},
+	{Name: "cov19_intensive_care_remaining_beds", Type: "gauge", Labels: []string{"province"}, Help: "Free intensive care beds, negative if there are more patients than beds"},
+	{Name: "cov19_days_until_full", Type: "gauge", Labels: []string{"province"}, Help: "Days until the normal care beds are occupied at the growth of the last 7 days, only while the occupancy grows"},
+	{Name: "cov19_intensive_care_days_until_full", Type: "gauge", Labels: []string{"province"}, Help: "Days until the intensive care beds are occupied at the growth of the last 7 days, only while the occupancy grows"},
+	{Name: "cov19_forecast", Type: "gauge", Labels: []string{"province", "series", "model", "days"}, Help: "Forecasted value of a series in days"},
+	{Name: "cov19_forecast_lower", Type: "gauge", Labels: []string{"province", "series", "model", "days"}, Help: "Lower bound of the 95% prediction interval of the forecast"},
+	{Name: "cov19_forecast_upper", Type: "gauge", Labels: []string{"province", "series", "model", "days"}, Help: "Upper bound of the 95% prediction interval of the forecast"},
+	{Name: "cov19_scrape_error", Type: "gauge", Labels: []string{"exporter"}, Help: "1 if an exporter failed to read or parse its source during the scrape"},
+}
+
+//findMetricInfo returns the registered metric, nil if it is unknown
+func findMetricInfo(name string) *metricInfo {
+	for i := range metricRegistry {
+		if metricRegistry[i].Name == name {
+			return &metricRegistry[i]
+		}
+	}
+	return nil
+}
+
+//hasLabel is true if the metric is emitted with the label
+func (m *metricInfo) hasLabel(label string) bool {
+	return contains(m.Labels, label)
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+//configLock guards the exporters against a reload while they are in use
+var configLock sync.RWMutex
+
+type reloader struct {
+	filename   string
+	config     *config
+	success    bool
+	lastError  error
+	lastReload time.Time
+	hashes     map[string]string
+}
+
+type reloadStatus struct {
+	Success    bool
+	Error      string
+	LastReload time.Time
+	Files      map[string]string
+}
+
+func newReloader(filename string) *reloader {
+	return &reloader{filename: filename, config: defaultConfig(), hashes: make(map[string]string)}
+}
+
+func hashBytes(bytes []byte) string {
+	sum := sha256.Sum256(bytes)
+	return hex.EncodeToString(sum[:])
+}
+
+func hashFile(filename string) (string, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(bytes), nil
+}
+
+func hashEmbedded(name string) (string, error) {
+	bytes, err := embeddedMetadata.ReadFile(name)
+	if err != nil {
+		return "", err
+	}
+	return hashBytes(bytes), nil
+}
+
+//fileHashes returns the sha256 of every file the configuration c is made of
+func (r *reloader) fileHashes(c *config) (map[string]string, error) {
+	result := make(map[string]string)
+	for _, name := range []string{"metadata.csv", "bezirke.csv", "altersgruppen.csv", "grenzen.geojson"} {
+		hash, err := hashEmbedded(name)
+		if err != nil {
+			return nil, err
+		}
+		result["embedded/"+name] = hash
+	}
+	for _, filename := range []string{r.filename, c.Metadata, c.Bezirke, c.AgeGroups, c.Capacities, c.Boundaries} {
+		if filename == "" {
+			continue
+		}
+		hash, err := hashFile(filename)
+		if err != nil {
+			return nil, err
+		}
+		result[filename] = hash
+	}
+	return result, nil
+}
+
+var austriaRegions = []string{"Austria", "Burgenland", "Kärnten", "Niederösterreich", "Oberösterreich", "Salzburg", "Steiermark", "Tirol", "Vorarlberg", "Wien"}
+
+func validateMetadata(metadata *metadataProvider) error {
+	for _, province := range austriaRegions {
+		if metadata.getPopulation(province) == 0 {
+			return fmt.Errorf("Missing population for %s", province)
+		}
+	}
+	return nil
+}
+
+//validateAgePopulation requires every age group for Austria, provinces are optional but need all groups once configured
+func validateAgePopulation(ages *agePopulationProvider) error {
+	for i, province := range austriaRegions {
+		if _, ok := ages.data[normalizeName(province)]; !ok && i > 0 {
+			continue
+		}
+		for _, group := range ages.groups {
+			if ages.getPopulation(province, group) == 0 {
+				return fmt.Errorf("Missing population for age group %s in %s", group, province)
+			}
+		}
+	}
+	return nil
+}
+
+//loadedConfig is a validated configuration that has not been applied yet
+type loadedConfig struct {
+	config     *config
+	metadata   *metadataProvider
+	bezirke    *metadataProvider
+	ages       *agePopulationProvider
+	capacities *capacityProvider
+	boundaries *boundaryProvider
+	hashes     map[string]string
+}
+
+//reload reads the config file and metadata and swaps them in if all of them are valid
+func (r *reloader) reload() error {
+	l, err := r.load()
+
+	configLock.Lock()
+	defer configLock.Unlock()
+	r.lastReload = time.Now()
+	r.success = err == nil
+	r.lastError = err
+	if err != nil {
+		return err
+	}
+
+	c := l.config
+	mp.data = l.metadata.data
+	he.mp.data = l.bezirke.data
+	*he.ages = *l.ages
+	cp.data = l.capacities.data
+	a.boundaries.data = l.boundaries.data
+	a.boundaries.names = l.boundaries.names
+	he.url = c.Sources.HealthMinistry
+	se.url = c.Sources.SocialMinistry
+	se.hospitalURL = c.Sources.Hospitalization
+	ee.Url = c.Sources.Ecdc
+	mde.url = c.Sources.Mathdro
+	responses.setInterval(c.refreshInterval())
+	upstream.fetcher.configure(c.Upstream)
+	cl.setInterval(c.refreshInterval())
+	wh.configure(c.Webhooks)
+	ae.configure(c.Alerts)
+	r.config = c
+	r.hashes = l.hashes
+	return nil
+}
+
+func (r *reloader) load() (*loadedConfig, error) {
+	c, err := loadConfig(r.filename)
+	if err != nil {
+		return nil, err
+	}
+	result := &loadedConfig{config: c}
+	result.metadata, err = loadMetadataProvider("metadata.csv", c.Metadata)
+	if err != nil {
+		return nil, err
+	}
+	err = validateMetadata(result.metadata)
+	if err != nil {
+		return nil, err
+	}
+	result.bezirke, err = loadMetadataProvider("bezirke.csv", c.Bezirke)
+	if err != nil {
+		return nil, err
+	}
+	result.ages, err = loadAgePopulationProvider(c.AgeGroups)
+	if err != nil {
+		return nil, err
+	}
+	err = validateAgePopulation(result.ages)
+	if err != nil {
+		return nil, err
+	}
+	result.capacities, err = loadCapacityProvider(c.Capacities)
+	if err != nil {
+		return nil, err
+	}
+	err = validateCapacities(result.capacities)
+	if err != nil {
+		return nil, err
+	}
+	result.boundaries, err = loadBoundaryProvider(c.Boundaries)
+	if err != nil {
+		return nil, err
+	}
+	result.hashes, err = r.fileHashes(c)
+	if err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+//GetMetrics exposes the state of the last reload, callers hold configLock
+func (r *reloader) GetMetrics() (metrics, error) {
+	success := 0.0
+	if r.success {
+		success = 1
+	}
+	result := metrics{
+		metric{Name: "cov19_config_reload_success", Value: success},
+		metric{Name: "cov19_config_last_reload_timestamp_seconds", Value: float64(r.lastReload.Unix())},
+	}
+	for file, hash := range r.hashes {
+		tags := &map[string]string{"file": file, "sha256": hash}
+		result = append(result, metric{Name: "cov19_config_file_info", Tags: tags, Value: 1})
+	}
+	return result, nil
+}
+
+func (r *reloader) Health() []error {
+	if r.lastError != nil {
+		return []error{fmt.Errorf("Config reload failed: %v", r.lastError)}
+	}
+	return nil
+}
+
+func (r *reloader) status() reloadStatus {
+	result := reloadStatus{Success: r.success, LastReload: r.lastReload, Files: r.hashes}
+	if r.lastError != nil {
+		result.Error = r.lastError.Error()
+	}
+	return result
+}
+
+//authorized checks the bearer token of an admin request, admin endpoints are disabled without a token
+func (r *reloader) authorized(req *http.Request) bool {
+	configLock.RLock()
+	token := r.config.AdminToken
+	configLock.RUnlock()
+	if token == "" {
+		return false
+	}
+	expected := []byte("Bearer " + token)
+	return subtle.ConstantTimeCompare([]byte(req.Header.Get("Authorization")), expected) == 1
+}
+
+func (r *reloader) handleReload(w http.ResponseWriter, req *http.Request) {
+	if !r.authorized(req) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if req.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	err := r.reload()
+	if err != nil {
+		logger.Printf("Reload failed: %v", err)
+		r.writeStatus(w, http.StatusUnprocessableEntity)
+	} else {
+		r.writeStatus(w, http.StatusOK)
+	}
+}
+
+func (r *reloader) handleStatus(w http.ResponseWriter, req *http.Request) {
+	if !r.authorized(req) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	r.writeStatus(w, http.StatusOK)
+}
+
+func (r *reloader) writeStatus(w http.ResponseWriter, statusCode int) {
+	configLock.RLock()
+	bytes, err := json.Marshal(r.status())
+	configLock.RUnlock()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Add("Content-type", "application/json; charset=utf-8")
+	w.WriteHeader(statusCode)
+	w.Write(bytes)
+}
+
+//withConfigLock prevents a reload from swapping the configuration while h is running
+func withConfigLock(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		configLock.RLock()
+		defer configLock.RUnlock()
+		h(w, r)
+	}
+}
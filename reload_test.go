@@ -0,0 +1,107 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeTempConfig(t *testing.T, content string) string {
+	file, err := ioutil.TempFile("", "config*.json")
+	assert.Nil(t, err)
+	defer file.Close()
+	_, err = file.WriteString(content)
+	assert.Nil(t, err)
+	return file.Name()
+}
+
+//reloadDefaults restores the embedded files and the default config, but keeps the unlimited test fetcher
+func reloadDefaults() {
+	newReloader("").reload()
+	upstream.fetcher.configure(testUpstreamConfig())
+}
+
+func TestReload(t *testing.T) {
+	defer reloadDefaults()
+	metadata := writeTempMetadata(t, "Wien,1234567,48.2,16.3\n")
+	defer os.Remove(metadata)
+	filename := writeTempConfig(t, `{"metadata": "`+metadata+`", "sources": {"ecdc": "http://localhost/ecdc"}}`)
+	defer os.Remove(filename)
+
+	r := newReloader(filename)
+	assert.Nil(t, r.reload())
+	assert.Equal(t, uint64(1234567), mp.getPopulation("Wien"))
+	assert.Equal(t, "http://localhost/ecdc", ee.Url)
+	assert.Equal(t, defaultConfig().Sources.HealthMinistry, he.url)
+
+	result, err := r.GetMetrics()
+	assert.Nil(t, err)
+	assert.Nil(t, result.checkMetric("cov19_config_reload_success", "", func(x float64) bool { return x == 1 }))
+	assert.NotNil(t, result.findMetric("cov19_config_file_info", "file="+metadata))
+	assert.NotNil(t, result.findMetric("cov19_config_file_info", "file=embedded/bezirke.csv"))
+}
+
+func TestReloadInvalid(t *testing.T) {
+	defer reloadDefaults()
+	metadata := writeTempMetadata(t, "Wien,0,48.2,16.3\n")
+	defer os.Remove(metadata)
+	filename := writeTempConfig(t, `{"metadata": "`+metadata+`", "sources": {"ecdc": "http://localhost/ecdc"}}`)
+	defer os.Remove(filename)
+
+	r := newReloader(filename)
+	assert.EqualError(t, r.reload(), "Missing population for Wien")
+	assert.Equal(t, uint64(1889100), mp.getPopulation("Wien"))
+	assert.Equal(t, defaultConfig().Sources.Ecdc, ee.Url)
+	assert.Equal(t, 1, len(r.Health()))
+
+	result, _ := r.GetMetrics()
+	assert.Nil(t, result.checkMetric("cov19_config_reload_success", "", func(x float64) bool { return x == 0 }))
+
+	filename = writeTempConfig(t, `{"sources": {"ecdc": "localhost"}}`)
+	defer os.Remove(filename)
+	r = newReloader(filename)
+	assert.EqualError(t, r.reload(), `Invalid url for source ecdc: "localhost"`)
+
+	filename = writeTempConfig(t, `{"refresh_interval": -1}`)
+	defer os.Remove(filename)
+	r = newReloader(filename)
+	assert.EqualError(t, r.reload(), filename+": refresh_interval needs to be 0 or more seconds")
+
+	filename = writeTempConfig(t, `{"webhooks": [{"url": "http://localhost/hook", "threshold": 50}]}`)
+	defer os.Remove(filename)
+	r = newReloader(filename)
+	assert.EqualError(t, r.reload(), filename+": webhook 0: A threshold needs a metric")
+
+	filename = writeTempConfig(t, `{"alerts": {"rules": [{"name": "incidence", "scope": "city"}]}}`)
+	defer os.Remove(filename)
+	r = newReloader(filename)
+	assert.EqualError(t, r.reload(), filename+`: alert rule 0: Unknown scope "city"`)
+}
+
+func TestReloadEndpoint(t *testing.T) {
+	defer reloadDefaults()
+	filename := writeTempConfig(t, `{"admin_token": "secret"}`)
+	defer os.Remove(filename)
+	r := newReloader(filename)
+	assert.Nil(t, r.reload())
+
+	ts := httptest.NewServer(http.HandlerFunc(r.handleReload))
+	defer ts.Close()
+
+	response, err := ts.Client().Post(ts.URL, "", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	request, _ := http.NewRequest(http.MethodPost, ts.URL, nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err = ts.Client().Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.True(t, strings.Contains(string(body), `"Success":true`), string(body))
+}
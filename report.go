@@ -0,0 +1,267 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+)
+
+//go:embed templates/daily.md templates/daily.html templates/daily.txt
+var reportTemplates embed.FS
+
+//reportTopCount is the number of districts listed in the top tables of the report
+const reportTopCount = 10
+
+//reportFormats are the formats of the daily report with their content type
+var reportFormats = map[string]string{
+	"markdown": "text/markdown; charset=utf-8",
+	"html":     "text/html; charset=utf-8",
+	"text":     "text/plain; charset=utf-8",
+}
+
+type reportTotal struct {
+	Label  string
+	Value  uint64
+	Change int64
+}
+
+type reportBundesland struct {
+	bundeslandStat
+	NewCases            int64
+	NewDead             int64
+	HospitalizedChange  int64
+	IntensiveCareChange int64
+}
+
+type reportBezirk struct {
+	bezirkStat
+	NewCases int64
+}
+
+//dailyReport compares the current snapshot with the last one of the previous day
+type dailyReport struct {
+	Time time.Time
+	//Since is the time of the snapshot the changes are computed against, equal to Time without a previous snapshot
+	Since      time.Time
+	Totals     []reportTotal
+	NewCases   []reportBezirk
+	Incidence  []reportBezirk
+	Bundesland []reportBundesland
+	Age        []ageStat
+	Sex        []sexStat
+	Anomalies  []string
+}
+
+func difference(current uint64, previous uint64) int64 {
+	return int64(current) - int64(previous)
+}
+
+//HasPrevious is false if there was no earlier snapshot to compare with
+func (r *dailyReport) HasPrevious() bool {
+	return !r.Since.Equal(r.Time)
+}
+
+//newDailyReport builds the report of current, changes are computed against previous if it is not nil
+func newDailyReport(current *snapshot, previous *snapshot) *dailyReport {
+	if previous == nil {
+		previous = current
+	}
+	result := &dailyReport{Time: current.Time, Since: previous.Time, Age: current.Age, Sex: current.Sex, Anomalies: make([]string, 0)}
+	result.Totals = []reportTotal{
+		{"Infected", current.Total.TotalInfected, difference(current.Total.TotalInfected, previous.Total.TotalInfected)},
+		{"Dead", current.Total.TotalDead, difference(current.Total.TotalDead, previous.Total.TotalDead)},
+		{"Hospitalized", current.Total.TotalHospitalized, difference(current.Total.TotalHospitalized, previous.Total.TotalHospitalized)},
+		{"Intensive care", current.Total.TotalIntensiveCare, difference(current.Total.TotalIntensiveCare, previous.Total.TotalIntensiveCare)},
+	}
+	for _, t := range result.Totals[:2] {
+		if t.Change < 0 {
+			result.Anomalies = append(result.Anomalies, fmt.Sprintf("%s in Austria decreased by %d", t.Label, -t.Change))
+		}
+	}
+
+	provinces := make(map[string]bundeslandStat)
+	for _, b := range previous.Bundesland {
+		provinces[b.Name] = b
+	}
+	bundeslandInfected := uint64(0)
+	for _, b := range current.Bundesland {
+		p, ok := provinces[b.Name]
+		if !ok {
+			p = b
+		}
+		r := reportBundesland{b, difference(b.Infected, p.Infected), difference(b.Dead, p.Dead), difference(b.Hospitalized, p.Hospitalized), difference(b.IntensiveCare, p.IntensiveCare)}
+		result.Bundesland = append(result.Bundesland, r)
+		bundeslandInfected += b.Infected
+		if r.NewCases < 0 {
+			result.Anomalies = append(result.Anomalies, fmt.Sprintf("Infected in %s decreased by %d", b.Name, -r.NewCases))
+		}
+		if r.NewDead < 0 {
+			result.Anomalies = append(result.Anomalies, fmt.Sprintf("Dead in %s decreased by %d", b.Name, -r.NewDead))
+		}
+	}
+	if len(current.Bundesland) > 0 && bundeslandInfected != current.Total.TotalInfected {
+		result.Anomalies = append(result.Anomalies, fmt.Sprintf("The Bundesländer add up to %d infections, the total is %d", bundeslandInfected, current.Total.TotalInfected))
+	}
+
+	districts := make(map[string]bezirkStat)
+	for _, b := range previous.Bezirk {
+		districts[b.Name] = b
+	}
+	bezirke := make([]reportBezirk, 0, len(current.Bezirk))
+	for _, b := range current.Bezirk {
+		p, ok := districts[b.Name]
+		if !ok {
+			p = b
+		}
+		delete(districts, b.Name)
+		r := reportBezirk{b, difference(b.Infected, p.Infected)}
+		bezirke = append(bezirke, r)
+		if r.NewCases < 0 {
+			result.Anomalies = append(result.Anomalies, fmt.Sprintf("Infected in %s decreased by %d", b.Name, -r.NewCases))
+		} else if r.NewCases >= 20 && r.NewCases > int64(p.Infected)/2 {
+			result.Anomalies = append(result.Anomalies, fmt.Sprintf("Infected in %s increased by %d to %d", b.Name, r.NewCases, b.Infected))
+		}
+	}
+	for _, b := range previous.Bezirk {
+		if _, missing := districts[b.Name]; missing {
+			result.Anomalies = append(result.Anomalies, fmt.Sprintf("%s is missing in the current data", b.Name))
+		}
+	}
+	result.NewCases = topBezirke(bezirke, func(a, b reportBezirk) bool { return a.NewCases > b.NewCases })
+	result.Incidence = topBezirke(bezirke, func(a, b reportBezirk) bool { return a.InfectedPer100k > b.InfectedPer100k })
+	return result
+}
+
+//topBezirke returns the first reportTopCount districts ordered by less, ties are ordered by name
+func topBezirke(bezirke []reportBezirk, less func(a, b reportBezirk) bool) []reportBezirk {
+	result := append([]reportBezirk{}, bezirke...)
+	sort.Slice(result, func(i, j int) bool {
+		if less(result[i], result[j]) {
+			return true
+		}
+		if less(result[j], result[i]) {
+			return false
+		}
+		return result[i].Name < result[j].Name
+	})
+	if len(result) > reportTopCount {
+		result = result[:reportTopCount]
+	}
+	return result
+}
+
+var reportFuncs = map[string]interface{}{
+	"signed": func(v int64) string {
+		if v > 0 {
+			return fmt.Sprintf("+%d", v)
+		}
+		return fmt.Sprintf("%d", v)
+	},
+	"decimal": func(v float64) string { return fmt.Sprintf("%.1f", v) },
+	"percent": func(v float64) string { return fmt.Sprintf("%.1f%%", v*100) },
+	"date":    func(t time.Time) string { return t.Format("2006-01-02 15:04") },
+}
+
+//render writes the report in format (markdown, html or text)
+func (r *dailyReport) render(format string, w io.Writer) error {
+	switch format {
+	case "markdown", "text":
+		name := map[string]string{"markdown": "daily.md", "text": "daily.txt"}[format]
+		t, err := template.New(name).Funcs(reportFuncs).ParseFS(reportTemplates, "templates/"+name)
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, r)
+	case "html":
+		t, err := htmltemplate.New("daily.html").Funcs(reportFuncs).ParseFS(reportTemplates, "templates/daily.html")
+		if err != nil {
+			return err
+		}
+		return t.Execute(w, r)
+	}
+	return fmt.Errorf("Unknown report format %q, use markdown, html or text", format)
+}
+
+//reportFormat reads the format parameter or the Accept header, markdown by default
+func reportFormat(r *http.Request) string {
+	if format := r.URL.Query().Get("format"); format != "" {
+		return format
+	}
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "text/html") {
+		return "html"
+	}
+	if strings.Contains(accept, "text/plain") {
+		return "text"
+	}
+	return "markdown"
+}
+
+//handleDailyReport renders the daily report of the collected snapshots
+func (c *collector) handleDailyReport(w http.ResponseWriter, r *http.Request) {
+	format := reportFormat(r)
+	contentType, ok := reportFormats[format]
+	if !ok {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(fmt.Sprintf("Unknown report format %q, use markdown, html or text", format)))
+		return
+	}
+	current, previous := c.dailySnapshots()
+	if current == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	var body strings.Builder
+	err := newDailyReport(current, previous).render(format, &body)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Write([]byte(body.String()))
+}
+
+//writeDailyReport collects a snapshot and writes the report in format to w.
+//The changes are computed against the snapshot stored in snapshotFile, which is replaced once the day changed
+//and then added to the history of c.
+func writeDailyReport(c *collector, format string, snapshotFile string, w io.Writer) error {
+	if _, ok := reportFormats[format]; !ok {
+		return fmt.Errorf("Unknown report format %q, use markdown, html or text", format)
+	}
+	var previous *snapshot
+	if snapshotFile != "" {
+		bytes, err := ioutil.ReadFile(snapshotFile)
+		if err == nil {
+			previous = &snapshot{}
+			err = json.Unmarshal(bytes, previous)
+		}
+		if err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("%s: %v", snapshotFile, err)
+		}
+	}
+	current := c.collect()
+	err := newDailyReport(current, previous).render(format, w)
+	if err != nil || snapshotFile == "" || (previous != nil && sameDay(previous.Time, current.Time)) {
+		return err
+	}
+	bytes, err := json.Marshal(current)
+	if err != nil {
+		return err
+	}
+	err = ioutil.WriteFile(snapshotFile, bytes, 0644)
+	if err != nil || previous == nil {
+		return err
+	}
+	return c.appendHistory(previous)
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testReportSnapshots() (*snapshot, *snapshot) {
+	previous := &snapshot{
+		Time:  time.Date(2020, 3, 29, 20, 0, 0, 0, time.UTC),
+		Total: overallStat{TotalInfected: 1700, TotalDead: 30, TotalHospitalized: 100, TotalIntensiveCare: 20},
+		Bundesland: []bundeslandStat{
+			{Name: "Burgenland", Infected: 125},
+			{Name: "Wien", Infected: 1450, Dead: 20, Hospitalized: 80, IntensiveCare: 15},
+		},
+		Bezirk: []bezirkStat{
+			{Name: "Innsbruck-Land", Province: "Tirol", Infected: 90, InfectedPer100k: 50.8},
+			{Name: "Wien(Stadt)", Province: "Wien", Infected: 1450, InfectedPer100k: 76.8},
+			{Name: "Atlantis", Infected: 3},
+		},
+	}
+	current := &snapshot{
+		Time:  time.Date(2020, 3, 30, 8, 0, 0, 0, time.UTC),
+		Total: overallStat{TotalInfected: 1820, TotalDead: 32, TotalHospitalized: 110, TotalIntensiveCare: 18},
+		Bundesland: []bundeslandStat{
+			{Name: "Burgenland", Infected: 120},
+			{Name: "Wien", Infected: 1500, Dead: 22, Hospitalized: 90, IntensiveCare: 14},
+		},
+		Bezirk: []bezirkStat{
+			{Name: "Innsbruck-Land", Province: "Tirol", Infected: 150, InfectedPer100k: 84.7},
+			{Name: "Wien(Stadt)", Province: "Wien", Infected: 1500, InfectedPer100k: 79.4},
+		},
+		Age: []ageStat{{Group: "<5", Infected: 10, InfectedShare: 0.1}},
+		Sex: []sexStat{{Sex: "männlich", Infected: 52, InfectedShare: 0.52}, {Sex: "weiblich", Infected: 48, InfectedShare: 0.48}},
+	}
+	return current, previous
+}
+
+func TestDailyReport(t *testing.T) {
+	current, previous := testReportSnapshots()
+	r := newDailyReport(current, previous)
+	assert.True(t, r.HasPrevious())
+	assert.Equal(t, reportTotal{"Infected", 1820, 120}, r.Totals[0])
+	assert.Equal(t, reportTotal{"Intensive care", 18, -2}, r.Totals[3])
+	assert.Equal(t, "Innsbruck-Land", r.NewCases[0].Name)
+	assert.Equal(t, int64(60), r.NewCases[0].NewCases)
+	assert.Equal(t, "Wien(Stadt)", r.NewCases[1].Name)
+	assert.Equal(t, "Innsbruck-Land", r.Incidence[0].Name)
+	assert.Equal(t, int64(-5), r.Bundesland[0].NewCases)
+	assert.Equal(t, int64(10), r.Bundesland[1].HospitalizedChange)
+	assert.Equal(t, []string{
+		"Infected in Burgenland decreased by 5",
+		"The Bundesländer add up to 1620 infections, the total is 1820",
+		"Infected in Innsbruck-Land increased by 60 to 150",
+		"Atlantis is missing in the current data",
+	}, r.Anomalies)
+
+	r = newDailyReport(current, nil)
+	assert.False(t, r.HasPrevious())
+	assert.Equal(t, int64(0), r.Totals[0].Change)
+}
+
+func TestDailyReportFormats(t *testing.T) {
+	current, previous := testReportSnapshots()
+	r := newDailyReport(current, previous)
+
+	result := bytes.Buffer{}
+	assert.Nil(t, r.render("markdown", &result))
+	assert.Contains(t, result.String(), "# COVID-19 in Austria — 2020-03-30 08:00")
+	assert.Contains(t, result.String(), "| Infected | 1820 | +120 |")
+	assert.Contains(t, result.String(), "| Wien(Stadt) | Wien | +50 | 1500 | 79.4 |")
+	assert.Contains(t, result.String(), "| männlich | 52 | 52.0% |")
+	assert.Contains(t, result.String(), "- Atlantis is missing in the current data")
+
+	result.Reset()
+	assert.Nil(t, r.render("html", &result))
+	assert.Contains(t, result.String(), `<td>Infected</td><td class="number">1820</td><td class="number">&#43;120</td>`)
+	assert.Contains(t, result.String(), "<li>The Bundesländer add up to 1620 infections, the total is 1820</li>")
+
+	result.Reset()
+	assert.Nil(t, r.render("text", &result))
+	assert.Contains(t, result.String(), "Infected               1820     +120")
+	assert.Contains(t, result.String(), "  - Atlantis is missing in the current data")
+
+	assert.EqualError(t, r.render("pdf", &result), `Unknown report format "pdf", use markdown, html or text`)
+}
+
+func TestDailyReportHandler(t *testing.T) {
+	c := newCollector(nil)
+	ts := httptest.NewServer(http.HandlerFunc(c.handleDailyReport))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	current, previous := testReportSnapshots()
+	c.update(previous)
+	c.update(current)
+
+	response, err = ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/markdown; charset=utf-8", response.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Contains(t, string(body), "| Infected | 1820 | +120 |")
+
+	request, _ := http.NewRequest(http.MethodGet, ts.URL, nil)
+	request.Header.Set("Accept", "text/html,application/xhtml+xml")
+	response, err = ts.Client().Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/html; charset=utf-8", response.Header.Get("Content-Type"))
+
+	response, err = ts.Client().Get(ts.URL + "?format=text")
+	assert.Nil(t, err)
+	assert.Equal(t, "text/plain; charset=utf-8", response.Header.Get("Content-Type"))
+
+	response, err = ts.Client().Get(ts.URL + "?format=pdf")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func TestWriteDailyReport(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	file, err := ioutil.TempFile("", "snapshot*.json")
+	assert.Nil(t, err)
+	file.Close()
+	os.Remove(file.Name())
+	defer os.Remove(file.Name())
+
+	c := newCollector(mockApi)
+	result := bytes.Buffer{}
+	assert.Nil(t, writeDailyReport(c, "markdown", file.Name(), &result))
+	assert.Contains(t, result.String(), "No earlier data available")
+	assert.Contains(t, result.String(), "| Infected | 1820 | 0 |")
+
+	//the second run compares against the stored snapshot
+	result.Reset()
+	assert.Nil(t, writeDailyReport(c, "text", file.Name(), &result))
+	assert.True(t, strings.HasPrefix(result.String(), "COVID-19 in Austria"))
+	assert.Contains(t, result.String(), "Changes since")
+
+	//a snapshot of an earlier day is replaced and added to the history
+	yesterday := testSnapshot(1500, 1800)
+	yesterday.Time = time.Now().Add(-48 * time.Hour)
+	stored, _ := json.Marshal(yesterday)
+	assert.Nil(t, ioutil.WriteFile(file.Name(), stored, 0644))
+	historyFile := file.Name() + ".history"
+	defer os.Remove(historyFile)
+	assert.Nil(t, c.loadHistory(historyFile))
+	assert.Nil(t, writeDailyReport(c, "text", file.Name(), &result))
+	history, err := readHistory(historyFile)
+	assert.Nil(t, err)
+	if assert.Equal(t, 1, len(history)) {
+		assert.Equal(t, uint64(1800), history[0].Total.TotalInfected)
+	}
+
+	assert.EqualError(t, writeDailyReport(c, "pdf", "", &result), `Unknown report format "pdf", use markdown, html or text`)
+}
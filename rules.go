@@ -0,0 +1,125 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+//rulesJob is the Prometheus job scraping the exporter, as in config/prometheus.yml
+const rulesJob = "covid19"
+
+//staleSeconds is the time after which a source without new content is reported as stale
+const staleSeconds = 36 * 60 * 60
+
+type recordingRule struct {
+	Record string
+	Expr   string
+}
+
+type alertingRule struct {
+	Alert       string
+	Expr        string
+	For         string
+	Severity    string
+	Summary     string
+	Description string
+}
+
+type ruleGroup struct {
+	Name      string
+	Recording []recordingRule
+	Alerting  []alertingRule
+}
+
+//recordingRules records the daily increase and the 7-day sum of new cases of every cumulative metric per region
+func recordingRules() []recordingRule {
+	result := make([]recordingRule, 0)
+	for _, m := range metricRegistry {
+		if m.Region == "" {
+			continue
+		}
+		result = append(result,
+			recordingRule{
+				Record: fmt.Sprintf("%s:%s:increase1d", m.Region, m.Name),
+				Expr:   fmt.Sprintf("sum by (%s) (delta(%s[1d]))", m.Region, m.Name),
+			},
+			recordingRule{
+				Record: fmt.Sprintf("%s:%s:increase7d", m.Region, m.Name),
+				Expr:   fmt.Sprintf("sum by (%s) (delta(%s[7d]))", m.Region, m.Name),
+			})
+	}
+	return result
+}
+
+func alertingRules() []alertingRule {
+	return []alertingRule{
+		{
+			Alert:       "CovidExporterDown",
+			Expr:        fmt.Sprintf(`up{job="%s"} == 0`, rulesJob),
+			For:         "5m",
+			Severity:    "critical",
+			Summary:     "covid19-at exporter is down",
+			Description: "{{ $labels.instance }} could not be scraped for 5 minutes.",
+		},
+		{
+			Alert:       "CovidSourceStale",
+			Expr:        fmt.Sprintf("time() - cov19_upstream_last_changed_timestamp_seconds > %d", staleSeconds),
+			For:         "1h",
+			Severity:    "warning",
+			Summary:     "Upstream source publishes no new data",
+			Description: "{{ $labels.url }} did not change for {{ $value | humanizeDuration }}.",
+		},
+		{
+			Alert:       "CovidScrapeErrors",
+			Expr:        "cov19_scrape_error == 1",
+			For:         "15m",
+			Severity:    "warning",
+			Summary:     "Exporter fails to parse its source",
+			Description: "{{ $labels.exporter }} failed to read or parse its source for 15 minutes, see /health.",
+		},
+		{
+			Alert:       "CovidConfigReloadFailed",
+			Expr:        "cov19_config_reload_success == 0",
+			For:         "5m",
+			Severity:    "warning",
+			Summary:     "Config reload failed",
+			Description: "The last reload of the configuration of {{ $labels.instance }} failed, the previous configuration is still in use.",
+		},
+	}
+}
+
+func ruleGroups() []ruleGroup {
+	return []ruleGroup{
+		{Name: "covid19.recording", Recording: recordingRules()},
+		{Name: "covid19.alerts", Alerting: alertingRules()},
+	}
+}
+
+//writeRules writes the rule groups as a Prometheus rules file
+func writeRules(w io.Writer) error {
+	var b strings.Builder
+	b.WriteString("# Generated by covid19-at -rules, do not edit\n")
+	b.WriteString("groups:\n")
+	for _, g := range ruleGroups() {
+		fmt.Fprintf(&b, "  - name: %s\n", strconv.Quote(g.Name))
+		b.WriteString("    rules:\n")
+		for _, r := range g.Recording {
+			fmt.Fprintf(&b, "      - record: %s\n", strconv.Quote(r.Record))
+			fmt.Fprintf(&b, "        expr: %s\n", strconv.Quote(r.Expr))
+		}
+		for _, r := range g.Alerting {
+			fmt.Fprintf(&b, "      - alert: %s\n", strconv.Quote(r.Alert))
+			fmt.Fprintf(&b, "        expr: %s\n", strconv.Quote(r.Expr))
+			fmt.Fprintf(&b, "        for: %s\n", r.For)
+			b.WriteString("        labels:\n")
+			fmt.Fprintf(&b, "          severity: %s\n", strconv.Quote(r.Severity))
+			b.WriteString("        annotations:\n")
+			fmt.Fprintf(&b, "          summary: %s\n", strconv.Quote(r.Summary))
+			fmt.Fprintf(&b, "          description: %s\n", strconv.Quote(r.Description))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
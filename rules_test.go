@@ -0,0 +1,73 @@
+package main
+
+import (
+	"io/ioutil"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var metricName = regexp.MustCompile(`cov19_[a-z0-9_]+`)
+
+func TestRulesReferenceRegisteredMetrics(t *testing.T) {
+	records := make(map[string]bool)
+	for _, g := range ruleGroups() {
+		for _, r := range g.Recording {
+			assert.False(t, records[r.Record], r.Record)
+			records[r.Record] = true
+			for _, name := range metricName.FindAllString(r.Expr, -1) {
+				assert.NotNil(t, findMetricInfo(name), "%s: unknown metric %s", r.Record, name)
+			}
+		}
+		for _, r := range g.Alerting {
+			for _, name := range metricName.FindAllString(r.Expr, -1) {
+				assert.NotNil(t, findMetricInfo(name), "%s: unknown metric %s", r.Alert, name)
+			}
+		}
+	}
+	assert.True(t, records["bezirk:cov19_bezirk_infected:increase7d"])
+	assert.True(t, records["province:cov19_detail:increase1d"])
+	assert.True(t, records["country:cov19_world_infected:increase1d"])
+}
+
+func TestRulesFileIsGenerated(t *testing.T) {
+	var b strings.Builder
+	assert.Nil(t, writeRules(&b))
+	assert.Contains(t, b.String(), `      - alert: "CovidExporterDown"`)
+	assert.Contains(t, b.String(), `        expr: "up{job=\"covid19\"} == 0"`)
+
+	committed, err := ioutil.ReadFile("config/rules.yml")
+	assert.Nil(t, err)
+	assert.Equal(t, b.String(), string(committed), "config/rules.yml is outdated, run covid19-at -rules > config/rules.yml")
+}
+
+func TestRegistryCoversExporters(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+	e := newAlertEngine()
+	e.alerts["r/Wien"] = &alertState{Rule: "r", Region: "Wien", State: "firing"}
+	d := newWebhookDispatcher()
+	d.record(webhookDelivery{URL: "http://localhost/hook", Success: true})
+	c := newCollector(nil)
+	c.history = []*snapshot{testSnapshot(1400, 1720), testSnapshot(1450, 1770), testSnapshot(1480, 1800)}
+	c.current = testSnapshot(1500, 1820)
+	f := newForecastExporter(c)
+	capacities := newCapacityExporter(c, testCapacities(t))
+	c.history[2].Bundesland[1].Tests, c.current.Bundesland[1].Tests = 9000, 9400
+
+	for _, exporter := range []Exporter{mockApi.he, mockApi.se, mockApi.ee, mockApi.mde, newReloader(""), upstream, d, e, f, capacities, newTestExporter(c)} {
+		metrics, _ := exporter.GetMetrics()
+		for _, m := range metrics {
+			info := findMetricInfo(m.Name)
+			if !assert.NotNil(t, info, "%s: %s is not registered", exporterName(exporter), m.Name) || m.Tags == nil {
+				continue
+			}
+			for label := range *m.Tags {
+				assert.True(t, info.hasLabel(label), "%s: label %s is not registered", m.Name, label)
+			}
+		}
+	}
+	assert.NotNil(t, findMetricInfo("cov19_scrape_error"))
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+
+	"github.com/cinemast/covid19-at/simulation"
+)
+
+//newSimulationSeed reads the totals of s, the recovered of Austria in the world stats and the population of the metadata.
+//Without the population or the recovered the active cases are unknown, so both are required.
+func newSimulationSeed(s *snapshot, metadata *metadataProvider) (simulation.Seed, error) {
+	result := simulation.Seed{Time: s.Time, Population: metadata.getPopulation("Austria"), Infected: s.Total.TotalInfected, Dead: s.Total.TotalDead}
+	if result.Population == 0 {
+		return result, fmt.Errorf("No population of Austria in the metadata")
+	}
+	found := false
+	for _, w := range s.World {
+		if w.Name == "Austria" {
+			result.Recovered, found = w.Recovered, true
+		}
+	}
+	if !found {
+		return result, fmt.Errorf("No recovered cases of Austria in the world stats")
+	}
+	return result, nil
+}
+
+//handleSimulate runs the simulation with the posted parameters from the current snapshot
+func (c *collector) handleSimulate(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	p, err := simulation.ReadParameters(http.MaxBytesReader(w, r.Body, 1<<20))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	current, _ := c.snapshots()
+	if current == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	seed, err := newSimulationSeed(current, mp)
+	if err != nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	writeResponse(w, r, func() (interface{}, error) { return simulation.Simulate(seed, p), nil })
+}
+
+//writeSimulation collects the current data and writes the simulation as json or csv
+func writeSimulation(c *collector, format string, parametersFile string, w io.Writer) error {
+	if format != "json" && format != "csv" {
+		return fmt.Errorf("Unknown simulation format %q, use json or csv", format)
+	}
+	p := simulation.DefaultParameters()
+	if parametersFile != "" {
+		file, err := os.Open(parametersFile)
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+		p, err = simulation.ReadParameters(file)
+		if err != nil {
+			return fmt.Errorf("%s: %v", parametersFile, err)
+		}
+	}
+	seed, err := newSimulationSeed(c.collect(), mp)
+	if err != nil {
+		return err
+	}
+	days := simulation.Simulate(seed, p)
+	if format == "csv" {
+		return writeCsv(w, days)
+	}
+	return json.NewEncoder(w).Encode(days)
+}
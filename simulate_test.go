@@ -0,0 +1,81 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/cinemast/covid19-at/simulation"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSimulationSeed(t *testing.T) {
+	s := testSnapshot(1500, 1820)
+	s.World = []worldStat{{Name: "Germany", Recovered: 5000}, {Name: "Austria", Recovered: 300}}
+	seed, err := newSimulationSeed(s, newMetadataProvider())
+	assert.Nil(t, err)
+	assert.Equal(t, simulation.Seed{Time: s.Time, Population: 8747358, Infected: 1820, Dead: 32, Recovered: 300}, seed)
+
+	s.World = s.World[:1]
+	_, err = newSimulationSeed(s, newMetadataProvider())
+	assert.EqualError(t, err, "No recovered cases of Austria in the world stats")
+	_, err = newSimulationSeed(s, &metadataProvider{data: map[string]metaData{}})
+	assert.EqualError(t, err, "No population of Austria in the metadata")
+}
+
+func TestSimulateHandler(t *testing.T) {
+	c := newCollector(nil)
+	ts := httptest.NewServer(http.HandlerFunc(c.handleSimulate))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusMethodNotAllowed, response.StatusCode)
+	response, err = ts.Client().Post(ts.URL, "application/json", strings.NewReader(`{"days": 10}`))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	s := testSnapshot(1500, 1820)
+	c.update(s)
+	response, err = ts.Client().Post(ts.URL, "application/json", strings.NewReader(`{"days": 10}`))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode, "no recovered cases")
+
+	s.World = []worldStat{{Name: "Austria", Recovered: 300}}
+	response, err = ts.Client().Post(ts.URL, "application/json", strings.NewReader(`{"days": 10}`))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	days := make([]simulation.Day, 0)
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&days))
+	assert.Equal(t, 11, len(days))
+	assert.Equal(t, 1820.0, days[0].Infected)
+
+	response, err = ts.Client().Post(ts.URL+"?format=csv", "application/json", strings.NewReader(`{"days": 2}`))
+	assert.Nil(t, err)
+	assert.Equal(t, "text/csv; charset=utf-8", response.Header.Get("Content-type"))
+	body, _ := ioutil.ReadAll(response.Body)
+	lines := strings.Split(strings.TrimSpace(string(body)), "\n")
+	assert.Equal(t, 4, len(lines))
+	assert.Equal(t, "day,date,susceptible,exposed,infectious,recovered,dead,infected,new_cases,r", lines[0])
+
+	response, err = ts.Client().Post(ts.URL, "application/json", strings.NewReader(`{"r0": "high"}`))
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
+
+func TestWriteSimulationFormats(t *testing.T) {
+	assert.NotNil(t, writeSimulation(newCollector(nil), "xml", "", ioutil.Discard))
+
+	dir, err := ioutil.TempDir("", "simulation")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "parameters.json")
+	assert.Nil(t, ioutil.WriteFile(file, []byte(`{"days": -1}`), 0644))
+	err = writeSimulation(newCollector(nil), "json", file, ioutil.Discard)
+	assert.True(t, err != nil && strings.HasPrefix(err.Error(), file))
+}
@@ -0,0 +1,171 @@
+//Package simulation is a SEIR model of the spread of COVID-19 in a population.
+package simulation
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"time"
+)
+
+//MaxDays limits the length of a simulation
+const MaxDays = 730
+
+//steps is the number of integration steps per simulated day
+const steps = 10
+
+//Intervention reduces the contacts from Day on until the next intervention, 0 is the first simulated day
+type Intervention struct {
+	Day int `json:"day"`
+	//Reduction is the share of contacts prevented, 0.4 lowers the reproduction number by 40%
+	Reduction float64 `json:"reduction"`
+}
+
+//Parameters configure the SEIR model, missing fields keep their default
+type Parameters struct {
+	//R0 is the basic reproduction number without interventions
+	R0 float64 `json:"r0"`
+	//IncubationDays is the mean time from exposure until a case is infectious
+	IncubationDays float64 `json:"incubation_days"`
+	//InfectiousDays is the mean time a case is infectious
+	InfectiousDays float64 `json:"infectious_days"`
+	//FatalityRate is the share of the infectious cases that die
+	FatalityRate  float64        `json:"fatality_rate"`
+	Days          int            `json:"days"`
+	Interventions []Intervention `json:"interventions"`
+}
+
+//DefaultParameters are the parameters used for fields missing in the input
+func DefaultParameters() Parameters {
+	return Parameters{R0: 2.5, IncubationDays: 5.2, InfectiousDays: 5, FatalityRate: 0.01, Days: 180, Interventions: make([]Intervention, 0)}
+}
+
+//Validate checks the ranges of the parameters. Incubation and infectious times below a day are rejected,
+//the integration with steps per day overshoots or diverges for them.
+func (p Parameters) Validate() error {
+	if p.R0 < 0 {
+		return fmt.Errorf("r0 must not be negative")
+	}
+	if p.IncubationDays < 1 || p.InfectiousDays < 1 {
+		return fmt.Errorf("incubation_days and infectious_days must be at least 1")
+	}
+	if p.FatalityRate < 0 || p.FatalityRate > 1 {
+		return fmt.Errorf("fatality_rate must be between 0 and 1")
+	}
+	if p.Days < 1 || p.Days > MaxDays {
+		return fmt.Errorf("days must be between 1 and %d", MaxDays)
+	}
+	for _, i := range p.Interventions {
+		if i.Day < 0 || i.Reduction < 0 || i.Reduction > 1 {
+			return fmt.Errorf("Invalid intervention on day %d, day must not be negative and reduction must be between 0 and 1", i.Day)
+		}
+	}
+	return nil
+}
+
+//reduction returns the reduction of the latest intervention that started at or before day
+func (p Parameters) reduction(day int) float64 {
+	result, start := 0.0, -1
+	for _, i := range p.Interventions {
+		if i.Day <= day && i.Day >= start {
+			result, start = i.Reduction, i.Day
+		}
+	}
+	return result
+}
+
+//ReadParameters reads json parameters on top of the defaults, an empty input keeps all defaults
+func ReadParameters(r io.Reader) (Parameters, error) {
+	result := DefaultParameters()
+	bytes, err := ioutil.ReadAll(r)
+	if err != nil {
+		return result, err
+	}
+	if len(bytes) > 0 {
+		err = json.Unmarshal(bytes, &result)
+		if err != nil {
+			return result, err
+		}
+	}
+	return result, result.Validate()
+}
+
+//Seed is the state the simulation starts from
+type Seed struct {
+	Time       time.Time
+	Population uint64
+	//Infected, Dead and Recovered are the cases since the start of the pandemic
+	Infected  uint64
+	Dead      uint64
+	Recovered uint64
+}
+
+//Day is the state at the end of a simulated day, day 0 is the seed
+type Day struct {
+	Day         int       `json:"day"`
+	Date        time.Time `json:"date"`
+	Susceptible float64   `json:"susceptible"`
+	Exposed     float64   `json:"exposed"`
+	Infectious  float64   `json:"infectious"`
+	Recovered   float64   `json:"recovered"`
+	Dead        float64   `json:"dead"`
+	//Infected are the cases since the start of the pandemic, a case counts once it is infectious
+	Infected float64 `json:"infected"`
+	NewCases float64 `json:"new_cases"`
+	//R is the effective reproduction number of the day
+	R float64 `json:"r"`
+}
+
+//Simulate integrates the SEIR model from the seed, the active cases are infectious and
+//as many are exposed as become infectious during the incubation time
+func Simulate(seed Seed, p Parameters) []Day {
+	n := float64(seed.Population)
+	infectious := math.Max(float64(seed.Infected)-float64(seed.Dead)-float64(seed.Recovered), 0)
+	exposed := math.Min(infectious*p.IncubationDays/p.InfectiousDays, n)
+	state := Day{
+		Date:       seed.Time,
+		Exposed:    exposed,
+		Infectious: infectious,
+		Recovered:  float64(seed.Recovered),
+		Dead:       float64(seed.Dead),
+		Infected:   float64(seed.Infected),
+	}
+	state.Susceptible = math.Max(n-state.Exposed-state.Infectious-state.Recovered-state.Dead, 0)
+	effectiveR := func(day int) float64 {
+		if n == 0 {
+			return 0
+		}
+		return p.R0 * (1 - p.reduction(day)) * state.Susceptible / n
+	}
+	state.R = effectiveR(0)
+
+	result := []Day{state}
+	dt := 1 / float64(steps)
+	for day := 1; day <= p.Days; day++ {
+		beta := p.R0 * (1 - p.reduction(day)) / p.InfectiousDays
+		infected := state.Infected
+		for step := 0; step < steps; step++ {
+			exposures := 0.0
+			if n > 0 {
+				exposures = beta * state.Susceptible * state.Infectious / n * dt
+			}
+			exposures = math.Min(exposures, state.Susceptible)
+			onsets := state.Exposed / p.IncubationDays * dt
+			removals := state.Infectious / p.InfectiousDays * dt
+			state.Susceptible -= exposures
+			state.Exposed += exposures - onsets
+			state.Infectious += onsets - removals
+			state.Recovered += removals * (1 - p.FatalityRate)
+			state.Dead += removals * p.FatalityRate
+			state.Infected += onsets
+		}
+		state.Day = day
+		state.Date = seed.Time.Add(time.Duration(day) * 24 * time.Hour)
+		state.NewCases = state.Infected - infected
+		state.R = effectiveR(day)
+		result = append(result, state)
+	}
+	return result
+}
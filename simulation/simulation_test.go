@@ -0,0 +1,73 @@
+package simulation
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testSeed() Seed {
+	return Seed{Time: time.Date(2020, 3, 30, 8, 0, 0, 0, time.UTC), Population: 1000000, Infected: 1200, Dead: 100, Recovered: 100}
+}
+
+func TestSimulate(t *testing.T) {
+	p := DefaultParameters()
+	p.Days = 100
+	days := Simulate(testSeed(), p)
+	assert.Equal(t, 101, len(days))
+	assert.Equal(t, 1000.0, days[0].Infectious)
+	assert.Equal(t, 1040.0, days[0].Exposed)
+	assert.InDelta(t, 2.5, days[0].R, 0.01)
+	assert.Equal(t, testSeed().Time.Add(24*time.Hour), days[1].Date)
+
+	for _, d := range days {
+		total := d.Susceptible + d.Exposed + d.Infectious + d.Recovered + d.Dead
+		assert.InDelta(t, 1000000, total, 0.001, "day %d", d.Day)
+	}
+	last := days[len(days)-1]
+	assert.True(t, last.R < 1, "herd immunity after 100 days")
+	assert.InDelta(t, last.Infected-days[99].Infected, last.NewCases, 0.001)
+	assert.InDelta(t, 0.01, (last.Dead-100)/(last.Dead-100+last.Recovered-100), 0.0001)
+
+	p.Interventions = []Intervention{{Day: 1, Reduction: 0.8}}
+	locked := Simulate(testSeed(), p)
+	assert.InDelta(t, 0.5, locked[1].R, 0.01)
+	assert.True(t, locked[100].Infected < last.Infected)
+	assert.True(t, locked[100].NewCases < locked[1].NewCases, "R below 1 lowers the new cases")
+
+	assert.False(t, math.IsNaN(Simulate(Seed{}, DefaultParameters())[1].R), "no population")
+}
+
+func TestSimulateShortTimes(t *testing.T) {
+	p := DefaultParameters()
+	p.IncubationDays, p.InfectiousDays, p.R0 = 1, 1, 8
+	for _, d := range Simulate(testSeed(), p) {
+		assert.True(t, d.Susceptible >= 0 && d.Exposed >= 0 && d.Infectious >= 0, "day %d", d.Day)
+	}
+}
+
+func TestParameters(t *testing.T) {
+	p := Parameters{Interventions: []Intervention{{0, 0.2}, {30, 0.6}, {10, 0.4}}}
+	assert.Equal(t, 0.2, p.reduction(5))
+	assert.Equal(t, 0.4, p.reduction(29))
+	assert.Equal(t, 0.6, p.reduction(30))
+	assert.Equal(t, 0.0, Parameters{}.reduction(3))
+
+	p, err := ReadParameters(strings.NewReader(""))
+	assert.Nil(t, err)
+	assert.Equal(t, DefaultParameters(), p)
+	p, err = ReadParameters(strings.NewReader(`{"r0": 1.2, "interventions": [{"day": 3, "reduction": 0.5}]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 1.2, p.R0)
+	assert.Equal(t, 180, p.Days)
+	assert.Equal(t, []Intervention{{3, 0.5}}, p.Interventions)
+
+	for _, body := range []string{`{"r0": -1}`, `{"days": 0}`, `{"days": 731}`, `{"fatality_rate": 2}`, `{"infectious_days": 0}`,
+		`{"infectious_days": 0.5}`, `{"incubation_days": 0.9}`, `{"interventions": [{"day": 3, "reduction": 1.5}]}`, `{`} {
+		_, err = ReadParameters(strings.NewReader(body))
+		assert.NotNil(t, err, body)
+	}
+}
@@ -1,22 +1,23 @@
 package main
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"github.com/PuerkitoBio/goquery"
-	"net/http"
 	"regexp"
 	"strings"
-	"time"
 )
 
 type socialMinistryExporter struct {
-	url string
-	mp  *metadataProvider
+	url         string
+	hospitalURL string
+	mp          *metadataProvider
 }
 
 func newSocialMinistryExporter(lp *metadataProvider) *socialMinistryExporter {
-	return &socialMinistryExporter{url: "https://www.sozialministerium.at/Informationen-zum-Coronavirus/Neuartiges-Coronavirus-(2019-nCov).html", mp: lp}
+	sources := defaultConfig().Sources
+	return &socialMinistryExporter{url: sources.SocialMinistry, hospitalURL: sources.Hospitalization, mp: lp}
 }
 
 func (e *socialMinistryExporter) Health() []error {
@@ -31,15 +32,20 @@ func (e *socialMinistryExporter) Health() []error {
 	return errors
 }
 
-//GetMetrics returns total stats and province details
-func (e *socialMinistryExporter) GetMetrics() (metrics, error) {
-	client := http.Client{Timeout: 3 * time.Second}
-	response, err := client.Get(e.url)
+//getDocument returns the parsed page of the ministry, it is only parsed again when the page changes
+func (e *socialMinistryExporter) getDocument() (*goquery.Document, error) {
+	document, err := upstream.parse("social_ministry", e.url, func(body []byte) (interface{}, error) {
+		return goquery.NewDocumentFromReader(bytes.NewReader(body))
+	})
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
-	document, err := goquery.NewDocumentFromReader(response.Body)
+	return document.(*goquery.Document), nil
+}
+
+//GetMetrics returns total stats and province details
+func (e *socialMinistryExporter) GetMetrics() (metrics, error) {
+	document, err := e.getDocument()
 	if err != nil {
 		return nil, err
 	}
@@ -59,6 +65,9 @@ func (e *socialMinistryExporter) GetMetrics() (metrics, error) {
 	for _, s := range provinceStats {
 		tags := e.getTags(s.location)
 		population := e.mp.getPopulation(s.location)
+		if s.tests > 0 {
+			provinceMetrics = append(provinceMetrics, metric{Name: "cov19_tests_detail", Value: float64(s.tests), Tags: tags})
+		}
 		if s.deaths > 0 {
 			provinceMetrics = append(provinceMetrics, metric{Name: "cov19_detail_dead", Value: float64(s.deaths), Tags: tags})
 			if population > 0 {
@@ -80,13 +89,7 @@ func (e *socialMinistryExporter) getTags(province string) *map[string]string {
 }
 
 func (e *socialMinistryExporter) getBundeslandStats() (map[string]CovidStat, error) {
-	client := http.Client{Timeout: 3 * time.Second}
-	response, err := client.Get(e.url)
-	if err != nil {
-		return nil, err
-	}
-	defer response.Body.Close()
-	document, err := goquery.NewDocumentFromReader(response.Body)
+	document, err := e.getDocument()
 	if err != nil {
 		return nil, err
 	}
@@ -110,7 +113,7 @@ func (e *socialMinistryExporter) getProvinceStats(document *goquery.Document) (m
 	for _, match := range matches {
 		infected := atoi(match[2])
 		province := strings.TrimSpace(strings.ReplaceAll(match[1], ",", ""))
-		result[province] = CovidStat{province, infected, 0}
+		result[province] = CovidStat{location: province, infected: infected}
 
 	}
 
@@ -133,6 +136,17 @@ func (e *socialMinistryExporter) getProvinceStats(document *goquery.Document) (m
 			}
 		}
 	}
+
+	testsMatch := regexp.MustCompile(`Testungen.*`).FindAllString(summary, 1)
+	if len(testsMatch) > 0 {
+		for _, match := range re.FindAllStringSubmatch(testsMatch[0], -1) {
+			location := strings.TrimSpace(strings.ReplaceAll(match[1], ",", ""))
+			stat := result[location]
+			stat.location = location
+			stat.tests = atoi(match[2])
+			result[location] = stat
+		}
+	}
 	return result, nil
 }
 
@@ -144,12 +158,34 @@ func (e *socialMinistryExporter) getTotalMetrics(document *goquery.Document) (me
 		return nil, err
 	}
 
+	if tests, ok := parseTotalTests(summary); ok {
+		result = append(result, metric{Name: "cov19_tests", Value: float64(tests)})
+	}
+
+	return result, nil
+}
+
+//parseTotalTests reads the tests performed in Austria from the summary, the number after the last colon of "Testungen"
+func parseTotalTests(summary string) (uint64, bool) {
 	testsMatch := regexp.MustCompile(`Testungen.*: [^0-9]*(?P<number>[0-9\.]+)`).FindAllStringSubmatch(summary, -1)
 	if len(testsMatch) >= 1 && len(testsMatch[0]) >= 2 {
-		result = append(result, metric{Name: "cov19_tests", Value: atoif(testsMatch[0][1])})
+		return atoi(testsMatch[0][1]), true
 	}
+	return 0, false
+}
 
-	return result, nil
+//getTotalTests returns the tests performed in Austria, 0 if the page does not contain them
+func (e *socialMinistryExporter) getTotalTests() (uint64, error) {
+	document, err := e.getDocument()
+	if err != nil {
+		return 0, err
+	}
+	summary, err := document.Find("#content").First().Html()
+	if err != nil {
+		return 0, err
+	}
+	tests, _ := parseTotalTests(summary)
+	return tests, nil
 }
 
 func (e *socialMinistryExporter) getHospitalizedMetrics() (metrics, error) {
@@ -177,15 +213,20 @@ type hospitalStat struct {
 	IntensiveCare uint64
 }
 
+//getHospitalizedStats returns the patients per province, the result is shared and must not be modified
 func (e *socialMinistryExporter) getHospitalizedStats() (map[string]hospitalStat, error) {
-	client := http.Client{Timeout: 3 * time.Second}
-	response, err := client.Get("https://www.sozialministerium.at/Informationen-zum-Coronavirus/Dashboard/Zahlen-zur-Hospitalisierung")
+	result, err := upstream.parse("hospitalization", e.hospitalURL, parseHospitalizedStats)
 	if err != nil {
 		return nil, err
 	}
-	defer response.Body.Close()
+	return result.(map[string]hospitalStat), nil
+}
 
-	document, _ := goquery.NewDocumentFromReader(response.Body)
+func parseHospitalizedStats(body []byte) (interface{}, error) {
+	document, err := goquery.NewDocumentFromReader(bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
 	rows := document.Find("table").Find("tbody").Find("tr")
 
 	result := make(map[string]hospitalStat, 0)
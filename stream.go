@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//keepAliveInterval is the time after which an idle stream receives a comment, so proxies keep the connection open
+var keepAliveInterval = 30 * time.Second
+
+//parseScopes reads the comma separated scope parameter, all scopes if it is missing
+func parseScopes(r *http.Request) (map[string]bool, error) {
+	result := make(map[string]bool)
+	parameter := r.URL.Query().Get("scope")
+	if parameter == "" {
+		for _, scope := range eventScopes {
+			result[scope] = true
+		}
+		return result, nil
+	}
+	for _, scope := range strings.Split(parameter, ",") {
+		known := false
+		for _, s := range eventScopes {
+			known = known || s == scope
+		}
+		if !known {
+			return nil, fmt.Errorf("Unknown scope %q, use %s", scope, strings.Join(eventScopes, ", "))
+		}
+		result[scope] = true
+	}
+	return result, nil
+}
+
+func writeEvent(w http.ResponseWriter, e updateEvent) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", e.ID, e.Scope, data)
+	return err
+}
+
+//handleStream sends the update events of the collector as Server-Sent Events.
+//Clients reconnecting with Last-Event-ID first receive the events they missed.
+func (c *collector) handleStream(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		w.WriteHeader(500)
+		w.Write([]byte("Streaming is not supported"))
+		return
+	}
+	scopes, err := parseScopes(r)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	lastID := r.Header.Get("Last-Event-ID")
+	if lastID == "" {
+		lastID = r.URL.Query().Get("last_event_id")
+	}
+	sent, _ := strconv.ParseUint(lastID, 10, 64)
+
+	events := c.subscribe()
+	defer c.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprint(w, "retry: 10000\n\n")
+	send := func(e updateEvent) error {
+		if e.ID <= sent {
+			return nil
+		}
+		sent = e.ID
+		if !scopes[e.Scope] {
+			return nil
+		}
+		return writeEvent(w, e)
+	}
+	if lastID != "" {
+		for _, e := range c.eventsSince(sent) {
+			if send(e) != nil {
+				return
+			}
+		}
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(keepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			if send(e) != nil {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+		}
+		flusher.Flush()
+	}
+}
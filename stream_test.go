@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+//readEvent returns the next event of a Server-Sent Events stream without the trailing empty line
+func readEvent(reader *bufio.Reader) []string {
+	result := make([]string, 0)
+	for {
+		line, err := reader.ReadString('\n')
+		line = strings.TrimSuffix(line, "\n")
+		if err != nil || line == "" {
+			return result
+		}
+		result = append(result, line)
+	}
+}
+
+func TestStream(t *testing.T) {
+	c := newCollector(nil)
+	c.update(testSnapshot(1500, 1820))
+	c.update(testSnapshot(1520, 1840))
+	ts := httptest.NewServer(http.HandlerFunc(c.handleStream))
+	defer ts.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	request, _ := http.NewRequestWithContext(ctx, "GET", ts.URL+"?scope=bundesland", nil)
+	request.Header.Set("Last-Event-ID", "0")
+	response, err := ts.Client().Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, "text/event-stream", response.Header.Get("Content-Type"))
+	reader := bufio.NewReader(response.Body)
+	assert.Equal(t, []string{"retry: 10000"}, readEvent(reader))
+
+	//the missed bundesland event is sent first, the total event is filtered
+	event := readEvent(reader)
+	assert.Equal(t, "id: 2", event[0])
+	assert.Equal(t, "event: bundesland", event[1])
+	assert.Equal(t, `data: {"id":2,"scope":"bundesland","time":"2020-03-30T08:00:00Z","regions":[{"region":"Wien","changes":{"infected":{"previous":1500,"current":1520,"delta":20}}}]}`, event[2])
+
+	c.update(testSnapshot(1530, 1840))
+	event = readEvent(reader)
+	assert.Equal(t, "id: 3", event[0])
+}
+
+func TestStreamScopes(t *testing.T) {
+	c := newCollector(nil)
+	ts := httptest.NewServer(http.HandlerFunc(c.handleStream))
+	defer ts.Close()
+	response, err := ts.Client().Get(ts.URL + "?scope=total,hospital")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusBadRequest, response.StatusCode)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+)
+
+//mapColors are the fill colors of a choropleth from the lowest to the highest value
+var mapColors = []string{"#37872D", "#F2CC0C", "#FA6400", "#E02F44", "#C4162A"}
+
+//mapNoData is the fill color of regions without a value
+const mapNoData = "#cccccc"
+
+//shape is a drawing primitive of a canvas
+type shape struct {
+	//kind is path, polyline, rect or text
+	kind string
+	//rings are the closed rings of a path, holes are cut out with the evenodd fill rule, a polyline has one open ring
+	rings  [][][2]float64
+	fill   string
+	stroke string
+	width  float64
+	text   string
+	size   float64
+	anchor string
+	//title is shown as tooltip in svgs
+	title string
+}
+
+//canvas collects shapes and renders them as svg or png
+type canvas struct {
+	class  string
+	width  float64
+	height float64
+	shapes []shape
+}
+
+func newCanvas(class string, width float64, height float64) *canvas {
+	return &canvas{class: class, width: width, height: height}
+}
+
+func (c *canvas) path(rings [][][2]float64, fill string, stroke string, title string) {
+	c.shapes = append(c.shapes, shape{kind: "path", rings: rings, fill: fill, stroke: stroke, width: 1, title: title})
+}
+
+func (c *canvas) polyline(points [][2]float64, stroke string, width float64) {
+	c.shapes = append(c.shapes, shape{kind: "polyline", rings: [][][2]float64{points}, stroke: stroke, width: width})
+}
+
+func (c *canvas) rect(x float64, y float64, width float64, height float64, fill string) {
+	c.shapes = append(c.shapes, shape{kind: "rect", rings: [][][2]float64{{{x, y}, {x + width, y}, {x + width, y + height}, {x, y + height}}}, fill: fill})
+}
+
+//text draws s with its baseline at y, anchor is start, middle or end
+func (c *canvas) text(x float64, y float64, size float64, anchor string, s string) {
+	c.shapes = append(c.shapes, shape{kind: "text", rings: [][][2]float64{{{x, y}}}, size: size, anchor: anchor, text: s})
+}
+
+func (c *canvas) svg() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" class="%s" width="%.0f" height="%.0f" viewBox="0 0 %.0f %.0f">`, c.class, c.width, c.height, c.width, c.height)
+	for _, s := range c.shapes {
+		switch s.kind {
+		case "path":
+			var d strings.Builder
+			for _, ring := range s.rings {
+				for i, point := range ring {
+					command := "L"
+					if i == 0 {
+						command = "M"
+					}
+					fmt.Fprintf(&d, "%s%.1f,%.1f", command, point[0], point[1])
+				}
+				d.WriteString("Z")
+			}
+			fmt.Fprintf(&b, `<path d="%s" fill="%s" fill-rule="evenodd" stroke="%s" stroke-width="%g"><title>%s</title></path>`, d.String(), s.fill, s.stroke, s.width, html.EscapeString(s.title))
+		case "polyline":
+			points := make([]string, 0, len(s.rings[0]))
+			for _, point := range s.rings[0] {
+				points = append(points, fmt.Sprintf("%.1f,%.1f", point[0], point[1]))
+			}
+			fmt.Fprintf(&b, `<polyline points="%s" fill="none" stroke="%s" stroke-width="%g"/>`, strings.Join(points, " "), s.stroke, s.width)
+		case "rect":
+			corners := s.rings[0]
+			fmt.Fprintf(&b, `<rect x="%.0f" y="%.0f" width="%.0f" height="%.0f" fill="%s"/>`, corners[0][0], corners[0][1], corners[2][0]-corners[0][0], corners[2][1]-corners[0][1], s.fill)
+		case "text":
+			anchor := ""
+			if s.anchor != "start" {
+				anchor = fmt.Sprintf(` text-anchor="%s"`, s.anchor)
+			}
+			fmt.Fprintf(&b, `<text x="%.0f" y="%.0f" font-size="%g" font-family="sans-serif"%s>%s</text>`, s.rings[0][0][0], s.rings[0][0][1], s.size, anchor, html.EscapeString(s.text))
+		}
+	}
+	b.WriteString("</svg>")
+	return b.String()
+}
+
+//mapRegion is a region of a choropleth with the value it is colored by
+type mapRegion struct {
+	Name  string
+	Value float64
+}
+
+//projection maps longitude and latitude to svg coordinates, scaled to the bounding box of the polygons
+type projection struct {
+	minLong float64
+	maxLat  float64
+	//scaleX shrinks the longitude by the cosine of the latitude so the map is not stretched
+	scaleX float64
+	scaleY float64
+	width  float64
+	height float64
+}
+
+func newProjection(polygons []polygon, width float64) projection {
+	minLong, maxLong, minLat, maxLat := math.Inf(1), math.Inf(-1), math.Inf(1), math.Inf(-1)
+	for _, p := range polygons {
+		for _, ring := range p {
+			for _, point := range ring {
+				minLong, maxLong = math.Min(minLong, point[0]), math.Max(maxLong, point[0])
+				minLat, maxLat = math.Min(minLat, point[1]), math.Max(maxLat, point[1])
+			}
+		}
+	}
+	if len(polygons) == 0 || maxLong <= minLong || maxLat <= minLat {
+		return projection{scaleX: 1, scaleY: 1, width: width, height: width}
+	}
+	stretch := math.Cos((minLat + maxLat) / 2 * math.Pi / 180)
+	scale := width / ((maxLong - minLong) * stretch)
+	return projection{
+		minLong: minLong,
+		maxLat:  maxLat,
+		scaleX:  scale * stretch,
+		scaleY:  scale,
+		width:   width,
+		height:  (maxLat - minLat) * scale,
+	}
+}
+
+func (p projection) point(long float64, lat float64) (float64, float64) {
+	return (long - p.minLong) * p.scaleX, (p.maxLat - lat) * p.scaleY
+}
+
+//rings projects all rings of the polygons
+func (p projection) rings(polygons []polygon) [][][2]float64 {
+	result := make([][][2]float64, 0)
+	for _, poly := range polygons {
+		for _, ring := range poly {
+			projected := make([][2]float64, 0, len(ring))
+			for _, point := range ring {
+				x, y := p.point(point[0], point[1])
+				projected = append(projected, [2]float64{x, y})
+			}
+			result = append(result, projected)
+		}
+	}
+	return result
+}
+
+//mapColor returns the color of value in the range 0 to max
+func mapColor(value float64, max float64) string {
+	if max <= 0 {
+		return mapColors[0]
+	}
+	i := int(value / max * float64(len(mapColors)))
+	if i >= len(mapColors) {
+		i = len(mapColors) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	return mapColors[i]
+}
+
+//choropleth draws the boundaries of the regions colored by their value with a legend below the map.
+//Regions without a boundary are skipped, boundaries without a region are drawn gray.
+func choropleth(b *boundaryProvider, regions []mapRegion, width float64, label string) *canvas {
+	names := make([]string, 0, len(b.data))
+	for name := range b.data {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	shapes := make(map[string][]polygon)
+	all := make([]polygon, 0)
+	for _, name := range names {
+		polygons, err := b.data[name].polygons()
+		if err != nil {
+			continue
+		}
+		shapes[name] = polygons
+		all = append(all, polygons...)
+	}
+	p := newProjection(all, width)
+
+	max := 0.0
+	values := make(map[string]mapRegion)
+	for _, r := range regions {
+		values[normalizeName(r.Name)] = r
+		max = math.Max(max, r.Value)
+	}
+
+	legendHeight := 30.0
+	result := newCanvas("map", p.width, p.height+legendHeight)
+	for _, name := range names {
+		fill, title := mapNoData, b.names[name]+": no data"
+		if r, ok := values[name]; ok {
+			fill, title = mapColor(r.Value, max), fmt.Sprintf("%s: %.1f", r.Name, r.Value)
+		}
+		result.path(p.rings(shapes[name]), fill, "#ffffff", title)
+	}
+	step := max / float64(len(mapColors))
+	for i, color := range mapColors {
+		x := float64(i) * p.width / float64(len(mapColors))
+		result.rect(x, p.height+10, 12, 12, color)
+		result.text(x+16, p.height+20, 11, "start", fmt.Sprintf("%.0f–%.0f", float64(i)*step, float64(i+1)*step))
+	}
+	result.text(p.width, 12, 11, "end", label)
+	return result
+}
+
+//sparkline draws values as a small line without axes, nil for less than two values
+func sparkline(values []float64, width float64, height float64) *canvas {
+	if len(values) < 2 {
+		return nil
+	}
+	min, max := values[0], values[0]
+	for _, v := range values {
+		min, max = math.Min(min, v), math.Max(max, v)
+	}
+	points := make([][2]float64, 0, len(values))
+	for i, v := range values {
+		y := height / 2
+		if max > min {
+			y = height - 1 - (v-min)/(max-min)*(height-2)
+		}
+		points = append(points, [2]float64{float64(i) * width / float64(len(values)-1), y})
+	}
+	result := newCanvas("sparkline", width, height)
+	result.polyline(points, "#E02F44", 1.5)
+	return result
+}
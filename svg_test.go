@@ -0,0 +1,37 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSparkline(t *testing.T) {
+	assert.Nil(t, sparkline([]float64{1}, 100, 20))
+	assert.Contains(t, sparkline([]float64{0, 5, 10}, 100, 20).svg(), `points="0.0,19.0 50.0,10.0 100.0,1.0"`)
+	assert.Contains(t, sparkline([]float64{3, 3}, 100, 20).svg(), `points="0.0,10.0 100.0,10.0"`)
+}
+
+func TestChoropleth(t *testing.T) {
+	assert.Equal(t, "#37872D", mapColor(0, 100))
+	assert.Equal(t, "#FA6400", mapColor(50, 100))
+	assert.Equal(t, "#C4162A", mapColor(100, 100))
+	assert.Equal(t, "#37872D", mapColor(5, 0))
+
+	svg := choropleth(newBoundaryProvider(), []mapRegion{{"Wien", 100}, {"Tirol", 10}, {"Atlantis", 5}}, 600, "Infected per 100k").svg()
+	assert.True(t, strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg" class="map" width="600"`))
+	assert.Equal(t, 9, strings.Count(svg, "<path "))
+	assert.Contains(t, svg, `fill="#C4162A" fill-rule="evenodd" stroke="#ffffff" stroke-width="1"><title>Wien: 100.0</title>`)
+	assert.Contains(t, svg, `fill="#37872D" fill-rule="evenodd" stroke="#ffffff" stroke-width="1"><title>Tirol: 10.0</title>`)
+	assert.Contains(t, svg, `fill="#cccccc" fill-rule="evenodd" stroke="#ffffff" stroke-width="1"><title>Kärnten: no data</title>`)
+	assert.NotContains(t, svg, "Atlantis")
+	assert.Contains(t, svg, "80–100")
+
+	p := newProjection([]polygon{{{{9, 46}, {17, 46}, {17, 49}, {9, 49}}}}, 600)
+	x, y := p.point(9, 49)
+	assert.Equal(t, 0.0, x)
+	assert.Equal(t, 0.0, y)
+	x, _ = p.point(17, 46)
+	assert.InDelta(t, 600, x, 0.001)
+}
@@ -0,0 +1,198 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+)
+
+//upstreamResource is the last response of an upstream url
+type upstreamResource struct {
+	source       string
+	url          string
+	etag         string
+	lastModified string
+	hash         string
+	body         []byte
+	//changed is the time the content of the url was first seen
+	changed time.Time
+	//parsed is the result of parsing body, it is reused until the content changes
+	parsed interface{}
+}
+
+//upstreamChange is an upstream url that published new content
+type upstreamChange struct {
+	Source  string    `json:"source"`
+	URL     string    `json:"url"`
+	Changed time.Time `json:"changed"`
+}
+
+//upstreamClient reads the upstream sources of all exporters. It remembers the ETag and Last-Modified of every url
+//for conditional requests and keeps the parsed content as long as the source does not publish new data.
+type upstreamClient struct {
+	lock    sync.Mutex
+	fetcher *fetcher
+	//resources are stored by source and url, so sources sharing a url can parse it differently
+	resources map[[2]string]*upstreamResource
+	now       func() time.Time
+}
+
+var upstream = newUpstreamClient()
+
+func newUpstreamClient() *upstreamClient {
+	return &upstreamClient{
+		fetcher:   newFetcher(defaultConfig().Upstream),
+		resources: make(map[[2]string]*upstreamResource),
+		now:       time.Now,
+	}
+}
+
+//get returns the current content of url and whether it changed since the last call
+func (u *upstreamClient) get(source string, url string) (*upstreamResource, bool, error) {
+	request, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	key := [2]string{source, url}
+	u.lock.Lock()
+	known, ok := u.resources[key]
+	if ok {
+		if known.etag != "" {
+			request.Header.Set("If-None-Match", known.etag)
+		}
+		if known.lastModified != "" {
+			request.Header.Set("If-Modified-Since", known.lastModified)
+		}
+	}
+	u.lock.Unlock()
+
+	response, err := u.fetcher.do(request)
+	if err != nil {
+		return nil, false, err
+	}
+	defer response.Body.Close()
+	if response.StatusCode == http.StatusNotModified && ok {
+		return known, false, nil
+	}
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		return nil, false, fmt.Errorf("%s returned %s", url, response.Status)
+	}
+	body, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, false, err
+	}
+
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	hash := hashBytes(body)
+	if ok && known.hash == hash {
+		known.etag = response.Header.Get("ETag")
+		known.lastModified = response.Header.Get("Last-Modified")
+		return known, false, nil
+	}
+	resource := &upstreamResource{
+		source:       source,
+		url:          url,
+		etag:         response.Header.Get("ETag"),
+		lastModified: response.Header.Get("Last-Modified"),
+		hash:         hash,
+		body:         body,
+		changed:      u.now(),
+	}
+	u.resources[key] = resource
+	return resource, true, nil
+}
+
+//fetch returns the content of url
+func (u *upstreamClient) fetch(source string, url string) ([]byte, error) {
+	resource, _, err := u.get(source, url)
+	if err != nil {
+		return nil, err
+	}
+	return resource.body, nil
+}
+
+//parse returns the content of url converted by f, f is only called when the content changed.
+//The result is shared between callers and must not be modified.
+func (u *upstreamClient) parse(source string, url string, f func([]byte) (interface{}, error)) (interface{}, error) {
+	resource, changed, err := u.get(source, url)
+	if err != nil {
+		return nil, err
+	}
+	u.lock.Lock()
+	parsed := resource.parsed
+	u.lock.Unlock()
+	if !changed && parsed != nil {
+		return parsed, nil
+	}
+	parsed, err = f(resource.body)
+	if err != nil {
+		return nil, err
+	}
+	u.lock.Lock()
+	resource.parsed = parsed
+	u.lock.Unlock()
+	return parsed, nil
+}
+
+//changedSince returns the urls whose content changed after t, sorted by source and url
+func (u *upstreamClient) changedSince(t time.Time) []upstreamChange {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	result := make([]upstreamChange, 0)
+	for _, r := range u.resources {
+		if r.changed.After(t) {
+			result = append(result, upstreamChange{r.source, r.url, r.changed})
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Source+result[i].URL < result[j].Source+result[j].URL
+	})
+	return result
+}
+
+//lastChanged returns the latest Last-Modified of the upstream urls, or the time new content was seen
+//for urls without the header. It is zero before the first response.
+func (u *upstreamClient) lastChanged() time.Time {
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	result := time.Time{}
+	for _, r := range u.resources {
+		changed, err := http.ParseTime(r.lastModified)
+		if err != nil {
+			changed = r.changed
+		}
+		if changed.After(result) {
+			result = changed
+		}
+	}
+	return result
+}
+
+//GetMetrics returns the time every upstream url last published new content and the request metrics per host
+func (u *upstreamClient) GetMetrics() (metrics, error) {
+	requests, _ := u.fetcher.GetMetrics()
+	u.lock.Lock()
+	defer u.lock.Unlock()
+	resources := make([]*upstreamResource, 0, len(u.resources))
+	for _, r := range u.resources {
+		resources = append(resources, r)
+	}
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].source+resources[i].url < resources[j].source+resources[j].url
+	})
+	result := make(metrics, 0, len(resources)+len(requests))
+	for _, r := range resources {
+		tags := map[string]string{"source": r.source, "url": r.url}
+		result = append(result, metric{Name: "cov19_upstream_last_changed_timestamp_seconds", Value: float64(r.changed.Unix()), Tags: &tags})
+	}
+	return append(result, requests...), nil
+}
+
+//Health is always fine, failing sources are reported by the exporters reading them
+func (u *upstreamClient) Health() []error {
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpstreamConditionalRequests(t *testing.T) {
+	body := "var Erkrankungen = 1820;"
+	requests, notModified := 0, 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		etag := `"` + hashBytes([]byte(body)) + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			notModified++
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", etag)
+		w.Write([]byte(body))
+	}))
+	defer ts.Close()
+
+	u := newUpstreamClient()
+	u.fetcher.configure(testUpstreamConfig())
+	now := time.Unix(1585555200, 0)
+	u.now = func() time.Time { return now }
+	parses := 0
+	parse := func(b []byte) (interface{}, error) {
+		parses++
+		return string(b), nil
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := u.parse("health_ministry", ts.URL, parse)
+		assert.Nil(t, err)
+		assert.Equal(t, body, result)
+	}
+	assert.Equal(t, 3, requests)
+	assert.Equal(t, 2, notModified)
+	assert.Equal(t, 1, parses)
+
+	now = now.Add(time.Hour)
+	body = "var Erkrankungen = 2000;"
+	result, err := u.parse("health_ministry", ts.URL, parse)
+	assert.Nil(t, err)
+	assert.Equal(t, body, result)
+	assert.Equal(t, 2, parses)
+
+	metrics, _ := u.GetMetrics()
+	changed := metrics.findMetric("cov19_upstream_last_changed_timestamp_seconds", "source=health_ministry")
+	if assert.NotNil(t, changed) {
+		assert.Equal(t, map[string]string{"source": "health_ministry", "url": ts.URL}, *changed.Tags)
+		assert.Equal(t, float64(1585558800), changed.Value)
+	}
+	assert.NotNil(t, metrics.findMetric("cov19_upstream_requests_total", "status=304"))
+
+	assert.Equal(t, []upstreamChange{{"health_ministry", ts.URL, now}}, u.changedSince(now.Add(-time.Minute)))
+	assert.Equal(t, 0, len(u.changedSince(now)))
+	assert.Equal(t, now, u.lastChanged(), "without Last-Modified the time new content was seen")
+}
+
+func TestUpstreamUnchangedBody(t *testing.T) {
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/missing" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte("<html></html>"))
+	}))
+	defer ts.Close()
+
+	u := newUpstreamClient()
+	u.fetcher.configure(testUpstreamConfig())
+	_, changed, err := u.get("ecdc", ts.URL)
+	assert.Nil(t, err)
+	assert.True(t, changed)
+	_, changed, err = u.get("ecdc", ts.URL)
+	assert.Nil(t, err)
+	assert.False(t, changed)
+
+	_, err = u.fetch("ecdc", ts.URL+"/missing")
+	assert.EqualError(t, err, ts.URL+"/missing returned 404 Not Found")
+}
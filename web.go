@@ -0,0 +1,188 @@
+package main
+
+import (
+	"embed"
+	htmltemplate "html/template"
+	"net/http"
+	"strings"
+	"time"
+)
+
+//go:embed templates/index.html
+var webTemplates embed.FS
+
+//webRow is a Bundesland or Bezirk in the tables of the web dashboard
+type webRow struct {
+	Name            string
+	Province        string
+	Infected        uint64
+	NewCases        int64
+	InfectedPer100k float64
+	Dead            uint64
+	Hospitalized    uint64
+	IntensiveCare   uint64
+	//Trend is a sparkline of the daily new cases
+	Trend htmltemplate.HTML
+}
+
+type webTotal struct {
+	reportTotal
+	//Trend is a sparkline of the daily increases of cumulative totals and of the values of the others
+	Trend htmltemplate.HTML
+}
+
+//webPage is rendered by templates/index.html
+type webPage struct {
+	Time       time.Time
+	Since      time.Time
+	Totals     []webTotal
+	Bundesland []webRow
+	Bezirk     []webRow
+	Map        htmltemplate.HTML
+}
+
+//seriesOf returns the value of every snapshot that contains the region
+func seriesOf(snapshots []*snapshot, value func(s *snapshot) (float64, bool)) []float64 {
+	result := make([]float64, 0, len(snapshots))
+	for _, s := range snapshots {
+		if v, ok := value(s); ok {
+			result = append(result, v)
+		}
+	}
+	return result
+}
+
+//increases returns the differences between consecutive values
+func increases(values []float64) []float64 {
+	result := make([]float64, 0, len(values))
+	for i := 1; i < len(values); i++ {
+		result = append(result, values[i]-values[i-1])
+	}
+	return result
+}
+
+func trend(values []float64) htmltemplate.HTML {
+	line := sparkline(values, 100, 20)
+	if line == nil {
+		return ""
+	}
+	return htmltemplate.HTML(line.svg())
+}
+
+func bundeslandValue(name string) func(s *snapshot) (float64, bool) {
+	return func(s *snapshot) (float64, bool) {
+		for _, b := range s.Bundesland {
+			if b.Name == name {
+				return float64(b.Infected), true
+			}
+		}
+		return 0, false
+	}
+}
+
+func bezirkValue(name string) func(s *snapshot) (float64, bool) {
+	return func(s *snapshot) (float64, bool) {
+		for _, b := range s.Bezirk {
+			if b.Name == name {
+				return float64(b.Infected), true
+			}
+		}
+		return 0, false
+	}
+}
+
+//newWebPage shows current with the changes since the daily snapshot and trends of the history, which ends with current
+func newWebPage(current *snapshot, daily *snapshot, history []*snapshot, boundaries *boundaryProvider) *webPage {
+	report := newDailyReport(current, daily)
+	result := &webPage{Time: report.Time, Since: report.Since}
+
+	totals := []func(s *snapshot) (float64, bool){
+		func(s *snapshot) (float64, bool) { return float64(s.Total.TotalInfected), true },
+		func(s *snapshot) (float64, bool) { return float64(s.Total.TotalDead), true },
+		func(s *snapshot) (float64, bool) { return float64(s.Total.TotalHospitalized), true },
+		func(s *snapshot) (float64, bool) { return float64(s.Total.TotalIntensiveCare), true },
+	}
+	for i, t := range report.Totals {
+		values := seriesOf(history, totals[i])
+		//infected and dead are cumulative, hospitalized and intensive care are the current patients
+		if i < 2 {
+			values = increases(values)
+		}
+		result.Totals = append(result.Totals, webTotal{t, trend(values)})
+	}
+
+	regions := make([]mapRegion, 0, len(report.Bundesland))
+	for _, b := range report.Bundesland {
+		values := seriesOf(history, bundeslandValue(b.Name))
+		result.Bundesland = append(result.Bundesland, webRow{
+			Name:            b.Name,
+			Infected:        b.Infected,
+			NewCases:        b.NewCases,
+			InfectedPer100k: b.InfectedPer100k,
+			Dead:            b.Dead,
+			Hospitalized:    b.Hospitalized,
+			IntensiveCare:   b.IntensiveCare,
+			Trend:           trend(increases(values)),
+		})
+		regions = append(regions, mapRegion{b.Name, b.InfectedPer100k})
+	}
+	result.Map = htmltemplate.HTML(choropleth(boundaries, regions, 600, "Infected per 100k").svg())
+
+	previous := make(map[string]uint64)
+	if daily != nil {
+		for _, b := range daily.Bezirk {
+			previous[b.Name] = b.Infected
+		}
+	}
+	for _, b := range current.Bezirk {
+		p, ok := previous[b.Name]
+		if !ok {
+			p = b.Infected
+		}
+		result.Bezirk = append(result.Bezirk, webRow{
+			Name:            b.Name,
+			Province:        b.Province,
+			Infected:        b.Infected,
+			NewCases:        difference(b.Infected, p),
+			InfectedPer100k: b.InfectedPer100k,
+			Trend:           trend(increases(seriesOf(history, bezirkValue(b.Name)))),
+		})
+	}
+	return result
+}
+
+//onlyRoot serves / with h, other paths end up in the catch all route and are not found
+func onlyRoot(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		h(w, r)
+	}
+}
+
+//handleIndex renders the web dashboard of the collected snapshots
+func (c *collector) handleIndex(w http.ResponseWriter, r *http.Request) {
+	current, daily := c.dailySnapshots()
+	if current == nil {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte("No data collected yet"))
+		return
+	}
+	t, err := htmltemplate.New("index.html").Funcs(reportFuncs).ParseFS(webTemplates, "templates/index.html")
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	var body strings.Builder
+	err = t.Execute(&body, newWebPage(current, daily, c.dailyHistory(), c.api.boundaries))
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		w.Write([]byte(err.Error()))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(body.String()))
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWebPage(t *testing.T) {
+	days := make([]*snapshot, 0)
+	for i, infected := range []uint64{1400, 1450, 1500, 1520} {
+		s := testSnapshot(infected, infected+320)
+		s.Time = s.Time.Add(time.Duration(i) * 24 * time.Hour)
+		s.Bundesland[1].InfectedPer100k = float64(infected) * 100000 / float64(s.Bundesland[1].Population)
+		s.Bezirk = []bezirkStat{{Name: "Wien  1. Innere Stadt", Province: "Wien", Infected: infected / 10}}
+		days = append(days, s)
+	}
+	current, daily := days[3], days[2]
+	page := newWebPage(current, daily, days, newBoundaryProvider())
+
+	assert.Equal(t, current.Time, page.Time)
+	assert.Equal(t, daily.Time, page.Since)
+	assert.Equal(t, "Infected", page.Totals[0].Label)
+	assert.Equal(t, int64(20), page.Totals[0].Change)
+	assert.Contains(t, string(page.Totals[0].Trend), `<polyline points="0.0,1.0 50.0,1.0 100.0,19.0"`)
+
+	assert.Equal(t, "Wien", page.Bundesland[1].Name)
+	assert.Equal(t, int64(20), page.Bundesland[1].NewCases)
+	assert.Contains(t, string(page.Bundesland[1].Trend), "<svg")
+	assert.Contains(t, string(page.Bundesland[0].Trend), `points="0.0,10.0 50.0,10.0 100.0,10.0"`, "Burgenland did not change")
+
+	assert.Equal(t, []webRow{{Name: "Wien  1. Innere Stadt", Province: "Wien", Infected: 152, NewCases: 2, Trend: page.Bezirk[0].Trend}}, page.Bezirk)
+	assert.Contains(t, string(page.Map), "<title>Wien: 80.5</title>")
+	assert.Contains(t, string(page.Map), "no data")
+}
+
+func TestWebIndexHandler(t *testing.T) {
+	c := newCollector(&api{boundaries: newBoundaryProvider()})
+	ts := httptest.NewServer(onlyRoot(c.handleIndex))
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusServiceUnavailable, response.StatusCode)
+
+	c.update(testSnapshot(1500, 1820))
+	response, err = ts.Client().Get(ts.URL)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, "text/html; charset=utf-8", response.Header.Get("Content-Type"))
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Contains(t, string(body), "<td>Wien</td><td class=\"number\">1500</td>")
+	assert.Contains(t, string(body), `<svg xmlns="http://www.w3.org/2000/svg" class="map"`)
+	assert.Contains(t, string(body), `<table class="sortable">`)
+
+	response, err = ts.Client().Get(ts.URL + "/wp-login.php")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}
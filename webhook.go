@@ -0,0 +1,440 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+type webhookConfig struct {
+	//URL receives the events as POST requests with a json body
+	URL string `json:"url"`
+	//Secret signs the body, the signature is sent as X-Covid19-Signature: sha256=<hex hmac>
+	Secret string `json:"secret"`
+	//Scopes limits the events to total, bundesland, bezirk or world, all scopes if empty
+	Scopes []string `json:"scopes"`
+	//Regions limits the events to some provinces, districts or countries, all regions if empty
+	Regions []string `json:"regions"`
+	//Metric and Threshold turn the webhook into a threshold webhook, it is only called when
+	//the metric of a region crosses the threshold instead of on every update
+	Metric    string  `json:"metric"`
+	Threshold float64 `json:"threshold"`
+	//Function and WindowDays are applied to the metric like in alert rules,
+	//e.g. increase_per_100k of infected over 7 days is the 7 day incidence
+	Function   string `json:"function"`
+	WindowDays int    `json:"window_days"`
+}
+
+func (w webhookConfig) validate() error {
+	if !strings.HasPrefix(w.URL, "http://") && !strings.HasPrefix(w.URL, "https://") {
+		return fmt.Errorf("Invalid url %q", w.URL)
+	}
+	for _, scope := range w.Scopes {
+		known := false
+		for _, s := range eventScopes {
+			known = known || s == scope
+		}
+		if !known {
+			return fmt.Errorf("Unknown scope %q", scope)
+		}
+	}
+	if w.Metric == "" && (w.Threshold != 0 || w.Function != "") {
+		return fmt.Errorf("A threshold needs a metric")
+	}
+	if !contains(alertFunctions, w.Function) {
+		return fmt.Errorf("Unknown function %q, use increase, increase_per_100k or change_ratio", w.Function)
+	}
+	if w.WindowDays < 0 {
+		return fmt.Errorf("window_days can not be negative")
+	}
+	return nil
+}
+
+func (w webhookConfig) matchesScope(scope string) bool {
+	if len(w.Scopes) == 0 {
+		return true
+	}
+	for _, s := range w.Scopes {
+		if s == scope {
+			return true
+		}
+	}
+	return false
+}
+
+//thresholdCrossing is a metric of a region that went above or below the threshold of a webhook
+type thresholdCrossing struct {
+	Scope     string  `json:"scope"`
+	Region    string  `json:"region"`
+	Metric    string  `json:"metric"`
+	Threshold float64 `json:"threshold"`
+	Previous  float64 `json:"previous"`
+	Current   float64 `json:"current"`
+	//Direction is above or below
+	Direction string `json:"direction"`
+}
+
+//webhookPayload is the body of a webhook request
+type webhookPayload struct {
+	//Type is update, threshold or ping
+	Type      string             `json:"type"`
+	Time      time.Time          `json:"time"`
+	Update    *updateEvent       `json:"update,omitempty"`
+	Threshold *thresholdCrossing `json:"threshold,omitempty"`
+}
+
+//crossing returns above or below if the value crossed the threshold, an empty string otherwise
+func (w webhookConfig) crossing(previous float64, current float64) string {
+	if previous < w.Threshold && current >= w.Threshold {
+		return "above"
+	} else if previous >= w.Threshold && current < w.Threshold {
+		return "below"
+	}
+	return ""
+}
+
+//values returns the function of the metric for the regions of the scope in the current and previous snapshot
+func (w webhookConfig) values(scope string, current *snapshot, previous *snapshot, at func(time.Time) *snapshot) (map[string]float64, map[string]float64) {
+	rule := alertRule{Scope: scope, Regions: w.Regions, Metric: w.Metric, Function: w.Function, WindowDays: w.WindowDays}
+	if current == nil || previous == nil || at == nil {
+		return map[string]float64{}, map[string]float64{}
+	}
+	return rule.values(current, at, nil, current.Time), rule.values(previous, at, nil, previous.Time)
+}
+
+//payloads returns the requests an event causes for the webhook, current and previous are the snapshots
+//the event was computed from and at returns the daily snapshots for the function of threshold webhooks
+func (w webhookConfig) payloads(e updateEvent, current *snapshot, previous *snapshot, at func(time.Time) *snapshot) []webhookPayload {
+	result := make([]webhookPayload, 0)
+	if !w.matchesScope(e.Scope) {
+		return result
+	}
+	regions := make([]regionChange, 0)
+	for _, r := range e.Regions {
+		if matchesRegion(w.Regions, r.Region) {
+			regions = append(regions, r)
+		}
+	}
+	if w.Metric == "" {
+		if len(regions) > 0 {
+			update := e
+			update.Regions = regions
+			result = append(result, webhookPayload{Type: "update", Time: e.Time, Update: &update})
+		}
+		return result
+	}
+	currentValues, previousValues := w.values(e.Scope, current, previous, at)
+	for _, r := range regions {
+		crossing := thresholdCrossing{Scope: e.Scope, Region: r.Region, Metric: alertRule{Metric: w.Metric, Function: w.Function, WindowDays: w.WindowDays}.expression(), Threshold: w.Threshold}
+		if w.Function == "" {
+			change, ok := r.Changes[w.Metric]
+			if !ok {
+				continue
+			}
+			crossing.Previous, crossing.Current = change.Previous, change.Current
+		} else {
+			var ok, known bool
+			crossing.Current, ok = currentValues[r.Region]
+			crossing.Previous, known = previousValues[r.Region]
+			if !ok || !known {
+				continue
+			}
+		}
+		crossing.Direction = w.crossing(crossing.Previous, crossing.Current)
+		if crossing.Direction != "" {
+			result = append(result, webhookPayload{Type: "threshold", Time: e.Time, Threshold: &crossing})
+		}
+	}
+	return result
+}
+
+//webhookDelivery is an entry of the delivery log
+type webhookDelivery struct {
+	ID       uint64    `json:"id"`
+	URL      string    `json:"url"`
+	Type     string    `json:"type"`
+	Time     time.Time `json:"time"`
+	Attempts int       `json:"attempts"`
+	Status   int       `json:"status"`
+	Error    string    `json:"error,omitempty"`
+	Success  bool      `json:"success"`
+}
+
+//maxDeliveries is the number of deliveries kept in the log
+const maxDeliveries = 200
+
+//maxQueuedDeliveries is the number of deliveries waiting for a slow webhook before new ones are dropped
+const maxQueuedDeliveries = 100
+
+//webhookJob is a payload waiting for its delivery
+type webhookJob struct {
+	hook    webhookConfig
+	payload webhookPayload
+}
+
+//webhookDispatcher sends the events of the collector to the configured webhooks
+type webhookDispatcher struct {
+	lock       sync.Mutex
+	hooks      []webhookConfig
+	client     *http.Client
+	retries    int
+	backoff    time.Duration
+	deliveries []webhookDelivery
+	//counts are the deliveries since the start per url and result, they are not limited like the log
+	counts map[[2]string]uint64
+	//queues deliver the payloads of every url in order, without waiting for the other urls
+	queues map[string]chan webhookJob
+	nextID uint64
+	now    func() time.Time
+	sleep  func(time.Duration)
+}
+
+func newWebhookDispatcher() *webhookDispatcher {
+	return &webhookDispatcher{
+		client:  &http.Client{Timeout: 10 * time.Second},
+		retries: 3,
+		backoff: time.Second,
+		counts:  make(map[[2]string]uint64),
+		queues:  make(map[string]chan webhookJob),
+		nextID:  1,
+		now:     time.Now,
+		sleep:   time.Sleep,
+	}
+}
+
+//configure replaces the webhooks, the queues of removed urls are stopped after their pending deliveries
+func (d *webhookDispatcher) configure(hooks []webhookConfig) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	d.hooks = hooks
+	for url, queue := range d.queues {
+		configured := false
+		for _, hook := range hooks {
+			configured = configured || hook.URL == url
+		}
+		if !configured {
+			close(queue)
+			delete(d.queues, url)
+		}
+	}
+}
+
+//run queues the events of the collector for the webhooks until the subscription is closed
+func (d *webhookDispatcher) run(c *collector) {
+	for e := range c.subscribe() {
+		current, previous := c.snapshots()
+		d.enqueue(e, current, previous, c.snapshotAt)
+	}
+}
+
+//enqueue adds the payloads of the event to the queues of the webhooks, a payload is dropped and
+//logged as failed delivery if the queue of its webhook is full
+func (d *webhookDispatcher) enqueue(e updateEvent, current *snapshot, previous *snapshot, at func(time.Time) *snapshot) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	for _, hook := range d.hooks {
+		for _, payload := range hook.payloads(e, current, previous, at) {
+			queue, ok := d.queues[hook.URL]
+			if !ok {
+				queue = make(chan webhookJob, maxQueuedDeliveries)
+				d.queues[hook.URL] = queue
+				go d.work(queue)
+			}
+			select {
+			case queue <- webhookJob{hook, payload}:
+			default:
+				delivery := webhookDelivery{ID: d.nextID, URL: hook.URL, Type: payload.Type, Time: d.now(), Error: "Too many pending deliveries, dropped"}
+				d.nextID++
+				logger.Printf("Webhook %s dropped a %s event, %d deliveries are pending", hook.URL, payload.Type, len(queue))
+				d.record(delivery)
+			}
+		}
+	}
+}
+
+//work delivers the jobs of a queue until it is closed
+func (d *webhookDispatcher) work(queue chan webhookJob) {
+	for job := range queue {
+		d.deliver(job.hook, job.payload)
+	}
+}
+
+//dispatch sends an event to all matching webhooks and waits for the deliveries
+func (d *webhookDispatcher) dispatch(e updateEvent, current *snapshot, previous *snapshot, at func(time.Time) *snapshot) []webhookDelivery {
+	d.lock.Lock()
+	hooks := d.hooks
+	d.lock.Unlock()
+	result := make([]webhookDelivery, 0)
+	for _, hook := range hooks {
+		for _, payload := range hook.payloads(e, current, previous, at) {
+			result = append(result, d.deliver(hook, payload))
+		}
+	}
+	return result
+}
+
+//ping sends a test request to all webhooks
+func (d *webhookDispatcher) ping() []webhookDelivery {
+	d.lock.Lock()
+	hooks := d.hooks
+	d.lock.Unlock()
+	result := make([]webhookDelivery, 0, len(hooks))
+	for _, hook := range hooks {
+		result = append(result, d.deliver(hook, webhookPayload{Type: "ping", Time: d.now()}))
+	}
+	return result
+}
+
+func sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+//deliver posts the payload to the webhook, timeouts and 5xx responses are retried with exponential backoff
+func (d *webhookDispatcher) deliver(hook webhookConfig, payload webhookPayload) webhookDelivery {
+	d.lock.Lock()
+	delivery := webhookDelivery{ID: d.nextID, URL: hook.URL, Type: payload.Type, Time: d.now()}
+	d.nextID++
+	d.lock.Unlock()
+
+	body, err := json.Marshal(payload)
+	for attempt := 0; err == nil && attempt <= d.retries; attempt++ {
+		if attempt > 0 {
+			d.sleep(d.backoff * time.Duration(1<<uint(attempt-1)))
+		}
+		delivery.Attempts++
+		delivery.Status = 0
+		err = d.post(hook, delivery.ID, payload.Type, body, &delivery)
+		if err == nil && delivery.Status < 500 {
+			break
+		}
+		if attempt < d.retries {
+			err = nil
+		}
+	}
+	delivery.Success = err == nil && delivery.Status >= 200 && delivery.Status < 300
+	if err != nil {
+		delivery.Error = err.Error()
+	} else if !delivery.Success {
+		delivery.Error = fmt.Sprintf("%s returned %d", hook.URL, delivery.Status)
+	}
+	if !delivery.Success {
+		logger.Printf("Webhook %s failed: %s", hook.URL, delivery.Error)
+	}
+
+	d.lock.Lock()
+	d.record(delivery)
+	d.lock.Unlock()
+	return delivery
+}
+
+//record adds a delivery to the log and the counts, callers hold the lock
+func (d *webhookDispatcher) record(delivery webhookDelivery) {
+	result := "failure"
+	if delivery.Success {
+		result = "success"
+	}
+	d.counts[[2]string{delivery.URL, result}]++
+	d.deliveries = append(d.deliveries, delivery)
+	if len(d.deliveries) > maxDeliveries {
+		d.deliveries = d.deliveries[len(d.deliveries)-maxDeliveries:]
+	}
+}
+
+func (d *webhookDispatcher) post(hook webhookConfig, id uint64, eventType string, body []byte, delivery *webhookDelivery) error {
+	request, err := http.NewRequest(http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", defaultConfig().Upstream.UserAgent)
+	request.Header.Set("X-Covid19-Event", eventType)
+	request.Header.Set("X-Covid19-Delivery", strconv.FormatUint(id, 10))
+	if hook.Secret != "" {
+		request.Header.Set("X-Covid19-Signature", sign(hook.Secret, body))
+	}
+	response, err := d.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+	io.Copy(ioutil.Discard, response.Body)
+	delivery.Status = response.StatusCode
+	return nil
+}
+
+//GetMetrics counts the deliveries per url and result since the start
+func (d *webhookDispatcher) GetMetrics() (metrics, error) {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	counts := d.counts
+	keys := make([][2]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i][0]+keys[i][1] < keys[j][0]+keys[j][1] })
+	result := make(metrics, 0, len(keys))
+	for _, k := range keys {
+		tags := map[string]string{"url": k[0], "result": k[1]}
+		result = append(result, metric{Name: "cov19_webhook_deliveries_total", Value: float64(counts[k]), Tags: &tags})
+	}
+	return result, nil
+}
+
+//Health reports webhooks whose last delivery failed
+func (d *webhookDispatcher) Health() []error {
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	last := make(map[string]webhookDelivery)
+	for _, delivery := range d.deliveries {
+		last[delivery.URL] = delivery
+	}
+	result := make([]error, 0)
+	for url, delivery := range last {
+		if !delivery.Success {
+			result = append(result, fmt.Errorf("Webhook %s failed: %s", url, delivery.Error))
+		}
+	}
+	return result
+}
+
+func writeDeliveries(w http.ResponseWriter, deliveries []webhookDelivery) {
+	writeJson(w, func() (interface{}, error) { return deliveries, nil })
+}
+
+//handleDeliveries returns the delivery log, newest last
+func (d *webhookDispatcher) handleDeliveries(w http.ResponseWriter, r *http.Request) {
+	if !rl.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	d.lock.Lock()
+	deliveries := append([]webhookDelivery{}, d.deliveries...)
+	d.lock.Unlock()
+	writeDeliveries(w, deliveries)
+}
+
+//handlePing sends a ping to all webhooks and returns the deliveries
+func (d *webhookDispatcher) handlePing(w http.ResponseWriter, r *http.Request) {
+	if !rl.authorized(r) {
+		w.WriteHeader(http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return
+	}
+	writeDeliveries(w, d.ping())
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testUpdateEvent() updateEvent {
+	return updateEvent{ID: 7, Scope: "bezirk", Time: time.Unix(1585555200, 0), Regions: []regionChange{
+		{Region: "Innsbruck-Stadt", Changes: map[string]fieldChange{"infected_per_100k": {Previous: 45, Current: 52, Delta: 7}}},
+		{Region: "Wien(Stadt)", Changes: map[string]fieldChange{"infected_per_100k": {Previous: 60, Current: 70, Delta: 10}}},
+	}}
+}
+
+func TestWebhookPayloads(t *testing.T) {
+	e := testUpdateEvent()
+
+	result := webhookConfig{URL: "http://localhost"}.payloads(e, nil, nil, nil)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "update", result[0].Type)
+	assert.Equal(t, 2, len(result[0].Update.Regions))
+
+	assert.Equal(t, 0, len(webhookConfig{URL: "http://localhost", Scopes: []string{"bundesland"}}.payloads(e, nil, nil, nil)))
+
+	result = webhookConfig{URL: "http://localhost", Regions: []string{"wien (stadt)"}}.payloads(e, nil, nil, nil)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "Wien(Stadt)", result[0].Update.Regions[0].Region)
+	assert.Equal(t, 2, len(e.Regions))
+
+	result = webhookConfig{URL: "http://localhost", Metric: "infected_per_100k", Threshold: 50}.payloads(e, nil, nil, nil)
+	assert.Equal(t, []webhookPayload{{Type: "threshold", Time: e.Time, Threshold: &thresholdCrossing{
+		Scope: "bezirk", Region: "Innsbruck-Stadt", Metric: "infected_per_100k", Threshold: 50, Previous: 45, Current: 52, Direction: "above"}}}, result)
+
+	result = webhookConfig{URL: "http://localhost", Metric: "infected_per_100k", Threshold: 65}.payloads(e, nil, nil, nil)
+	assert.Equal(t, 1, len(result))
+	assert.Equal(t, "Wien(Stadt)", result[0].Threshold.Region)
+}
+
+func TestWebhookConfig(t *testing.T) {
+	assert.Nil(t, webhookConfig{URL: "https://example.com/hook", Scopes: []string{"bezirk"}}.validate())
+	assert.EqualError(t, webhookConfig{URL: "example.com"}.validate(), `Invalid url "example.com"`)
+	assert.EqualError(t, webhookConfig{URL: "http://localhost", Scopes: []string{"city"}}.validate(), `Unknown scope "city"`)
+	assert.EqualError(t, webhookConfig{URL: "http://localhost", Threshold: 50}.validate(), "A threshold needs a metric")
+}
+
+func TestWebhookDelivery(t *testing.T) {
+	requests := 0
+	received := make([]webhookPayload, 0)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 || requests < 0 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		body, _ := ioutil.ReadAll(r.Body)
+		assert.Equal(t, sign("secret", body), r.Header.Get("X-Covid19-Signature"))
+		assert.Equal(t, "update", r.Header.Get("X-Covid19-Event"))
+		assert.Equal(t, "1", r.Header.Get("X-Covid19-Delivery"))
+		payload := webhookPayload{}
+		assert.Nil(t, json.Unmarshal(body, &payload))
+		received = append(received, payload)
+	}))
+	defer ts.Close()
+
+	d := newWebhookDispatcher()
+	sleeps := make([]time.Duration, 0)
+	d.sleep = func(d time.Duration) { sleeps = append(sleeps, d) }
+	d.configure([]webhookConfig{{URL: ts.URL, Secret: "secret"}})
+	result := d.dispatch(testUpdateEvent(), nil, nil, nil)
+	assert.Equal(t, 1, len(result))
+	assert.True(t, result[0].Success)
+	assert.Equal(t, 2, result[0].Attempts)
+	assert.Equal(t, http.StatusOK, result[0].Status)
+	assert.Equal(t, []time.Duration{time.Second}, sleeps)
+	assert.Equal(t, 1, len(received))
+	assert.Equal(t, uint64(7), received[0].Update.ID)
+
+	//failed deliveries end up in the log and the health check
+	requests = -10
+	d.retries = 1
+	result = d.ping()
+	assert.False(t, result[0].Success)
+	assert.Equal(t, 2, result[0].Attempts)
+	assert.Equal(t, ts.URL+" returned 502", result[0].Error)
+	assert.Equal(t, 1, len(d.Health()))
+
+	metrics, _ := d.GetMetrics()
+	assert.Nil(t, metrics.checkMetric("cov19_webhook_deliveries_total", "result=success", func(x float64) bool { return x == 1 }))
+	assert.Nil(t, metrics.checkMetric("cov19_webhook_deliveries_total", "result=failure", func(x float64) bool { return x == 1 }))
+}
+
+func TestWebhookEndpoints(t *testing.T) {
+	pings := 0
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "ping", r.Header.Get("X-Covid19-Event"))
+		pings++
+	}))
+	defer receiver.Close()
+
+	configLock.Lock()
+	previous := rl.config
+	rl.config = &config{AdminToken: "secret"}
+	configLock.Unlock()
+	defer func() {
+		configLock.Lock()
+		rl.config = previous
+		configLock.Unlock()
+	}()
+
+	d := newWebhookDispatcher()
+	d.configure([]webhookConfig{{URL: receiver.URL}})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/admin/webhooks", d.handleDeliveries)
+	mux.HandleFunc("/admin/webhooks/test", d.handlePing)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	response, err := ts.Client().Post(ts.URL+"/admin/webhooks/test", "", nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusUnauthorized, response.StatusCode)
+
+	request, _ := http.NewRequest(http.MethodPost, ts.URL+"/admin/webhooks/test", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err = ts.Client().Do(request)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+	assert.Equal(t, 1, pings)
+
+	request, _ = http.NewRequest(http.MethodGet, ts.URL+"/admin/webhooks", nil)
+	request.Header.Set("Authorization", "Bearer secret")
+	response, err = ts.Client().Do(request)
+	assert.Nil(t, err)
+	deliveries := make([]webhookDelivery, 0)
+	assert.Nil(t, json.NewDecoder(response.Body).Decode(&deliveries))
+	assert.Equal(t, 1, len(deliveries))
+	assert.Equal(t, "ping", deliveries[0].Type)
+	assert.True(t, deliveries[0].Success)
+}
+
+func TestWebhookIncidenceThreshold(t *testing.T) {
+	weekAgo, previous, current := testSnapshot(1000, 1320), testSnapshot(1500, 1820), testSnapshot(2000, 2320)
+	at := func(time.Time) *snapshot { return weekAgo }
+	e := updateEvent{Scope: "bundesland", Time: current.Time, Regions: []regionChange{
+		{Region: "Wien", Changes: map[string]fieldChange{"infected": {Previous: 1500, Current: 2000, Delta: 500}}},
+	}}
+	hook := webhookConfig{URL: "http://localhost", Metric: "infected", Function: "increase_per_100k", Threshold: 50}
+	result := hook.payloads(e, current, previous, at)
+	if assert.Equal(t, 1, len(result)) {
+		assert.Equal(t, "increase_per_100k(infected, 7d)", result[0].Threshold.Metric)
+		assert.InDelta(t, 26.5, result[0].Threshold.Previous, 0.1)
+		assert.InDelta(t, 52.9, result[0].Threshold.Current, 0.1)
+		assert.Equal(t, "above", result[0].Threshold.Direction)
+	}
+	assert.Empty(t, hook.payloads(e, current, previous, func(time.Time) *snapshot { return nil }), "no daily snapshot a week ago")
+	assert.EqualError(t, webhookConfig{URL: "http://localhost", Metric: "infected", Function: "avg"}.validate(), `Unknown function "avg", use increase, increase_per_100k or change_ratio`)
+}
+
+func TestWebhookQueue(t *testing.T) {
+	release := make(chan bool)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}))
+	defer ts.Close()
+	defer close(release)
+
+	d := newWebhookDispatcher()
+	d.configure([]webhookConfig{{URL: ts.URL}})
+	for i := 0; i < maxQueuedDeliveries+5; i++ {
+		d.enqueue(testUpdateEvent(), nil, nil, nil)
+	}
+	d.lock.Lock()
+	dropped, first := len(d.deliveries), d.deliveries[0]
+	d.lock.Unlock()
+	assert.True(t, dropped >= 4, "dropped %d", dropped)
+	assert.Equal(t, "Too many pending deliveries, dropped", first.Error)
+
+	//the counts do not shrink when the log wraps
+	for i := 0; i < maxDeliveries; i++ {
+		d.record(webhookDelivery{URL: ts.URL, Success: true})
+	}
+	metrics, _ := d.GetMetrics()
+	assert.Nil(t, metrics.checkMetric("cov19_webhook_deliveries_total", "result=success", func(x float64) bool { return x == maxDeliveries }))
+	assert.Nil(t, metrics.checkMetric("cov19_webhook_deliveries_total", "result=failure", func(x float64) bool { return x == float64(dropped) }))
+	assert.Equal(t, maxDeliveries, len(d.deliveries))
+}
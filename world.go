@@ -0,0 +1,167 @@
+package main
+
+import (
+	"sort"
+	"strings"
+)
+
+type worldStat struct {
+	//Name is the country as used in metadata.csv
+	Name      string      `json:"name"`
+	Continent string      `json:"continent"`
+	Location  apiLocation `json:"location"`
+	//Population is the number of inhabitants, 0 if unknown
+	Population uint64 `json:"population"`
+	//Infected are the confirmed infections reported by the ECDC
+	Infected uint64 `json:"infected"`
+	//Dead are the deaths reported by the ECDC
+	Dead uint64 `json:"dead"`
+	//Recovered are the recovered cases reported by mathdro, summed over all provinces
+	Recovered uint64 `json:"recovered"`
+	//InfectedPer100k are the infections per 100.000 inhabitants, 0 if the population is unknown
+	InfectedPer100k float64 `json:"infected_per_100k"`
+	//DeadPer100k are the deaths per 100.000 inhabitants, 0 if the population is unknown
+	DeadPer100k float64 `json:"dead_per_100k"`
+	//FatalityRate is the share of infected that died, 0 without infections
+	FatalityRate float64 `json:"fatality_rate"`
+}
+
+type continentStat struct {
+	Name string `json:"name"`
+	//Countries is the number of countries with reported cases
+	Countries  uint64 `json:"countries"`
+	Population uint64 `json:"population"`
+	Infected   uint64 `json:"infected"`
+	Dead       uint64 `json:"dead"`
+	Recovered  uint64 `json:"recovered"`
+	//InfectedPer100k and DeadPer100k only include countries with a known population
+	InfectedPer100k float64 `json:"infected_per_100k"`
+	DeadPer100k     float64 `json:"dead_per_100k"`
+	FatalityRate    float64 `json:"fatality_rate"`
+}
+
+//mathdroCountryNames maps the country names of mathdro to the ones used by the ECDC and metadata.csv
+var mathdroCountryNames = map[string]string{
+	"US":                  "United States of America",
+	"Korea, South":        "South Korea",
+	"Taiwan*":             "Taiwan",
+	"Czechia":             "Czech Republic",
+	"Congo (Kinshasa)":    "Democratic Republic of the Congo",
+	"Congo (Brazzaville)": "Congo",
+	"Cote d'Ivoire":       "Cote dIvoire",
+	"Burma":               "Myanmar",
+}
+
+//countryName returns the name of a country as it appears in the metadata, so sources with different spellings can be merged
+func (a *api) countryName(name string) string {
+	if alias, ok := mathdroCountryNames[name]; ok {
+		name = alias
+	}
+	if data := a.ee.Mp.getMetadata(name); data != nil {
+		return data.country
+	}
+	return normalizeCountryName(name)
+}
+
+func (a *api) getRecovered() map[string]uint64 {
+	result := make(map[string]uint64)
+	recovered, err := a.mde.getRecoveredStats()
+	if err != nil {
+		logger.Printf("Reading recovered stats failed: %v", err)
+		return result
+	}
+	for _, r := range recovered {
+		result[normalizeName(a.countryName(r.CountryRegion))] += r.Recovered
+	}
+	return result
+}
+
+//GetWorldStat merges the infections and deaths of the ECDC with the recovered cases of mathdro, ordered by name
+func (a *api) GetWorldStat() ([]worldStat, error) {
+	stats, err := getEcdcStat(a.ee.Url)
+	if err != nil {
+		return nil, err
+	}
+	recovered := a.getRecovered()
+	result := make([]worldStat, 0, len(stats))
+	for _, s := range stats {
+		stat := worldStat{
+			Name:      a.countryName(s.location),
+			Continent: strings.TrimSpace(s.continent),
+			Infected:  s.infected,
+			Dead:      s.deaths,
+		}
+		stat.Recovered = recovered[normalizeName(stat.Name)]
+		if data := a.ee.Mp.getMetadata(stat.Name); data != nil {
+			stat.Location = apiLocation{Lat: data.location.lat, Long: data.location.long}
+			stat.Population = data.population
+		}
+		if stat.Population > 0 {
+			stat.InfectedPer100k = infection100k(stat.Infected, stat.Population)
+			stat.DeadPer100k = infection100k(stat.Dead, stat.Population)
+		}
+		if stat.Infected > 0 {
+			stat.FatalityRate = fatalityRate(stat.Infected, stat.Dead)
+		}
+		result = append(result, stat)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+//GetCountryStat returns the stats of a single country, nil if the country has no reported cases
+func (a *api) GetCountryStat(country string) (*worldStat, error) {
+	stats, err := a.GetWorldStat()
+	if err != nil {
+		return nil, err
+	}
+	name := normalizeName(a.countryName(country))
+	for _, s := range stats {
+		if normalizeName(s.Name) == name {
+			return &s, nil
+		}
+	}
+	return nil, nil
+}
+
+//GetContinentStat sums up the countries of each continent, ordered by name
+func (a *api) GetContinentStat() ([]continentStat, error) {
+	stats, err := a.GetWorldStat()
+	if err != nil {
+		return nil, err
+	}
+	continents := make(map[string]*continentStat)
+	//infected and dead of the countries with a known population, for the per 100k rates
+	known := make(map[string]*continentStat)
+	for _, s := range stats {
+		c, ok := continents[s.Continent]
+		if !ok {
+			c = &continentStat{Name: s.Continent}
+			continents[s.Continent] = c
+			known[s.Continent] = &continentStat{}
+		}
+		c.Countries++
+		c.Population += s.Population
+		c.Infected += s.Infected
+		c.Dead += s.Dead
+		c.Recovered += s.Recovered
+		if s.Population > 0 {
+			known[s.Continent].Infected += s.Infected
+			known[s.Continent].Dead += s.Dead
+		}
+	}
+
+	result := make([]continentStat, 0, len(continents))
+	for name, c := range continents {
+		if c.Population > 0 {
+			c.InfectedPer100k = infection100k(known[name].Infected, c.Population)
+			c.DeadPer100k = infection100k(known[name].Dead, c.Population)
+		}
+		if c.Infected > 0 {
+			c.FatalityRate = fatalityRate(c.Infected, c.Dead)
+		}
+		result = append(result, *c)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWorldStat(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	stats, err := mockApi.GetWorldStat()
+	assert.Nil(t, err)
+	assert.Equal(t, 4, len(stats))
+	assert.Equal(t, "Atlantis", stats[0].Name)
+	assert.Equal(t, uint64(0), stats[0].Population)
+	assert.Equal(t, float64(0), stats[0].InfectedPer100k)
+
+	austria := stats[1]
+	assert.Equal(t, "Austria", austria.Name)
+	assert.Equal(t, "Europe", austria.Continent)
+	assert.Equal(t, uint64(9618), austria.Infected)
+	assert.Equal(t, uint64(108), austria.Dead)
+	assert.Equal(t, uint64(636), austria.Recovered)
+	assert.True(t, austria.Population > 8000000)
+	assert.True(t, austria.Location.Lat > 47)
+	assert.InDelta(t, 0.0112, austria.FatalityRate, 0.0001)
+
+	usa, err := mockApi.GetCountryStat("US")
+	assert.Nil(t, err)
+	assert.Equal(t, "United States of America", usa.Name)
+	assert.Equal(t, uint64(102), usa.Recovered)
+
+	unknown, err := mockApi.GetCountryStat("Lilliput")
+	assert.Nil(t, err)
+	assert.Nil(t, unknown)
+}
+
+func TestContinentStat(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+
+	stats, err := mockApi.GetContinentStat()
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"America", "Europe", "Oceania"}, []string{stats[0].Name, stats[1].Name, stats[2].Name})
+	europe := stats[1]
+	assert.Equal(t, uint64(2), europe.Countries)
+	assert.Equal(t, uint64(111357), europe.Infected)
+	assert.Equal(t, uint64(636), europe.Recovered)
+	assert.Equal(t, float64(0), stats[2].InfectedPer100k)
+}
+
+func TestWorldHandlers(t *testing.T) {
+	mockApi, closeMock := newMockApi(t)
+	defer closeMock()
+	defaultApi := a
+	a = mockApi
+	defer func() { a = defaultApi }()
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/world", handleApiV1World)
+	mux.HandleFunc("/api/v1/world/", handleApiV1Country)
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	response, err := ts.Client().Get(ts.URL + "/api/v1/world?continent=europe&sort=-infected&fields=name,infected&format=csv")
+	assert.Nil(t, err)
+	body, _ := ioutil.ReadAll(response.Body)
+	assert.Equal(t, "name,infected\nItaly,101739\nAustria,9618\n", string(body))
+
+	response, err = ts.Client().Get(ts.URL + "/api/v1/world/austria")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, response.StatusCode)
+
+	response, err = ts.Client().Get(ts.URL + "/api/v1/world/Lilliput")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusNotFound, response.StatusCode)
+}